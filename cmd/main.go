@@ -1,22 +1,80 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/yanrishbe/gaming-website/entity"
 	"github.com/yanrishbe/gaming-website/game"
+	"github.com/yanrishbe/gaming-website/memory"
 	"github.com/yanrishbe/gaming-website/postgres"
 	"github.com/yanrishbe/gaming-website/server"
 )
 
+// scheduleEvery is how often the scheduler looks for due tournaments.
+const scheduleEvery = 10 * time.Second
+
+func storage() (game.Storage, error) {
+	if os.Getenv("STORAGE") == "memory" {
+		return memory.New(), nil
+	}
+	return postgres.New()
+}
+
+// loadPolicy puts the registration policy of REG_FEE, SIGNUP_BONUS,
+// MIN_BALANCE, WAGERING and SPEND_ORDER in force. Unset variables keep the
+// terms of the current policy, and with none of them set the current policy
+// stays.
+func loadPolicy(c game.Controller) error {
+	p, err := c.CurrentPolicy()
+	if err != nil {
+		return err
+	}
+	set := false
+	for _, v := range []struct {
+		name string
+		term *int
+	}{{"REG_FEE", &p.Fee}, {"SIGNUP_BONUS", &p.Bonus}, {"MIN_BALANCE", &p.MinBalance}, {"WAGERING", &p.Wagering}} {
+		s, ok := os.LookupEnv(v.name)
+		if !ok {
+			continue
+		}
+		*v.term, err = strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%s: %v", v.name, err)
+		}
+		set = true
+	}
+	if s, ok := os.LookupEnv("SPEND_ORDER"); ok {
+		p.SpendOrder = entity.SpendOrder(s)
+		set = true
+	}
+	if !set {
+		return nil
+	}
+	_, err = c.LoadPolicy(p)
+	return err
+}
+
 func main() {
 	logrus.SetFormatter(&logrus.JSONFormatter{})
 	logrus.SetLevel(logrus.DebugLevel)
-	db, err := postgres.New()
+	db, err := storage()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	c := game.New(db)
+	err = loadPolicy(c)
 	if err != nil {
 		logrus.Fatal(err)
 	}
-	r, err := server.New(game.New(db))
+	go game.NewScheduler(c, game.SystemClock{}).Run(context.Background(), scheduleEvery)
+	r, err := server.New(c)
 	if err != nil {
 		logrus.Fatal(err)
 	}
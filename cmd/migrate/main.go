@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yanrishbe/gaming-website/postgres"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [n]    revert the last n migrations (default 1)
+  version     print the current schema version
+  force <v>   mark the schema as cleanly migrated to version v
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := postgres.Open()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		err = db.Migrate()
+	case "down":
+		n := 1
+		if flag.NArg() > 1 {
+			n, err = strconv.Atoi(flag.Arg(1))
+			if err != nil {
+				logrus.Fatal(err)
+			}
+		}
+		err = db.MigrateDown(n)
+	case "version":
+		var version int
+		var dirty bool
+		version, dirty, err = db.MigrationVersion()
+		if err == nil {
+			fmt.Printf("version %d, dirty %t\n", version, dirty)
+		}
+	case "force":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		var version int
+		version, err = strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		err = db.ForceVersion(version)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Account is a double-entry ledger account. Every journal posts entries that
+// sum to zero, so the balances of all accounts always net to zero.
+type Account string
+
+const (
+	// HouseAccount is where points enter and leave the system.
+	HouseAccount Account = "house"
+	// FeesAccount collects registration fees.
+	FeesAccount Account = "registration_fees"
+	// RakeAccount collects the house's cut of tournament deposits.
+	RakeAccount Account = "rake"
+
+	userPrefix   = "user:"
+	bonusPrefix  = "bonus:"
+	escrowPrefix = "escrow:"
+)
+
+func UserAccount(id int) Account {
+	return Account(fmt.Sprintf("%s%d", userPrefix, id))
+}
+
+// BonusAccount holds a user's bonus points until they convert to cash.
+// UserAccount holds the cash.
+func BonusAccount(id int) Account {
+	return Account(fmt.Sprintf("%s%d", bonusPrefix, id))
+}
+
+// EscrowAccount holds the deposits of a tournament until they are paid out.
+func EscrowAccount(tournamentID int) Account {
+	return Account(fmt.Sprintf("%s%d", escrowPrefix, tournamentID))
+}
+
+// UserID returns the id of the user the cash or bonus account belongs to.
+func (a Account) UserID() (int, bool) {
+	s := string(a)
+	for _, prefix := range []string{userPrefix, bonusPrefix} {
+		if !strings.HasPrefix(s, prefix) {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(s, prefix))
+		if err != nil {
+			return 0, false
+		}
+		return id, true
+	}
+	return 0, false
+}
+
+// IsBonus reports whether the account holds a user's bonus points.
+func (a Account) IsBonus() bool {
+	return strings.HasPrefix(string(a), bonusPrefix)
+}
+
+type AccountBalance struct {
+	Account Account `json:"account"`
+	Balance int     `json:"balance"`
+}
+
+// TrialBalance lists the balances of all accounts. Total is their sum and is
+// zero unless the books are broken.
+type TrialBalance struct {
+	Accounts []AccountBalance `json:"accounts"`
+	Total    int              `json:"total"`
+	Balanced bool             `json:"balanced"`
+}
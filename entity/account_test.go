@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountUserID(t *testing.T) {
+	for _, tc := range []struct {
+		account Account
+		id      int
+		ok      bool
+	}{
+		{UserAccount(7), 7, true},
+		{BonusAccount(7), 7, true},
+		{EscrowAccount(7), 0, false},
+		{HouseAccount, 0, false},
+		{FeesAccount, 0, false},
+		{"user:x", 0, false},
+	} {
+		id, ok := tc.account.UserID()
+		assert.Equal(t, tc.id, id, string(tc.account))
+		assert.Equal(t, tc.ok, ok, string(tc.account))
+	}
+}
+
+func TestAccountIsBonus(t *testing.T) {
+	assert.True(t, BonusAccount(7).IsBonus())
+	assert.False(t, UserAccount(7).IsBonus())
+	assert.False(t, HouseAccount.IsBonus())
+}
@@ -0,0 +1,132 @@
+package entity
+
+import "errors"
+
+// Match is a game between two players of a tournament. Player1 and Player2
+// are 0 until the players are known. A bye match has a single player, who
+// wins it without playing. A drawn match has no winner.
+type Match struct {
+	Round   int  `json:"round"`
+	Slot    int  `json:"slot"`
+	Player1 int  `json:"player1,omitempty"`
+	Player2 int  `json:"player2,omitempty"`
+	Winner  int  `json:"winner,omitempty"`
+	Bye     bool `json:"bye,omitempty"`
+	Draw    bool `json:"draw,omitempty"`
+}
+
+// Played reports whether the match has a result.
+func (m Match) Played() bool {
+	return m.Winner != 0 || m.Draw
+}
+
+// Loser returns the player who lost a played match, 0 if there is none.
+func (m Match) Loser() int {
+	switch {
+	case m.Winner == 0 || m.Bye || m.Draw:
+		return 0
+	case m.Winner == m.Player1:
+		return m.Player2
+	}
+	return m.Player1
+}
+
+// Decide returns the match won by winner.
+func (m Match) Decide(winner int) (Match, error) {
+	err := m.playable()
+	if err != nil {
+		return m, err
+	}
+	if winner != m.Player1 && winner != m.Player2 {
+		return m, ReqErr(errors.New("the winner doesn't play in the match"))
+	}
+	m.Winner = winner
+	return m, nil
+}
+
+// Record returns the match with the result r reports, either a winner or a
+// draw.
+func (m Match) Record(r Match) (Match, error) {
+	if !r.Draw {
+		return m.Decide(r.Winner)
+	}
+	if r.Winner != 0 {
+		return m, ReqErr(errors.New("a drawn match has no winner"))
+	}
+	err := m.playable()
+	if err != nil {
+		return m, err
+	}
+	m.Draw = true
+	return m, nil
+}
+
+func (m Match) playable() error {
+	if m.Played() {
+		return ReqErr(errors.New("the match is already decided"))
+	}
+	if m.Player1 == 0 || m.Player2 == 0 {
+		return ReqErr(errors.New("the players of the match aren't known yet"))
+	}
+	return nil
+}
+
+// Feeds returns the round and slot of the match the winner plays next, and
+// whether they play it as Player1.
+func (m Match) Feeds() (round, slot int, first bool) {
+	return m.Round + 1, m.Slot / 2, m.Slot%2 == 0
+}
+
+// Bracket is the tree of a single-elimination tournament, listed by rounds
+// from the first one to the final. The winner of the match in slot s of a
+// round plays in slot s/2 of the next round, as Player1 if s is even.
+type Bracket struct {
+	TournamentID int       `json:"tournamentId"`
+	Rounds       [][]Match `json:"rounds"`
+	Winner       int       `json:"winner,omitempty"`
+}
+
+// NewBracket builds the tree from the matches of a tournament, which are
+// ordered by round and slot.
+func NewBracket(tID int, matches []Match) Bracket {
+	b := Bracket{TournamentID: tID, Rounds: rounds(matches)}
+	if len(b.Rounds) > 0 {
+		b.Winner = b.Rounds[len(b.Rounds)-1][0].Winner
+	}
+	return b
+}
+
+// rounds groups matches ordered by round and slot into rounds.
+func rounds(matches []Match) [][]Match {
+	rounds := [][]Match{}
+	for _, m := range matches {
+		for len(rounds) < m.Round {
+			rounds = append(rounds, []Match{})
+		}
+		rounds[m.Round-1] = append(rounds[m.Round-1], m)
+	}
+	return rounds
+}
+
+// Ranking returns the final ranking once the final is played: the winner,
+// the loser of the final, then the losers of every earlier round, those of
+// the same round in the order of their slots. ok is false while matches are
+// left to play. A bracket without matches, that is with a single player, is
+// complete and ranks nobody.
+func (b Bracket) Ranking() (ranking []int, ok bool) {
+	if len(b.Rounds) == 0 {
+		return nil, true
+	}
+	if b.Winner == 0 {
+		return nil, false
+	}
+	ranking = []int{b.Winner}
+	for r := len(b.Rounds) - 1; r >= 0; r-- {
+		for _, m := range b.Rounds[r] {
+			if loser := m.Loser(); loser != 0 {
+				ranking = append(ranking, loser)
+			}
+		}
+	}
+	return ranking, true
+}
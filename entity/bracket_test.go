@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchDecide(t *testing.T) {
+	m := Match{Round: 1, Slot: 3, Player1: 4, Player2: 7}
+	assert.Equal(t, 0, m.Loser(), "the match isn't played")
+	decided, err := m.Decide(7)
+	require.NoError(t, err)
+	assert.Equal(t, 7, decided.Winner)
+	assert.Equal(t, 4, decided.Loser())
+	_, err = decided.Decide(4)
+	assert.Error(t, err, "a match is decided once")
+	_, err = m.Decide(5)
+	assert.Error(t, err)
+	_, err = Match{Player1: 4}.Decide(4)
+	assert.Error(t, err, "the opponent isn't known yet")
+	assert.Equal(t, 0, Match{Player1: 4, Winner: 4, Bye: true}.Loser(), "nobody loses a bye")
+
+	round, slot, first := m.Feeds()
+	assert.Equal(t, []interface{}{2, 1, false}, []interface{}{round, slot, first})
+	round, slot, first = Match{Round: 2, Slot: 2}.Feeds()
+	assert.Equal(t, []interface{}{3, 1, true}, []interface{}{round, slot, first})
+}
+
+func TestBracketRanking(t *testing.T) {
+	matches := []Match{
+		{Round: 1, Slot: 0, Player1: 1, Winner: 1, Bye: true},
+		{Round: 1, Slot: 1, Player1: 2, Player2: 3, Winner: 3},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 3},
+	}
+	b := NewBracket(9, matches)
+	assert.Equal(t, 9, b.TournamentID)
+	require.Len(t, b.Rounds, 2)
+	assert.Len(t, b.Rounds[0], 2)
+	_, ok := b.Ranking()
+	assert.False(t, ok, "the final isn't played")
+
+	matches[2].Winner = 3
+	ranking, ok := NewBracket(9, matches).Ranking()
+	assert.True(t, ok)
+	assert.Equal(t, []int{3, 1, 2}, ranking)
+
+	ranking, ok = NewBracket(9, nil).Ranking()
+	assert.True(t, ok, "a lone player has no matches")
+	assert.Empty(t, ranking)
+}
@@ -2,13 +2,29 @@ package entity
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 )
 
+// User is a player. Balance is the user's cash and Bonus their bonus points,
+// which convert to cash once the user has made Wagering more points of
+// tournament deposits.
 type User struct {
-	ID      int    `json:"id"`
-	Name    string `json:"name"`
-	Balance int    `json:"balance"`
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Balance  int    `json:"balance"`
+	Bonus    int    `json:"bonus"`
+	Wagering int    `json:"wagering,omitempty"`
+	Rating   int    `json:"rating"`
+	// PolicyID is the registration policy the user registered under.
+	PolicyID int `json:"policyId,omitempty"`
+}
+
+// Funds returns the points the user can put into tournaments, cash and
+// bonus points together.
+func (u User) Funds() int {
+	return u.Balance + u.Bonus
 }
 
 func (u User) IsValid() error {
@@ -33,32 +49,334 @@ func (u UserTourn) IsValid() error {
 type Winner struct {
 	ID     int    `json:"userId"`
 	Name   string `json:"name"`
+	Stake  int    `json:"stake"`
 	Winner bool   `json:"winner,omitempty"`
+	// Place is the final place of the user, 0 until the tournament is
+	// finished. Payout is the part of the prize the place won.
+	Place  int `json:"place,omitempty"`
+	Payout int `json:"payout,omitempty"`
+	// Score is the last score the user submitted to a score tournament.
+	Score    *int       `json:"score,omitempty"`
+	ScoredAt *time.Time `json:"scoredAt,omitempty"`
+	// TeamID is the team a captain has registered, Members are the members
+	// of the team who take part.
+	TeamID  int      `json:"teamId,omitempty"`
+	Members []Member `json:"members,omitempty"`
 }
 
 type Tournament struct {
-	ID      int      `json:"id"`
-	Name    string   `json:"name"`
-	Deposit int      `json:"deposit"`
-	Winner  int      `json:"winner,omitempty"`
-	Prize   int      `json:"prize"`
-	Users   []Winner `json:"users"`
-	Status  Status   `json:"status"`
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Deposit  int      `json:"deposit"`
+	Winner   int      `json:"winner,omitempty"`
+	Prize    int      `json:"prize"`
+	Users    []Winner `json:"users"`
+	Status   Status   `json:"status"`
+	Selector Selector `json:"selector"`
+	Format   Format   `json:"format"`
+	Payouts  Payouts  `json:"payouts"`
+	// Rounds is the number of rounds of a Swiss tournament, 0 picks enough
+	// rounds to leave a single unbeaten player.
+	Rounds int `json:"rounds,omitempty"`
+	// TieBreak orders tied players of a tournament with the score selector.
+	TieBreak    TieBreak   `json:"tieBreak,omitempty"`
+	Leaderboard []Standing `json:"leaderboard,omitempty"`
+	// RakePercent or RakeFee sets the house's cut of every deposit, Rake is
+	// what the house has taken so far. The rest of the deposits is the prize.
+	RakePercent int `json:"rakePercent,omitempty"`
+	RakeFee     int `json:"rakeFee,omitempty"`
+	Rake        int `json:"rake"`
+	// OpensAt, StartsAt and EndsAt schedule the moves of the tournament to
+	// open, running and finished. Unset steps are left to the API.
+	OpensAt  *time.Time `json:"opensAt,omitempty"`
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	// A tournament with fewer than MinPlayers is cancelled instead of being
+	// finished. Users joining a tournament with MaxPlayers already in wait
+	// in Waitlist, first come first served, until a seat is free. 0 is no
+	// limit.
+	MinPlayers int         `json:"minPlayers,omitempty"`
+	MaxPlayers int         `json:"maxPlayers,omitempty"`
+	Waitlist   []UserTourn `json:"waitlist,omitempty"`
+	// MinRating and MaxRating bound the ratings of users who may join, 0 is
+	// no bound.
+	MinRating int `json:"minRating,omitempty"`
+	MaxRating int `json:"maxRating,omitempty"`
+	// TeamSize makes a team tournament. Only teams of TeamSize members enter
+	// it, each registered by its captain and taking a single seat.
+	TeamSize int `json:"teamSize,omitempty"`
+	// A Private tournament is left out of the listings and only users with
+	// an invite join it.
+	Private bool `json:"private,omitempty"`
+	// SeedHash commits to the secret Seed the winner is drawn from. The seed
+	// is revealed once the tournament is finished.
+	SeedHash string `json:"seedHash"`
+	Seed     string `json:"seed,omitempty"`
+}
+
+// Proof shows how the placings of a finished tournament were drawn.
+type Proof struct {
+	TournamentID    int      `json:"tournamentId"`
+	Selector        Selector `json:"selector"`
+	SeedHash        string   `json:"seedHash"`
+	Seed            string   `json:"seed"`
+	Input           string   `json:"input"`
+	Winner          int      `json:"winner"`
+	Computed        int      `json:"computedWinner"`
+	Ranking         []int    `json:"ranking"`
+	ComputedRanking []int    `json:"computedRanking"`
+	SeedMatches     bool     `json:"seedMatches"`
+	Valid           bool     `json:"valid"`
+}
+
+// Entry is a participant of a tournament with the points they put in, the
+// part of them taken by the house, the score they submitted, if any, and
+// their current rating. BonusStake is the part of Stake paid in bonus
+// points. A team entry is the team's captain, TeamID and Members tell the
+// team and who paid what.
+type Entry struct {
+	UserID     int
+	Stake      int
+	BonusStake int
+	Rake       int
+	Score      *int
+	ScoredAt   *time.Time
+	Rating     int
+	TeamID     int
+	Members    []Member
+}
+
+// Selector is the way a tournament chooses its winner.
+type Selector string
+
+const (
+	// SelectUniform gives every participant the same chance.
+	SelectUniform Selector = "uniform"
+	// SelectStake gives participants chances proportional to their stakes.
+	SelectStake Selector = "stake_weighted"
+	// SelectFirst makes the first registered participant the winner.
+	SelectFirst Selector = "first_registered"
+	// SelectExternal takes the winner from the finish request.
+	SelectExternal Selector = "external"
+	// SelectScore ranks the participants by their submitted scores.
+	SelectScore Selector = "score"
+)
+
+func (s Selector) IsValid() bool {
+	switch s {
+	case SelectUniform, SelectStake, SelectFirst, SelectExternal, SelectScore:
+		return true
+	}
+	return false
+}
+
+// Format is the way a tournament is played.
+type Format string
+
+const (
+	// FormatStandard is finished by the API or the schedule.
+	FormatStandard Format = "standard"
+	// FormatSitAndGo starts and finishes as soon as MaxPlayers have joined.
+	FormatSitAndGo Format = "sit_and_go"
+	// FormatBracket is a single-elimination bracket, finished by its final.
+	FormatBracket Format = "single_elimination"
+	// FormatRoundRobin is a league where everyone plays everyone once.
+	FormatRoundRobin Format = "round_robin"
+	// FormatSwiss pairs players with the same points round by round.
+	FormatSwiss Format = "swiss"
+)
+
+func (f Format) IsValid() bool {
+	switch f {
+	case FormatStandard, FormatSitAndGo, FormatBracket, FormatRoundRobin, FormatSwiss:
+		return true
+	}
+	return false
+}
+
+// HasMatches reports whether the tournament is played in matches that rank
+// its players.
+func (f Format) HasMatches() bool {
+	return f == FormatBracket || f.HasStandings()
+}
+
+// HasStandings reports whether the players are ranked by the points of their
+// matches, so the tournament can end at any time.
+func (f Format) HasStandings() bool {
+	return f == FormatRoundRobin || f == FormatSwiss
 }
+
+// JoinResult is the tournament a user has joined. Result is the user's place
+// and payout if the join finished the tournament.
+type JoinResult struct {
+	Tournament
+	Result *Winner `json:"result,omitempty"`
+}
+
 type Status string
 
 const (
-	Active   Status = "active"
-	Finished Status = "finished"
+	Draft     Status = "draft"
+	Open      Status = "open"
+	Running   Status = "running"
+	Finished  Status = "finished"
+	Cancelled Status = "cancelled"
 )
 
+// transitions lists the statuses a tournament may move to from each status.
+// Finished and cancelled tournaments never change.
+var transitions = map[Status][]Status{
+	Draft:   {Open, Cancelled},
+	Open:    {Running, Cancelled},
+	Running: {Finished, Cancelled},
+}
+
+func (s Status) IsValid() bool {
+	switch s {
+	case Draft, Open, Running, Finished, Cancelled:
+		return true
+	}
+	return false
+}
+
+// IsFinal reports whether the tournament is over.
+func (s Status) IsFinal() bool {
+	return s == Finished || s == Cancelled
+}
+
+// Transition returns an error if a tournament can't move from s to status to.
+func (s Status) Transition(to Status) error {
+	for _, next := range transitions[s] {
+		if next == to {
+			return nil
+		}
+	}
+	return TransitionErr(s, to)
+}
+
+// Require returns an error unless s is the status an action needs.
+func (s Status) Require(want Status) error {
+	if s != want {
+		return StatusErr(fmt.Errorf("the tournament is %s, expected %s", s, want))
+	}
+	return nil
+}
+
 func (t Tournament) IsValid() error {
 	if t.Name == "" {
 		return RegErr(errors.New("empty name"))
 	}
+	if t.RakePercent != 0 && t.RakeFee != 0 {
+		return RegErr(errors.New("rake can't be both a percentage and a fee"))
+	}
+	if t.RakePercent < 0 || t.RakePercent >= 100 {
+		return RegErr(errors.New("rake percentage must be between 0 and 99"))
+	}
+	if t.RakeFee < 0 || t.RakeFee != 0 && t.RakeFee >= t.Deposit {
+		return RegErr(errors.New("rake fee must be less than the deposit"))
+	}
+	if t.Rounds < 0 {
+		return RegErr(errors.New("number of rounds must not be negative"))
+	}
+	if t.MinRating < 0 || t.MaxRating < 0 {
+		return RegErr(errors.New("rating bounds must not be negative"))
+	}
+	if t.MaxRating != 0 && t.MinRating > t.MaxRating {
+		return RegErr(errors.New("minRating is greater than maxRating"))
+	}
+	if t.TeamSize < 0 || t.TeamSize == 1 {
+		return RegErr(errors.New("a team has at least 2 members"))
+	}
+	if t.MinPlayers < 0 || t.MaxPlayers < 0 {
+		return RegErr(errors.New("numbers of players must not be negative"))
+	}
+	if t.MaxPlayers != 0 && t.MinPlayers > t.MaxPlayers {
+		return RegErr(errors.New("minPlayers is greater than maxPlayers"))
+	}
+	var last *time.Time
+	for _, at := range []*time.Time{t.OpensAt, t.StartsAt, t.EndsAt} {
+		if at == nil {
+			continue
+		}
+		if last != nil && !last.Before(*at) {
+			return RegErr(errors.New("opensAt, startsAt and endsAt must follow each other"))
+		}
+		last = at
+	}
 	return nil
 }
 
+// Due returns the status the schedule moves the tournament to at now, if a
+// step is due.
+func (t Tournament) Due(now time.Time) (Status, bool) {
+	var at *time.Time
+	var next Status
+	switch t.Status {
+	case Draft:
+		at, next = t.OpensAt, Open
+	case Open:
+		at, next = t.StartsAt, Running
+	case Running:
+		at, next = t.EndsAt, Finished
+	}
+	if at == nil || now.Before(*at) {
+		return "", false
+	}
+	return next, true
+}
+
+// GoesWhenFull reports whether, with players taking part, the tournament is a
+// sit-and-go that is full and finishes.
+func (t Tournament) GoesWhenFull(players int) bool {
+	return t.Format == FormatSitAndGo && t.IsFull(players)
+}
+
+// IsFull reports whether, with players taking part, more users can only join the waitlist.
+func (t Tournament) IsFull(players int) bool {
+	return t.MaxPlayers != 0 && players >= t.MaxPlayers
+}
+
+// HasQuorum reports whether a tournament with players can be finished. It
+// needs at least one player.
+func (t Tournament) HasQuorum(players int) bool {
+	return players > 0 && players >= t.MinPlayers
+}
+
+// SwissRounds returns the number of rounds a Swiss tournament with players
+// plays. Nobody meets the same opponent twice, so there are at most
+// players-1 rounds.
+func (t Tournament) SwissRounds(players int) int {
+	if players < 2 {
+		return 0
+	}
+	rounds := t.Rounds
+	if rounds == 0 {
+		for 1<<uint(rounds) < players {
+			rounds++
+		}
+	}
+	if rounds > players-1 {
+		rounds = players - 1
+	}
+	return rounds
+}
+
+// AllowsRating returns an error unless a user with rating may join t.
+func (t Tournament) AllowsRating(rating int) error {
+	if t.MinRating != 0 && rating < t.MinRating || t.MaxRating != 0 && rating > t.MaxRating {
+		return RegErr(errors.New("rating is out of the tournament's band"))
+	}
+	return nil
+}
+
+// EntryRake returns the house's cut of a deposit, rounded down.
+func (t Tournament) EntryRake() int {
+	if t.RakeFee != 0 {
+		return t.RakeFee
+	}
+	return t.Deposit * t.RakePercent / 100
+}
+
 type Error struct {
 	Type    string `json:"type"`
 	Code    int    `json:"code"`
@@ -79,6 +397,8 @@ var (
 	ErrDecode       = "decoding data error"
 	ErrPoints       = "wrong input points"
 	ErrInvReq       = "wrong request"
+	ErrTransition   = "illegal status transition"
+	ErrStatus       = "wrong tournament status"
 )
 
 func RegErr(err error) Error {
@@ -156,3 +476,21 @@ func ReqErr(err error) Error {
 		Message: err.Error(),
 	}
 }
+
+func TransitionErr(from, to Status) Error {
+	return Error{
+		Type:    ErrTransition,
+		Cause:   fmt.Errorf("%s -> %s", from, to),
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("can't move the tournament from %s to %s", from, to),
+	}
+}
+
+func StatusErr(err error) Error {
+	return Error{
+		Type:    ErrStatus,
+		Cause:   err,
+		Code:    http.StatusConflict,
+		Message: err.Error(),
+	}
+}
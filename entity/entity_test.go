@@ -0,0 +1,148 @@
+package entity
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusTransition(t *testing.T) {
+	allowed := map[Status][]Status{
+		Draft:   {Open, Cancelled},
+		Open:    {Running, Cancelled},
+		Running: {Finished, Cancelled},
+	}
+	all := []Status{Draft, Open, Running, Finished, Cancelled}
+	for _, from := range all {
+		for _, to := range all {
+			want := false
+			for _, s := range allowed[from] {
+				want = want || s == to
+			}
+			err := from.Transition(to)
+			if want {
+				assert.NoError(t, err, "%s -> %s", from, to)
+				continue
+			}
+			require.Error(t, err, "%s -> %s", from, to)
+			assert.Equal(t, http.StatusConflict, err.(Error).Code)
+		}
+	}
+}
+
+func TestStatusIsFinal(t *testing.T) {
+	for _, s := range []Status{Draft, Open, Running} {
+		assert.False(t, s.IsFinal(), s)
+		assert.True(t, s.IsValid(), s)
+	}
+	for _, s := range []Status{Finished, Cancelled} {
+		assert.True(t, s.IsFinal(), s)
+		assert.True(t, s.IsValid(), s)
+	}
+	assert.False(t, Status("active").IsValid())
+}
+
+func TestStatusRequire(t *testing.T) {
+	assert.NoError(t, Open.Require(Open))
+	err := Running.Require(Open)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(Error).Code)
+	assert.Equal(t, "the tournament is running, expected open", err.(Error).Message)
+}
+
+func TestTournamentIsValid(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	tests := []struct {
+		name  string
+		tourn Tournament
+		valid bool
+	}{
+		{"no rake", Tournament{Name: "cup", Deposit: 100}, true},
+		{"empty name", Tournament{Deposit: 100}, false},
+		{"rake percent", Tournament{Name: "cup", Deposit: 100, RakePercent: 10}, true},
+		{"rake fee", Tournament{Name: "cup", Deposit: 100, RakeFee: 99}, true},
+		{"both", Tournament{Name: "cup", Deposit: 100, RakePercent: 10, RakeFee: 5}, false},
+		{"negative percent", Tournament{Name: "cup", Deposit: 100, RakePercent: -1}, false},
+		{"whole deposit", Tournament{Name: "cup", Deposit: 100, RakePercent: 100}, false},
+		{"fee of the deposit", Tournament{Name: "cup", Deposit: 100, RakeFee: 100}, false},
+		{"negative fee", Tournament{Name: "cup", Deposit: 100, RakeFee: -1}, false},
+		{"players", Tournament{Name: "cup", Deposit: 100, MinPlayers: 2, MaxPlayers: 2}, true},
+		{"no maximum", Tournament{Name: "cup", Deposit: 100, MinPlayers: 20}, true},
+		{"minimum over maximum", Tournament{Name: "cup", Deposit: 100, MinPlayers: 3, MaxPlayers: 2}, false},
+		{"negative players", Tournament{Name: "cup", Deposit: 100, MaxPlayers: -1}, false},
+		{"negative rounds", Tournament{Name: "cup", Deposit: 100, Rounds: -1}, false},
+		{"rating band", Tournament{Name: "cup", Deposit: 100, MinRating: 1400, MaxRating: 1600}, true},
+		{"no upper rating bound", Tournament{Name: "cup", Deposit: 100, MinRating: 1400}, true},
+		{"rating band crossed", Tournament{Name: "cup", Deposit: 100, MinRating: 1600, MaxRating: 1400}, false},
+		{"negative rating", Tournament{Name: "cup", Deposit: 100, MinRating: -1}, false},
+		{"teams", Tournament{Name: "cup", Deposit: 100, TeamSize: 2}, true},
+		{"team of one", Tournament{Name: "cup", Deposit: 100, TeamSize: 1}, false},
+		{"negative team size", Tournament{Name: "cup", Deposit: 100, TeamSize: -2}, false},
+		{"schedule", Tournament{Name: "cup", Deposit: 100, OpensAt: &now, EndsAt: &later}, true},
+		{"schedule out of order", Tournament{Name: "cup", Deposit: 100, StartsAt: &later, EndsAt: &now}, false},
+		{"steps at the same time", Tournament{Name: "cup", Deposit: 100, OpensAt: &now, StartsAt: &now}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tourn.IsValid()
+			assert.Equal(t, tt.valid, err == nil, "%v", err)
+		})
+	}
+}
+
+func TestEntryRake(t *testing.T) {
+	assert.Equal(t, 0, Tournament{Deposit: 100}.EntryRake())
+	assert.Equal(t, 10, Tournament{Deposit: 100, RakePercent: 10}.EntryRake())
+	assert.Equal(t, 3, Tournament{Deposit: 35, RakePercent: 10}.EntryRake(), "rounded down")
+	assert.Equal(t, 7, Tournament{Deposit: 35, RakeFee: 7}.EntryRake())
+}
+
+func TestTournamentDue(t *testing.T) {
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Second), now.Add(time.Second)
+	tests := []struct {
+		name  string
+		tourn Tournament
+		want  Status
+	}{
+		{"unscheduled", Tournament{Status: Draft}, ""},
+		{"opens", Tournament{Status: Draft, OpensAt: &before, StartsAt: &after}, Open},
+		{"opens now", Tournament{Status: Draft, OpensAt: &now}, Open},
+		{"not yet open", Tournament{Status: Draft, OpensAt: &after}, ""},
+		{"draft ignores startsAt", Tournament{Status: Draft, StartsAt: &before}, ""},
+		{"starts", Tournament{Status: Open, OpensAt: &before, StartsAt: &before}, Running},
+		{"ends", Tournament{Status: Running, EndsAt: &before}, Finished},
+		{"finished", Tournament{Status: Finished, EndsAt: &before}, ""},
+		{"cancelled", Tournament{Status: Cancelled, StartsAt: &before}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := tt.tourn.Due(now)
+			assert.Equal(t, tt.want, next)
+			assert.Equal(t, tt.want != "", ok)
+		})
+	}
+}
+
+func TestTournamentPlayers(t *testing.T) {
+	assert.False(t, Tournament{}.IsFull(1000), "no limit")
+	assert.False(t, Tournament{MaxPlayers: 2}.IsFull(1))
+	assert.True(t, Tournament{MaxPlayers: 2}.IsFull(2))
+	assert.False(t, Tournament{}.HasQuorum(0), "somebody has to play")
+	assert.True(t, Tournament{}.HasQuorum(1))
+	assert.False(t, Tournament{MinPlayers: 3}.HasQuorum(2))
+	assert.True(t, Tournament{MinPlayers: 3}.HasQuorum(3))
+}
+
+func TestGoesWhenFull(t *testing.T) {
+	assert.True(t, Format("sit_and_go").IsValid())
+	assert.False(t, Format("").IsValid())
+	sitAndGo := Tournament{Format: FormatSitAndGo, MaxPlayers: 3}
+	assert.False(t, sitAndGo.GoesWhenFull(2))
+	assert.True(t, sitAndGo.GoesWhenFull(3))
+	assert.False(t, Tournament{Format: FormatStandard, MaxPlayers: 3}.GoesWhenFull(3), "a standard tournament waits")
+}
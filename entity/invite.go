@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// Invite lets users into a private tournament. A code invite lets in
+// whoever presents Code, a personal invite the user UserID alone. MaxUses
+// limits the joins an invite lets in, 0 is no limit. A revoked invite lets
+// nobody in.
+type Invite struct {
+	ID           int       `json:"id"`
+	TournamentID int       `json:"tournamentId"`
+	Code         string    `json:"code,omitempty"`
+	UserID       int       `json:"userId,omitempty"`
+	MaxUses      int       `json:"maxUses,omitempty"`
+	Uses         int       `json:"uses"`
+	Revoked      bool      `json:"revoked,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+func (inv Invite) IsValid() error {
+	if inv.Code != "" && inv.UserID != 0 {
+		return RegErr(errors.New("an invite has either a code or a user"))
+	}
+	if inv.UserID < 0 {
+		return RegErr(errors.New("expected user id greater than 0"))
+	}
+	if inv.MaxUses < 0 {
+		return RegErr(errors.New("maxUses must not be negative"))
+	}
+	return nil
+}
+
+// Admit returns an error unless a user with inv, nil if they have none, may
+// join t. Anyone may join a public tournament.
+func (t Tournament) Admit(inv *Invite) error {
+	if !t.Private {
+		return nil
+	}
+	if inv == nil {
+		return RegErr(errors.New("the tournament is private, joining needs a valid invite"))
+	}
+	if inv.Revoked {
+		return RegErr(errors.New("the invite is revoked"))
+	}
+	if inv.MaxUses != 0 && inv.Uses >= inv.MaxUses {
+		return RegErr(errors.New("the invite is used up"))
+	}
+	return nil
+}
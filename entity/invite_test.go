@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInviteIsValid(t *testing.T) {
+	assert.NoError(t, Invite{}.IsValid(), "a code is drawn for an empty invite")
+	assert.NoError(t, Invite{Code: "vip", MaxUses: 10}.IsValid())
+	assert.NoError(t, Invite{UserID: 5}.IsValid())
+	assert.Error(t, Invite{Code: "vip", UserID: 5}.IsValid())
+	assert.Error(t, Invite{UserID: -1}.IsValid())
+	assert.Error(t, Invite{Code: "vip", MaxUses: -1}.IsValid())
+}
+
+func TestAdmit(t *testing.T) {
+	assert.NoError(t, Tournament{}.Admit(nil), "anyone joins a public tournament")
+	private := Tournament{Private: true}
+	assert.Error(t, private.Admit(nil))
+	assert.NoError(t, private.Admit(&Invite{UserID: 5}))
+	assert.NoError(t, private.Admit(&Invite{Code: "vip", MaxUses: 2, Uses: 1}))
+	assert.Error(t, private.Admit(&Invite{Code: "vip", MaxUses: 2, Uses: 2}), "the invite is used up")
+	assert.Error(t, private.Admit(&Invite{Code: "vip", Revoked: true}))
+}
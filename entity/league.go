@@ -0,0 +1,152 @@
+package entity
+
+import "sort"
+
+// Points a league player gets for a won and a drawn match.
+const (
+	WinPoints  = 3
+	DrawPoints = 1
+)
+
+// League is the schedule and the standings of a round-robin tournament. The
+// schedule is listed by rounds, a player with no match in a round sits it
+// out.
+type League struct {
+	TournamentID int              `json:"tournamentId"`
+	Rounds       [][]Match        `json:"rounds"`
+	Standings    []LeagueStanding `json:"standings"`
+}
+
+// LeagueStanding is a row of the standings table. Only played matches count.
+type LeagueStanding struct {
+	Rank   int    `json:"rank"`
+	UserID int    `json:"userId"`
+	Name   string `json:"name"`
+	Played int    `json:"played"`
+	Wins   int    `json:"wins"`
+	Draws  int    `json:"draws"`
+	Losses int    `json:"losses"`
+	Points int    `json:"points"`
+}
+
+// NewLeague builds the schedule and the standings of a tournament from its
+// users, in the order of registration, and its matches, ordered by round and
+// slot. Players are ranked by points, then by the points they took from each
+// other, then by wins and at last by the order of registration.
+func NewLeague(tID int, users []Winner, matches []Match) League {
+	l := League{TournamentID: tID, Rounds: rounds(matches), Standings: tally(users, matches)}
+	sort.SliceStable(l.Standings, func(i, j int) bool {
+		return l.Standings[i].Points > l.Standings[j].Points
+	})
+	for start := 0; start < len(l.Standings); {
+		end := start + 1
+		for end < len(l.Standings) && l.Standings[end].Points == l.Standings[start].Points {
+			end++
+		}
+		if end-start > 1 {
+			breakTie(l.Standings[start:end], matches)
+		}
+		start = end
+	}
+	for i := range l.Standings {
+		l.Standings[i].Rank = i + 1
+	}
+	return l
+}
+
+// tally counts the played matches of every user, in the order of users. A
+// bye counts as a win.
+func tally(users []Winner, matches []Match) []LeagueStanding {
+	standings := make([]LeagueStanding, len(users))
+	row := map[int]*LeagueStanding{}
+	for i, u := range users {
+		standings[i] = LeagueStanding{UserID: u.ID, Name: u.Name}
+		row[u.ID] = &standings[i]
+	}
+	for _, m := range matches {
+		p1, p2 := row[m.Player1], row[m.Player2]
+		if m.Bye && p1 != nil {
+			p1.Played++
+			p1.Wins++
+			continue
+		}
+		if !m.Played() || p1 == nil || p2 == nil {
+			continue
+		}
+		p1.Played++
+		p2.Played++
+		switch m.Winner {
+		case 0:
+			p1.Draws++
+			p2.Draws++
+		case m.Player1:
+			p1.Wins++
+			p2.Losses++
+		default:
+			p2.Wins++
+			p1.Losses++
+		}
+	}
+	for i := range standings {
+		s := &standings[i]
+		s.Points = s.Wins*WinPoints + s.Draws*DrawPoints
+	}
+	return standings
+}
+
+// breakTie orders players level on points by the points they took in the
+// matches among themselves, then by wins.
+func breakTie(tied []LeagueStanding, matches []Match) {
+	among := map[int]int{}
+	for _, s := range tied {
+		among[s.UserID] = 0
+	}
+	for _, m := range matches {
+		_, ok1 := among[m.Player1]
+		_, ok2 := among[m.Player2]
+		if !m.Played() || m.Bye || !ok1 || !ok2 {
+			continue
+		}
+		if m.Draw {
+			among[m.Player1] += DrawPoints
+			among[m.Player2] += DrawPoints
+		} else {
+			among[m.Winner] += WinPoints
+		}
+	}
+	sort.SliceStable(tied, func(i, j int) bool {
+		a, b := tied[i], tied[j]
+		if among[a.UserID] != among[b.UserID] {
+			return among[a.UserID] > among[b.UserID]
+		}
+		return a.Wins > b.Wins
+	})
+}
+
+// Complete reports whether every match of the schedule is played.
+func (l League) Complete() bool {
+	for _, r := range l.Rounds {
+		if !roundPlayed(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func roundPlayed(round []Match) bool {
+	for _, m := range round {
+		if !m.Played() {
+			return false
+		}
+	}
+	return true
+}
+
+// Ranking returns the users from the top of the standings down.
+func (l League) Ranking() []int {
+	ranking := make([]int, len(l.Standings))
+	for i, s := range l.Standings {
+		ranking[i] = s.UserID
+	}
+	return ranking
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchRecord(t *testing.T) {
+	m := Match{Round: 1, Player1: 4, Player2: 7}
+	assert.False(t, m.Played())
+	drawn, err := m.Record(Match{Draw: true})
+	require.NoError(t, err)
+	assert.True(t, drawn.Played())
+	assert.Equal(t, 0, drawn.Loser())
+	_, err = drawn.Record(Match{Winner: 4})
+	assert.Error(t, err, "the match is already decided")
+	_, err = m.Record(Match{Draw: true, Winner: 4})
+	assert.Error(t, err, "a drawn match has no winner")
+	won, err := m.Record(Match{Winner: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 7, won.Loser())
+}
+
+func TestNewLeague(t *testing.T) {
+	users := []Winner{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}}
+	matches := []Match{
+		{Round: 1, Slot: 0, Player1: 1, Player2: 2, Winner: 2},
+		{Round: 1, Slot: 1, Player1: 3, Player2: 4, Winner: 4},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 3, Winner: 3},
+		{Round: 2, Slot: 1, Player1: 2, Player2: 4},
+		{Round: 3, Slot: 0, Player1: 1, Player2: 4},
+		{Round: 3, Slot: 1, Player1: 2, Player2: 3},
+	}
+	l := NewLeague(5, users, matches)
+	assert.Equal(t, 5, l.TournamentID)
+	assert.Len(t, l.Rounds, 3)
+	assert.False(t, l.Complete())
+	assert.Equal(t, []LeagueStanding{
+		{Rank: 1, UserID: 4, Name: "d", Played: 1, Wins: 1, Points: 3},
+		{Rank: 2, UserID: 2, Name: "b", Played: 1, Wins: 1, Points: 3},
+		{Rank: 3, UserID: 3, Name: "c", Played: 2, Wins: 1, Losses: 1, Points: 3},
+		{Rank: 4, UserID: 1, Name: "a", Played: 2, Losses: 2},
+	}, l.Standings, "ties go to the points taken from each other, then to the first registered")
+
+	matches[3].Draw = true
+	matches[4].Winner = 1
+	matches[5].Winner = 3
+	l = NewLeague(5, users, matches)
+	assert.True(t, l.Complete())
+	assert.Equal(t, []int{3, 2, 4, 1}, l.Ranking())
+	assert.Equal(t, LeagueStanding{Rank: 3, UserID: 4, Name: "d", Played: 3, Wins: 1, Draws: 1, Losses: 1, Points: 4}, l.Standings[2])
+}
@@ -0,0 +1,48 @@
+package entity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor points at the last item of a page. Value holds the sort key of that
+// item when a listing is not sorted by ID.
+type Cursor struct {
+	ID    int    `json:"id"`
+	Value string `json:"v,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor produced by Encode, an empty string is the
+// beginning of a listing.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ReqErr(errors.New("invalid cursor"))
+	}
+	err = json.Unmarshal(b, &c)
+	if err != nil || c.ID <= 0 {
+		return c, ReqErr(errors.New("invalid cursor"))
+	}
+	return c, nil
+}
+
+// Page is the common part of every paginated request.
+type Page struct {
+	Cursor Cursor
+	Limit  int
+}
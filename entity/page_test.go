@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	for _, c := range []Cursor{{ID: 1}, {ID: 42, Value: "700"}, {ID: 7, Value: "alice"}} {
+		got, err := DecodeCursor(c.Encode())
+		require.NoError(t, err)
+		assert.Equal(t, c, got)
+	}
+
+	c, err := DecodeCursor("")
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{}, c, "an empty cursor is the first page")
+
+	for _, s := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id":0}`)),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id":-3}`)),
+	} {
+		_, err := DecodeCursor(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Payouts splits the prize among the best placed players. Places lists the
+// percentage of the prize for every paid place, TopPercent pays equal shares
+// to the best TopPercent of the field. The zero value pays the whole prize to
+// the winner.
+type Payouts struct {
+	Places     []int `json:"places,omitempty"`
+	TopPercent int   `json:"topPercent,omitempty"`
+}
+
+func (p Payouts) IsValid() error {
+	if len(p.Places) > 0 && p.TopPercent != 0 {
+		return RegErr(errors.New("payouts can't have both places and topPercent"))
+	}
+	if p.TopPercent < 0 || p.TopPercent > 100 {
+		return RegErr(errors.New("topPercent must be between 1 and 100"))
+	}
+	sum := 0
+	for i, pct := range p.Places {
+		if pct <= 0 {
+			return RegErr(fmt.Errorf("place %d must get more than 0%%", i+1))
+		}
+		sum += pct
+	}
+	if len(p.Places) > 0 && sum != 100 {
+		return RegErr(fmt.Errorf("places must add up to 100%%, not %d%%", sum))
+	}
+	return nil
+}
+
+// PaidPlaces returns how many places are paid when players take part.
+func (p Payouts) PaidPlaces(players int) int {
+	n := 1
+	switch {
+	case len(p.Places) > 0:
+		n = len(p.Places)
+	case p.TopPercent > 0:
+		n = (players*p.TopPercent + 99) / 100
+	}
+	if n > players {
+		n = players
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Split returns the amounts of the paid places, from the first one down. If
+// fewer players take part than there are places, the shares of the filled
+// places are scaled up so the whole prize is paid. Points lost to integer
+// rounding go one by one to the best places.
+func (p Payouts) Split(prize, players int) []int {
+	n := p.PaidPlaces(players)
+	weights := make([]int, n)
+	total := 0
+	for i := range weights {
+		weights[i] = 1
+		if len(p.Places) > 0 {
+			weights[i] = p.Places[i]
+		}
+		total += weights[i]
+	}
+	amounts := make([]int, n)
+	left := prize
+	for i, w := range weights {
+		amounts[i] = prize * w / total
+		left -= amounts[i]
+	}
+	for i := 0; left > 0; i = (i + 1) % n {
+		amounts[i]++
+		left--
+	}
+	return amounts
+}
+
+// Placing is the final place of a participant and the points they won.
+type Placing struct {
+	UserID int
+	Place  int
+	Amount int
+}
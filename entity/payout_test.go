@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayoutsSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		payouts Payouts
+		prize   int
+		players int
+		want    []int
+	}{
+		{"winner takes all", Payouts{}, 270, 3, []int{270}},
+		{"no players", Payouts{}, 0, 0, []int{0}},
+		{"places", Payouts{Places: []int{50, 30, 20}}, 1000, 5, []int{500, 300, 200}},
+		{"remainder to the first place", Payouts{Places: []int{50, 30, 20}}, 1001, 5, []int{501, 300, 200}},
+		{"remainder to the best places", Payouts{Places: []int{50, 30, 20}}, 999, 5, []int{500, 300, 199}},
+		{"fewer players than places", Payouts{Places: []int{50, 30, 20}}, 100, 2, []int{63, 37}},
+		{"one player", Payouts{Places: []int{50, 30, 20}}, 100, 1, []int{100}},
+		{"top percent", Payouts{TopPercent: 25}, 100, 10, []int{34, 33, 33}},
+		{"top percent of a small field", Payouts{TopPercent: 10}, 100, 3, []int{100}},
+		{"top percent of everyone", Payouts{TopPercent: 100}, 7, 3, []int{3, 2, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.payouts.Split(tt.prize, tt.players)
+			assert.Equal(t, tt.want, got)
+			sum := 0
+			for _, a := range got {
+				sum += a
+			}
+			assert.Equal(t, tt.prize, sum, "the whole prize is paid")
+		})
+	}
+}
+
+func TestPayoutsIsValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		payouts Payouts
+		valid   bool
+	}{
+		{"zero value", Payouts{}, true},
+		{"places", Payouts{Places: []int{50, 30, 20}}, true},
+		{"top percent", Payouts{TopPercent: 10}, true},
+		{"both", Payouts{Places: []int{100}, TopPercent: 10}, false},
+		{"places under 100%", Payouts{Places: []int{50, 30}}, false},
+		{"empty place", Payouts{Places: []int{100, 0}}, false},
+		{"top percent over 100", Payouts{TopPercent: 101}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payouts.IsValid()
+			assert.Equal(t, tt.valid, err == nil, "%v", err)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// SpendOrder is the order in which tournament deposits spend a user's cash
+// and bonus points.
+type SpendOrder string
+
+const (
+	CashFirst  SpendOrder = "cash_first"
+	BonusFirst SpendOrder = "bonus_first"
+)
+
+func (o SpendOrder) IsValid() bool {
+	return o == CashFirst || o == BonusFirst
+}
+
+// Policy is the economic policy of registration. A new user must bring an
+// initial balance of at least MinBalance, gets the signup Bonus and is
+// charged the Fee. A change of the policy is a new version with a new ID, so
+// the policy a user registered under stays on record.
+//
+// The bonus converts to cash once the user has made tournament deposits of
+// Wagering times the bonus, with 0 it is cash at once. SpendOrder applies to
+// all deposits made while the policy is in force.
+type Policy struct {
+	ID         int        `json:"id"`
+	Fee        int        `json:"fee"`
+	Bonus      int        `json:"bonus"`
+	MinBalance int        `json:"minBalance"`
+	Wagering   int        `json:"wagering"`
+	SpendOrder SpendOrder `json:"spendOrder"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// DefaultPolicy is in force until the policy is changed.
+var DefaultPolicy = Policy{Fee: 300, MinBalance: 300, SpendOrder: CashFirst}
+
+func (p Policy) IsValid() error {
+	if p.Fee < 0 || p.Bonus < 0 || p.MinBalance < 0 || p.Wagering < 0 {
+		return RegErr(errors.New("fee, bonus, minBalance and wagering must not be negative"))
+	}
+	if !p.SpendOrder.IsValid() {
+		return RegErr(errors.New("unknown spend order"))
+	}
+	if p.Wagering != 0 && p.MinBalance < p.Fee {
+		return RegErr(errors.New("minBalance must cover the fee, the bonus isn't cash until it is wagered"))
+	}
+	if p.MinBalance+p.Bonus < p.Fee {
+		return RegErr(errors.New("minBalance and bonus must cover the fee"))
+	}
+	return nil
+}
+
+// Same reports whether p and other have the same terms.
+func (p Policy) Same(other Policy) bool {
+	return p.Fee == other.Fee && p.Bonus == other.Bonus && p.MinBalance == other.MinBalance &&
+		p.Wagering == other.Wagering && p.SpendOrder == other.SpendOrder
+}
+
+// Charge splits a deposit of amount into the parts u pays in cash and in
+// bonus points by the spend order. u must have the funds for it.
+func (p Policy) Charge(u User, amount int) (cash, bonus int) {
+	if p.SpendOrder == BonusFirst {
+		bonus = amount
+		if bonus > u.Bonus {
+			bonus = u.Bonus
+		}
+		return amount - bonus, bonus
+	}
+	cash = amount
+	if cash > u.Balance {
+		cash = u.Balance
+	}
+	return cash, amount - cash
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyIsValid(t *testing.T) {
+	assert.NoError(t, DefaultPolicy.IsValid())
+	assert.NoError(t, Policy{SpendOrder: CashFirst}.IsValid(), "registration may be free")
+	assert.NoError(t, Policy{Fee: 300, Bonus: 200, MinBalance: 100, SpendOrder: BonusFirst}.IsValid())
+	assert.Error(t, Policy{Fee: 300, Bonus: 100, MinBalance: 100, SpendOrder: CashFirst}.IsValid(), "the fee isn't covered")
+	assert.Error(t, Policy{Fee: 300, Bonus: 200, MinBalance: 100, Wagering: 2, SpendOrder: CashFirst}.IsValid(),
+		"a wagered bonus doesn't cover the fee")
+	assert.NoError(t, Policy{Fee: 100, Bonus: 200, MinBalance: 100, Wagering: 2, SpendOrder: CashFirst}.IsValid())
+	assert.Error(t, Policy{}.IsValid(), "no spend order")
+	assert.Error(t, Policy{Fee: -1, SpendOrder: CashFirst}.IsValid())
+	assert.Error(t, Policy{Bonus: -1, SpendOrder: CashFirst}.IsValid())
+	assert.Error(t, Policy{MinBalance: -1, SpendOrder: CashFirst}.IsValid())
+	assert.Error(t, Policy{Wagering: -1, SpendOrder: CashFirst}.IsValid())
+}
+
+func TestPolicySame(t *testing.T) {
+	assert.True(t, DefaultPolicy.Same(Policy{ID: 3, Fee: 300, MinBalance: 300, SpendOrder: CashFirst}), "only the terms count")
+	assert.False(t, DefaultPolicy.Same(Policy{Fee: 300, Bonus: 1, MinBalance: 300, SpendOrder: CashFirst}))
+	assert.False(t, DefaultPolicy.Same(Policy{Fee: 300, MinBalance: 300, Wagering: 1, SpendOrder: CashFirst}))
+	assert.False(t, DefaultPolicy.Same(Policy{Fee: 300, MinBalance: 300, SpendOrder: BonusFirst}))
+}
+
+func TestPolicyCharge(t *testing.T) {
+	u := User{Balance: 100, Bonus: 50}
+	for _, tc := range []struct {
+		order       SpendOrder
+		amount      int
+		cash, bonus int
+	}{
+		{CashFirst, 80, 80, 0},
+		{CashFirst, 120, 100, 20},
+		{BonusFirst, 30, 0, 30},
+		{BonusFirst, 120, 70, 50},
+	} {
+		cash, bonus := Policy{SpendOrder: tc.order}.Charge(u, tc.amount)
+		assert.Equal(t, tc.cash, cash, "%s %d", tc.order, tc.amount)
+		assert.Equal(t, tc.bonus, bonus, "%s %d", tc.order, tc.amount)
+	}
+}
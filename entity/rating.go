@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+// InitialRating is the rating of a new user.
+const InitialRating = 1500
+
+// RatingChange is a change of a user's rating by a match, or by the ranking
+// of a score tournament. Rating is the rating after the change.
+type RatingChange struct {
+	UserID       int       `json:"userId"`
+	TournamentID int       `json:"tournamentId"`
+	Change       int       `json:"change"`
+	Rating       int       `json:"rating"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// TournamentRating sums up the changes of a user's rating in a tournament.
+type TournamentRating struct {
+	TournamentID int `json:"tournamentId"`
+	Before       int `json:"before"`
+	After        int `json:"after"`
+	Games        int `json:"games"`
+}
+
+// UserRating is a user's rating with its history by tournament, the latest
+// tournament first.
+type UserRating struct {
+	UserID  int                `json:"userId"`
+	Name    string             `json:"name"`
+	Rating  int                `json:"rating"`
+	History []TournamentRating `json:"history"`
+}
+
+// NewUserRating builds the rating of u from the changes of it, oldest first.
+func NewUserRating(u User, changes []RatingChange) UserRating {
+	r := UserRating{UserID: u.ID, Name: u.Name, Rating: u.Rating, History: []TournamentRating{}}
+	index := map[int]int{}
+	for _, c := range changes {
+		i, ok := index[c.TournamentID]
+		if !ok {
+			i = len(r.History)
+			index[c.TournamentID] = i
+			r.History = append(r.History, TournamentRating{TournamentID: c.TournamentID, Before: c.Rating - c.Change})
+		}
+		r.History[i].After = c.Rating
+		r.History[i].Games++
+	}
+	for i, j := 0, len(r.History)-1; i < j; i, j = i+1, j-1 {
+		r.History[i], r.History[j] = r.History[j], r.History[i]
+	}
+	return r
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUserRating(t *testing.T) {
+	u := User{ID: 4, Name: "d", Rating: 1519}
+	r := NewUserRating(u, []RatingChange{
+		{UserID: 4, TournamentID: 1, Change: 16, Rating: 1516},
+		{UserID: 4, TournamentID: 1, Change: -1, Rating: 1515},
+		{UserID: 4, TournamentID: 3, Change: 4, Rating: 1519},
+	})
+	assert.Equal(t, UserRating{UserID: 4, Name: "d", Rating: 1519, History: []TournamentRating{
+		{TournamentID: 3, Before: 1515, After: 1519, Games: 1},
+		{TournamentID: 1, Before: 1500, After: 1515, Games: 2},
+	}}, r)
+	assert.Equal(t, []TournamentRating{}, NewUserRating(User{Rating: InitialRating}, nil).History)
+}
+
+func TestAllowsRating(t *testing.T) {
+	band := Tournament{MinRating: 1400, MaxRating: 1600}
+	assert.NoError(t, band.AllowsRating(1400), "bounds are inclusive")
+	assert.NoError(t, band.AllowsRating(1600))
+	assert.Error(t, band.AllowsRating(1399))
+	assert.Error(t, band.AllowsRating(1601))
+	assert.NoError(t, Tournament{MinRating: 1400}.AllowsRating(3000))
+	assert.NoError(t, Tournament{}.AllowsRating(0))
+}
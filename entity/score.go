@@ -0,0 +1,52 @@
+package entity
+
+import "sort"
+
+// TieBreak orders the players of a score tournament who have the same score.
+type TieBreak string
+
+const (
+	// TieFirstSubmitted puts the player who submitted the score first ahead.
+	TieFirstSubmitted TieBreak = "first_submitted"
+	// TieFirstRegistered puts the player who registered first ahead.
+	TieFirstRegistered TieBreak = "first_registered"
+	// TieRandom draws the order of tied players from the tournament's seed.
+	TieRandom TieBreak = "random"
+)
+
+func (t TieBreak) IsValid() bool {
+	switch t {
+	case TieFirstSubmitted, TieFirstRegistered, TieRandom:
+		return true
+	}
+	return false
+}
+
+// Standing is a row of the leaderboard of a score tournament. Players with
+// the same score share the rank.
+type Standing struct {
+	Rank   int    `json:"rank"`
+	UserID int    `json:"userId"`
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+}
+
+// Leaderboard ranks the users who have submitted a score, best first.
+func Leaderboard(users []Winner) []Standing {
+	l := []Standing{}
+	for _, u := range users {
+		if u.Score != nil {
+			l = append(l, Standing{UserID: u.ID, Name: u.Name, Score: *u.Score})
+		}
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].Score > l[j].Score
+	})
+	for i := range l {
+		l[i].Rank = i + 1
+		if i > 0 && l[i].Score == l[i-1].Score {
+			l[i].Rank = l[i-1].Rank
+		}
+	}
+	return l
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderboard(t *testing.T) {
+	score := func(s int) *int {
+		return &s
+	}
+	users := []Winner{
+		{ID: 1, Name: "a", Score: score(10)},
+		{ID: 2, Name: "b"},
+		{ID: 3, Name: "c", Score: score(30)},
+		{ID: 4, Name: "d", Score: score(10)},
+		{ID: 5, Name: "e", Score: score(5)},
+	}
+	assert.Equal(t, []Standing{
+		{Rank: 1, UserID: 3, Name: "c", Score: 30},
+		{Rank: 2, UserID: 1, Name: "a", Score: 10},
+		{Rank: 2, UserID: 4, Name: "d", Score: 10},
+		{Rank: 4, UserID: 5, Name: "e", Score: 5},
+	}, Leaderboard(users), "tied users share the rank")
+	assert.Equal(t, []Standing{}, Leaderboard([]Winner{{ID: 2}}))
+}
+
+func TestTieBreakIsValid(t *testing.T) {
+	for _, tb := range []TieBreak{TieFirstSubmitted, TieFirstRegistered, TieRandom} {
+		assert.True(t, tb.IsValid(), tb)
+	}
+	assert.False(t, TieBreak("coin").IsValid())
+}
@@ -0,0 +1,72 @@
+package entity
+
+import "sort"
+
+// Swiss is the pairings and the standings of a Swiss tournament. Every round
+// is paired once the one before it is played.
+type Swiss struct {
+	TournamentID int             `json:"tournamentId"`
+	Rounds       [][]Match       `json:"rounds"`
+	Standings    []SwissStanding `json:"standings"`
+}
+
+// SwissStanding is a row of the standings table. Buchholz is the sum of the
+// points of the opponents the player has met, byes left out.
+type SwissStanding struct {
+	LeagueStanding
+	Buchholz int `json:"buchholz"`
+}
+
+// NewSwiss builds the pairings and the standings of a tournament from its
+// users, in the order of registration, and its matches, ordered by round and
+// slot. Players are ranked by points, then by Buchholz and at last by the
+// order of registration. Points are scored as in a league.
+func NewSwiss(tID int, users []Winner, matches []Match) Swiss {
+	s := Swiss{TournamentID: tID, Rounds: rounds(matches), Standings: []SwissStanding{}}
+	points := map[int]int{}
+	for _, row := range tally(users, matches) {
+		s.Standings = append(s.Standings, SwissStanding{LeagueStanding: row})
+		points[row.UserID] = row.Points
+	}
+	for i := range s.Standings {
+		row := &s.Standings[i]
+		for _, m := range matches {
+			switch {
+			case !m.Played() || m.Bye:
+			case m.Player1 == row.UserID:
+				row.Buchholz += points[m.Player2]
+			case m.Player2 == row.UserID:
+				row.Buchholz += points[m.Player1]
+			}
+		}
+	}
+	sort.SliceStable(s.Standings, func(i, j int) bool {
+		a, b := s.Standings[i], s.Standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		return a.Buchholz > b.Buchholz
+	})
+	for i := range s.Standings {
+		s.Standings[i].Rank = i + 1
+	}
+	return s
+}
+
+// Played returns the number of rounds played to the end.
+func (s Swiss) Played() int {
+	n := 0
+	for n < len(s.Rounds) && roundPlayed(s.Rounds[n]) {
+		n++
+	}
+	return n
+}
+
+// Ranking returns the users from the top of the standings down.
+func (s Swiss) Ranking() []int {
+	ranking := make([]int, len(s.Standings))
+	for i, row := range s.Standings {
+		ranking[i] = row.UserID
+	}
+	return ranking
+}
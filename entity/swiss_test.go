@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSwiss(t *testing.T) {
+	users := []Winner{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}, {ID: 4, Name: "d"}, {ID: 5, Name: "e"}}
+	matches := []Match{
+		{Round: 1, Slot: 0, Player1: 1, Player2: 2, Winner: 1},
+		{Round: 1, Slot: 1, Player1: 3, Player2: 4, Draw: true},
+		{Round: 1, Slot: 2, Player1: 5, Winner: 5, Bye: true},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 5, Draw: true},
+		{Round: 2, Slot: 1, Player1: 2, Player2: 3},
+		{Round: 2, Slot: 2, Player1: 4, Winner: 4, Bye: true},
+	}
+	s := NewSwiss(7, users, matches)
+	assert.Equal(t, 7, s.TournamentID)
+	assert.Len(t, s.Rounds, 2)
+	assert.Equal(t, 1, s.Played())
+	row := func(rank, id int, name string, played, wins, draws, losses, buchholz int) SwissStanding {
+		return SwissStanding{LeagueStanding: LeagueStanding{
+			Rank: rank, UserID: id, Name: name, Played: played, Wins: wins, Draws: draws, Losses: losses,
+			Points: wins*WinPoints + draws*DrawPoints,
+		}, Buchholz: buchholz}
+	}
+	assert.Equal(t, []SwissStanding{
+		row(1, 1, "a", 2, 1, 1, 0, 4),
+		row(2, 5, "e", 2, 1, 1, 0, 4),
+		row(3, 4, "d", 2, 1, 1, 0, 1),
+		row(4, 3, "c", 1, 0, 1, 0, 4),
+		row(5, 2, "b", 1, 0, 0, 1, 4),
+	}, s.Standings, "a bye is a win that adds nothing to Buchholz")
+	assert.Equal(t, []int{1, 5, 4, 3, 2}, s.Ranking())
+
+	matches[4].Winner = 2
+	assert.Equal(t, 2, NewSwiss(7, users, matches).Played())
+}
+
+func TestSwissRounds(t *testing.T) {
+	for _, tt := range []struct {
+		rounds, players, want int
+	}{
+		{0, 1, 0},
+		{0, 2, 1},
+		{0, 4, 2},
+		{0, 5, 3},
+		{0, 9, 4},
+		{2, 8, 2},
+		{10, 4, 3},
+		{3, 1, 0},
+	} {
+		assert.Equal(t, tt.want, Tournament{Rounds: tt.rounds}.SwissRounds(tt.players), "%d rounds, %d players", tt.rounds, tt.players)
+	}
+}
@@ -0,0 +1,85 @@
+package entity
+
+import "errors"
+
+// Team is a group of users that enters team tournaments as a single player.
+// The captain creates the team, is its first member and registers it for
+// tournaments.
+type Team struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	CaptainID int          `json:"captainId"`
+	Members   []TeamMember `json:"members"`
+}
+
+func (t Team) IsValid() error {
+	if t.Name == "" {
+		return RegErr(errors.New("empty name"))
+	}
+	if t.CaptainID <= 0 {
+		return RegErr(errors.New("a team needs a captain"))
+	}
+	return nil
+}
+
+// TeamMember is a member of a team. Share is the member's weight in the
+// split of the prizes the team wins.
+type TeamMember struct {
+	UserID int    `json:"userId"`
+	Name   string `json:"name"`
+	Share  int    `json:"share"`
+}
+
+// Member is a member of a team entry with the points they put in, the part
+// of them paid in bonus points and the part taken by the house, their share
+// of the prize and, once the tournament is finished, the part of the prize
+// they got.
+type Member struct {
+	UserID     int    `json:"userId"`
+	Name       string `json:"name"`
+	Stake      int    `json:"stake"`
+	BonusStake int    `json:"-"`
+	Rake       int    `json:"-"`
+	Share      int    `json:"share"`
+	Payout     int    `json:"payout,omitempty"`
+}
+
+// BonusPart returns the part of amount the member won with bonus points, in
+// proportion to the part of their stake paid in bonus points.
+func (m Member) BonusPart(amount int) int {
+	if m.Stake == 0 {
+		return 0
+	}
+	return amount * m.BonusStake / m.Stake
+}
+
+// Payers returns who paid for the entry, the members of a team or the user
+// alone.
+func (e Entry) Payers() []Member {
+	if e.TeamID == 0 {
+		return []Member{{UserID: e.UserID, Stake: e.Stake, BonusStake: e.BonusStake, Rake: e.Rake, Share: 1}}
+	}
+	return e.Members
+}
+
+// Split returns the parts of amount the payers of the entry get by their
+// shares, in the order of Payers. Points lost to integer rounding go one by
+// one to the first payers, the captain first.
+func (e Entry) Split(amount int) []int {
+	payers := e.Payers()
+	total := 0
+	for _, m := range payers {
+		total += m.Share
+	}
+	amounts := make([]int, len(payers))
+	left := amount
+	for i, m := range payers {
+		amounts[i] = amount * m.Share / total
+		left -= amounts[i]
+	}
+	for i := 0; left > 0; i = (i + 1) % len(payers) {
+		amounts[i]++
+		left--
+	}
+	return amounts
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeamIsValid(t *testing.T) {
+	assert.NoError(t, Team{Name: "red", CaptainID: 1}.IsValid())
+	assert.Error(t, Team{CaptainID: 1}.IsValid())
+	assert.Error(t, Team{Name: "red"}.IsValid())
+}
+
+func TestEntryPayers(t *testing.T) {
+	assert.Equal(t, []Member{{UserID: 4, Stake: 100, BonusStake: 40, Rake: 10, Share: 1}},
+		Entry{UserID: 4, Stake: 100, BonusStake: 40, Rake: 10}.Payers())
+	members := []Member{{UserID: 4, Stake: 200, Share: 1}, {UserID: 5, Share: 3}}
+	assert.Equal(t, members, Entry{UserID: 4, Stake: 200, TeamID: 2, Members: members}.Payers())
+}
+
+func TestMemberBonusPart(t *testing.T) {
+	assert.Equal(t, 120, Member{Stake: 100, BonusStake: 40}.BonusPart(300))
+	assert.Equal(t, 0, Member{Stake: 100}.BonusPart(300))
+	assert.Equal(t, 0, Member{}.BonusPart(300), "the member paid nothing")
+}
+
+func TestEntrySplit(t *testing.T) {
+	team := func(shares ...int) Entry {
+		e := Entry{UserID: 1, TeamID: 1}
+		for i, s := range shares {
+			e.Members = append(e.Members, Member{UserID: i + 1, Share: s})
+		}
+		return e
+	}
+	tests := []struct {
+		name   string
+		entry  Entry
+		amount int
+		want   []int
+	}{
+		{"alone", Entry{UserID: 4}, 300, []int{300}},
+		{"equal shares", team(1, 1), 300, []int{150, 150}},
+		{"weighted", team(1, 2), 300, []int{100, 200}},
+		{"remainder to the captain", team(1, 2), 400, []int{134, 266}},
+		{"remainder one by one", team(1, 1, 1), 200, []int{67, 67, 66}},
+		{"nothing", team(1, 3), 0, []int{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.entry.Split(tt.amount))
+		})
+	}
+}
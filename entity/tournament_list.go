@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TournamentSummary is a tournament without its participants.
+type TournamentSummary struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	Deposit      int    `json:"deposit"`
+	Prize        int    `json:"prize"`
+	Rake         int    `json:"rake"`
+	Participants int    `json:"participants"`
+	Status       Status `json:"status"`
+	Format       Format `json:"format"`
+	Winner       int    `json:"winner,omitempty"`
+}
+
+// TournamentFilter selects a page of tournaments, newest first. Nil
+// MinDeposit and MaxDeposit are unbounded, both bounds are inclusive.
+type TournamentFilter struct {
+	Page
+	Statuses   []Status
+	MinDeposit *int
+	MaxDeposit *int
+}
+
+func (f TournamentFilter) IsValid() error {
+	for _, s := range f.Statuses {
+		if !s.IsValid() {
+			return ReqErr(fmt.Errorf("unknown status %q", s))
+		}
+	}
+	if f.MinDeposit != nil && f.MaxDeposit != nil && *f.MinDeposit > *f.MaxDeposit {
+		return ReqErr(errors.New("minimal deposit is greater than maximal"))
+	}
+	return nil
+}
+
+func (f TournamentFilter) Match(t TournamentSummary) bool {
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, s := range f.Statuses {
+			if t.Status == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.MinDeposit != nil && t.Deposit < *f.MinDeposit {
+		return false
+	}
+	if f.MaxDeposit != nil && t.Deposit > *f.MaxDeposit {
+		return false
+	}
+	return true
+}
+
+type TournamentPage struct {
+	Tournaments []TournamentSummary `json:"tournaments"`
+	Total       int                 `json:"total"`
+	NextCursor  string              `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTournamentFilterIsValid(t *testing.T) {
+	assert.NoError(t, TournamentFilter{}.IsValid())
+	assert.NoError(t, TournamentFilter{Statuses: []Status{Open, Finished, Cancelled}}.IsValid())
+	assert.Error(t, TournamentFilter{Statuses: []Status{Open, "paused"}}.IsValid())
+	assert.NoError(t, TournamentFilter{MinDeposit: intPtr(5), MaxDeposit: intPtr(5)}.IsValid())
+	assert.Error(t, TournamentFilter{MinDeposit: intPtr(6), MaxDeposit: intPtr(5)}.IsValid())
+}
+
+func TestTournamentFilterMatch(t *testing.T) {
+	tr := TournamentSummary{ID: 1, Deposit: 100, Status: Open}
+	tests := []struct {
+		name   string
+		filter TournamentFilter
+		match  bool
+	}{
+		{"any", TournamentFilter{}, true},
+		{"status", TournamentFilter{Statuses: []Status{Finished, Open}}, true},
+		{"other status", TournamentFilter{Statuses: []Status{Finished}}, false},
+		{"bounds are inclusive", TournamentFilter{MinDeposit: intPtr(100), MaxDeposit: intPtr(100)}, true},
+		{"below min", TournamentFilter{MinDeposit: intPtr(101)}, false},
+		{"above max", TournamentFilter{MaxDeposit: intPtr(99)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.filter.Match(tr))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package entity
+
+import "time"
+
+type TransactionType string
+
+const (
+	TxRegistration TransactionType = "registration"
+	TxRegFee       TransactionType = "registration_fee"
+	TxBonus        TransactionType = "signup_bonus"
+	TxTake         TransactionType = "take"
+	TxFund         TransactionType = "fund"
+	TxDeposit      TransactionType = "deposit"
+	TxPrize        TransactionType = "prize"
+	TxRefund       TransactionType = "refund"
+	TxClose        TransactionType = "account_closed"
+	// TxConvert moves bonus points whose wagering requirement is met to
+	// the user's cash.
+	TxConvert TransactionType = "bonus_converted"
+	// TxRake moves the rake of an entry from the escrow to the house. It
+	// never shows up in a user's history.
+	TxRake TransactionType = "rake"
+)
+
+func (t TransactionType) IsValid() bool {
+	switch t {
+	case TxRegistration, TxRegFee, TxBonus, TxConvert, TxTake, TxFund, TxDeposit, TxPrize, TxRefund, TxClose:
+		return true
+	}
+	return false
+}
+
+// Transaction is a ledger record of a single change of a user's balance.
+// Bonus marks changes of the bonus points, whose Balance is the bonus points
+// after the change.
+type Transaction struct {
+	ID           int             `json:"id"`
+	UserID       int             `json:"userId"`
+	Type         TransactionType `json:"type"`
+	Amount       int             `json:"amount"`
+	Bonus        bool            `json:"bonus,omitempty"`
+	Balance      int             `json:"balance"`
+	TournamentID int             `json:"tournamentId,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// TransactionFilter selects a user's transactions, newest first. Zero From
+// and To are unbounded.
+type TransactionFilter struct {
+	Page
+	UserID int
+	Types  []TransactionType
+	From   time.Time
+	To     time.Time
+}
+
+func (f TransactionFilter) Match(t Transaction) bool {
+	if t.UserID != f.UserID {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, typ := range f.Types {
+			if t.Type == typ {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.From.IsZero() && t.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionFilterMatch(t *testing.T) {
+	noon := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	tx := Transaction{UserID: 1, Type: TxDeposit, CreatedAt: noon}
+	tests := []struct {
+		name   string
+		filter TransactionFilter
+		match  bool
+	}{
+		{"user", TransactionFilter{UserID: 1}, true},
+		{"other user", TransactionFilter{UserID: 2}, false},
+		{"type", TransactionFilter{UserID: 1, Types: []TransactionType{TxPrize, TxDeposit}}, true},
+		{"other type", TransactionFilter{UserID: 1, Types: []TransactionType{TxPrize}}, false},
+		{"from is inclusive", TransactionFilter{UserID: 1, From: noon}, true},
+		{"after from", TransactionFilter{UserID: 1, From: noon.Add(time.Second)}, false},
+		{"to is exclusive", TransactionFilter{UserID: 1, To: noon}, false},
+		{"before to", TransactionFilter{UserID: 1, To: noon.Add(time.Second)}, true},
+		{"within", TransactionFilter{UserID: 1, From: noon.Add(-time.Hour), To: noon.Add(time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, tt.filter.Match(tx))
+		})
+	}
+}
+
+func TestTransactionTypeIsValid(t *testing.T) {
+	for _, typ := range []TransactionType{TxRegistration, TxRegFee, TxTake, TxFund, TxDeposit, TxPrize, TxRefund, TxClose} {
+		assert.True(t, typ.IsValid(), typ)
+	}
+	assert.False(t, TxRake.IsValid(), "the rake isn't in a user's history")
+	assert.False(t, TransactionType("bet").IsValid())
+	assert.False(t, TransactionType("").IsValid())
+}
@@ -0,0 +1,114 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type UserSort string
+
+const (
+	UserSortID      UserSort = "id"
+	UserSortName    UserSort = "name"
+	UserSortBalance UserSort = "balance"
+	UserSortRating  UserSort = "rating"
+)
+
+// UserFilter selects a page of users. Nil MinBalance and MaxBalance are
+// unbounded, both bounds are inclusive.
+type UserFilter struct {
+	Page
+	Sort       UserSort
+	Desc       bool
+	NamePrefix string
+	MinBalance *int
+	MaxBalance *int
+}
+
+func (f UserFilter) IsValid() error {
+	switch f.Sort {
+	case UserSortID, UserSortName:
+	case UserSortBalance, UserSortRating:
+		if f.Cursor.ID != 0 {
+			_, err := strconv.Atoi(f.Cursor.Value)
+			if err != nil {
+				return ReqErr(errors.New("invalid cursor"))
+			}
+		}
+	default:
+		return ReqErr(fmt.Errorf("can't sort users by %q", f.Sort))
+	}
+	if f.MinBalance != nil && f.MaxBalance != nil && *f.MinBalance > *f.MaxBalance {
+		return ReqErr(errors.New("minimal balance is greater than maximal"))
+	}
+	return nil
+}
+
+func (f UserFilter) Match(u User) bool {
+	if !strings.HasPrefix(u.Name, f.NamePrefix) {
+		return false
+	}
+	if f.MinBalance != nil && u.Balance < *f.MinBalance {
+		return false
+	}
+	if f.MaxBalance != nil && u.Balance > *f.MaxBalance {
+		return false
+	}
+	return true
+}
+
+// Less reports whether a goes before b in the listing.
+func (f UserFilter) Less(a, b User) bool {
+	less, equal := a.ID < b.ID, false
+	switch f.Sort {
+	case UserSortName:
+		less, equal = a.Name < b.Name, a.Name == b.Name
+	case UserSortBalance:
+		less, equal = a.Balance < b.Balance, a.Balance == b.Balance
+	case UserSortRating:
+		less, equal = a.Rating < b.Rating, a.Rating == b.Rating
+	}
+	if equal {
+		less = a.ID < b.ID
+	}
+	if f.Desc {
+		return !less && a.ID != b.ID
+	}
+	return less
+}
+
+// CursorOf returns the cursor pointing at u.
+func (f UserFilter) CursorOf(u User) Cursor {
+	c := Cursor{ID: u.ID}
+	switch f.Sort {
+	case UserSortName:
+		c.Value = u.Name
+	case UserSortBalance:
+		c.Value = strconv.Itoa(u.Balance)
+	case UserSortRating:
+		c.Value = strconv.Itoa(u.Rating)
+	}
+	return c
+}
+
+// CursorUser restores the sort key of the user the cursor points at.
+func (f UserFilter) CursorUser() User {
+	u := User{ID: f.Cursor.ID}
+	switch f.Sort {
+	case UserSortName:
+		u.Name = f.Cursor.Value
+	case UserSortBalance:
+		u.Balance, _ = strconv.Atoi(f.Cursor.Value)
+	case UserSortRating:
+		u.Rating, _ = strconv.Atoi(f.Cursor.Value)
+	}
+	return u
+}
+
+type UserPage struct {
+	Users      []User `json:"users"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestUserFilterIsValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter UserFilter
+		valid  bool
+	}{
+		{"id", UserFilter{Sort: UserSortID}, true},
+		{"name", UserFilter{Sort: UserSortName, Page: Page{Cursor: Cursor{ID: 1, Value: "bob"}}}, true},
+		{"balance", UserFilter{Sort: UserSortBalance, Page: Page{Cursor: Cursor{ID: 1, Value: "700"}}}, true},
+		{"balance cursor", UserFilter{Sort: UserSortBalance, Page: Page{Cursor: Cursor{ID: 1, Value: "bob"}}}, false},
+		{"rating", UserFilter{Sort: UserSortRating, Page: Page{Cursor: Cursor{ID: 1, Value: "1500"}}}, true},
+		{"rating cursor", UserFilter{Sort: UserSortRating, Page: Page{Cursor: Cursor{ID: 1, Value: "bob"}}}, false},
+		{"unknown sort", UserFilter{Sort: "age"}, false},
+		{"empty sort", UserFilter{}, false},
+		{"equal bounds", UserFilter{Sort: UserSortID, MinBalance: intPtr(5), MaxBalance: intPtr(5)}, true},
+		{"crossed bounds", UserFilter{Sort: UserSortID, MinBalance: intPtr(6), MaxBalance: intPtr(5)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.filter.IsValid()
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestUserFilterMatch(t *testing.T) {
+	u := User{ID: 1, Name: "alice", Balance: 500}
+	assert.True(t, UserFilter{}.Match(u))
+	assert.True(t, UserFilter{NamePrefix: "al"}.Match(u))
+	assert.False(t, UserFilter{NamePrefix: "Al"}.Match(u))
+	assert.True(t, UserFilter{MinBalance: intPtr(500), MaxBalance: intPtr(500)}.Match(u), "bounds are inclusive")
+	assert.False(t, UserFilter{MinBalance: intPtr(501)}.Match(u))
+	assert.False(t, UserFilter{MaxBalance: intPtr(499)}.Match(u))
+}
+
+func TestUserFilterLess(t *testing.T) {
+	a := User{ID: 1, Name: "bob", Balance: 500}
+	b := User{ID: 2, Name: "alice", Balance: 500}
+	byName := UserFilter{Sort: UserSortName}
+	assert.True(t, byName.Less(b, a))
+	assert.False(t, byName.Less(a, b))
+	byBalance := UserFilter{Sort: UserSortBalance}
+	assert.True(t, byBalance.Less(a, b), "ties are broken by id")
+	byBalance.Desc = true
+	assert.True(t, byBalance.Less(b, a), "descending order reverses the tie-break too")
+	assert.False(t, byBalance.Less(a, a))
+	byRating := UserFilter{Sort: UserSortRating, Desc: true}
+	assert.True(t, byRating.Less(User{ID: 2, Rating: 1516}, User{ID: 1, Rating: 1500}))
+	assert.True(t, UserFilter{Sort: UserSortID}.Less(a, b))
+}
+
+func TestUserFilterCursor(t *testing.T) {
+	u := User{ID: 3, Name: "carol", Balance: 700}
+	for _, sort := range []UserSort{UserSortID, UserSortName, UserSortBalance} {
+		f := UserFilter{Sort: sort}
+		f.Cursor = f.CursorOf(u)
+		got := f.CursorUser()
+		assert.False(t, f.Less(got, u), sort)
+		assert.False(t, f.Less(u, got), sort)
+	}
+	f := UserFilter{Sort: UserSortBalance}
+	assert.Equal(t, Cursor{ID: 3, Value: "700"}, f.CursorOf(u))
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"errors"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// seedBracket builds every match of a single-elimination bracket. Entries
+// are seeded in the order of registration and placed so that the best seeds
+// meet as late as possible. The field is filled up to a power of two with
+// byes, which go to the best seeds and are decided right away.
+func seedBracket(entries []entity.Entry) []entity.Match {
+	size := 1
+	for size < len(entries) {
+		size *= 2
+	}
+	order := []int{1}
+	for len(order) < size {
+		n := len(order) * 2
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	player := func(seed int) int {
+		if seed > len(entries) {
+			return 0
+		}
+		return entries[seed-1].UserID
+	}
+
+	var matches []entity.Match
+	round := 1
+	for n := size / 2; n >= 1; n /= 2 {
+		for slot := 0; slot < n; slot++ {
+			matches = append(matches, entity.Match{Round: round, Slot: slot})
+		}
+		round++
+	}
+	for slot := 0; slot < size/2; slot++ {
+		m := &matches[slot]
+		m.Player1, m.Player2 = player(order[2*slot]), player(order[2*slot+1])
+		if m.Player2 != 0 {
+			continue
+		}
+		m.Winner, m.Bye = m.Player1, true
+		next := &matches[size/2+slot/2]
+		if slot%2 == 0 {
+			next.Player1 = m.Winner
+		} else {
+			next.Player2 = m.Winner
+		}
+	}
+	return matches
+}
+
+// advanceBracket records the result r in its match and moves the winner on
+// to the match they play next. It returns the matches it has changed.
+func advanceBracket(matches []entity.Match, r entity.Match) ([]entity.Match, error) {
+	if r.Draw {
+		return nil, entity.ReqErr(errors.New("a bracket match can't be drawn"))
+	}
+	i, err := findMatch(matches, r.Round, r.Slot)
+	if err != nil {
+		return nil, err
+	}
+	decided, err := matches[i].Decide(r.Winner)
+	if err != nil {
+		return nil, err
+	}
+	changed := []entity.Match{decided}
+	round, slot, first := decided.Feeds()
+	j, err := findMatch(matches, round, slot)
+	if err != nil {
+		// The final feeds no match.
+		return changed, nil
+	}
+	next := matches[j]
+	if first {
+		next.Player1 = decided.Winner
+	} else {
+		next.Player2 = decided.Winner
+	}
+	return append(changed, next), nil
+}
@@ -0,0 +1,85 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func entries(uIDs ...int) []entity.Entry {
+	es := make([]entity.Entry, len(uIDs))
+	for i, uID := range uIDs {
+		es[i] = entity.Entry{UserID: uID}
+	}
+	return es
+}
+
+func TestSeedBracket(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entity.Entry
+		want    []entity.Match
+	}{
+		{"two", entries(1, 2), []entity.Match{
+			{Round: 1, Slot: 0, Player1: 1, Player2: 2},
+		}},
+		{"three", entries(1, 2, 3), []entity.Match{
+			{Round: 1, Slot: 0, Player1: 1, Winner: 1, Bye: true},
+			{Round: 1, Slot: 1, Player1: 2, Player2: 3},
+			{Round: 2, Slot: 0, Player1: 1},
+		}},
+		{"four", entries(1, 2, 3, 4), []entity.Match{
+			{Round: 1, Slot: 0, Player1: 1, Player2: 4},
+			{Round: 1, Slot: 1, Player1: 2, Player2: 3},
+			{Round: 2, Slot: 0},
+		}},
+		{"five", entries(1, 2, 3, 4, 5), []entity.Match{
+			{Round: 1, Slot: 0, Player1: 1, Winner: 1, Bye: true},
+			{Round: 1, Slot: 1, Player1: 4, Player2: 5},
+			{Round: 1, Slot: 2, Player1: 2, Winner: 2, Bye: true},
+			{Round: 1, Slot: 3, Player1: 3, Winner: 3, Bye: true},
+			{Round: 2, Slot: 0, Player1: 1},
+			{Round: 2, Slot: 1, Player1: 2, Player2: 3},
+			{Round: 3, Slot: 0},
+		}},
+		{"seven", entries(1, 2, 3, 4, 5, 6, 7), []entity.Match{
+			{Round: 1, Slot: 0, Player1: 1, Winner: 1, Bye: true},
+			{Round: 1, Slot: 1, Player1: 4, Player2: 5},
+			{Round: 1, Slot: 2, Player1: 2, Player2: 7},
+			{Round: 1, Slot: 3, Player1: 3, Player2: 6},
+			{Round: 2, Slot: 0, Player1: 1},
+			{Round: 2, Slot: 1},
+			{Round: 3, Slot: 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, seedBracket(tt.entries))
+		})
+	}
+}
+
+func TestAdvanceBracket(t *testing.T) {
+	matches := seedBracket(entries(1, 2, 3))
+
+	changed, err := advanceBracket(matches, entity.Match{Round: 1, Slot: 1, Winner: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Match{
+		{Round: 1, Slot: 1, Player1: 2, Player2: 3, Winner: 3},
+		{Round: 2, Slot: 0, Player1: 1, Player2: 3},
+	}, changed)
+
+	_, err = advanceBracket(matches, entity.Match{Round: 1, Slot: 1, Winner: 1})
+	assert.Error(t, err, "the winner plays the match")
+	_, err = advanceBracket(matches, entity.Match{Round: 2, Slot: 0, Winner: 1})
+	assert.Error(t, err, "the final waits for its players")
+	_, err = advanceBracket(matches, entity.Match{Round: 1, Slot: 1, Draw: true})
+	assert.Error(t, err, "a bracket match can't be drawn")
+
+	final := []entity.Match{{Round: 1, Slot: 0, Player1: 1, Player2: 2}}
+	changed, err = advanceBracket(final, entity.Match{Round: 1, Slot: 0, Winner: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Match{{Round: 1, Slot: 0, Player1: 1, Player2: 2, Winner: 2}}, changed, "the final feeds no match")
+}
@@ -2,18 +2,16 @@ package game
 
 import (
 	"errors"
-	"math/rand"
-	"time"
+	"fmt"
 
 	"github.com/yanrishbe/gaming-website/entity"
-	"github.com/yanrishbe/gaming-website/postgres"
 )
 
 type Controller struct {
-	db postgres.DB
+	db Storage
 }
 
-func New(db postgres.DB) Controller {
+func New(db Storage) Controller {
 	return Controller{db: db}
 }
 
@@ -22,25 +20,65 @@ func (c Controller) RegUser(u entity.User) (entity.User, error) {
 	if err != nil {
 		return u, err
 	}
-	if u.Balance < 300 {
+	u.Rating = entity.InitialRating
+	p, err := c.db.CurrentPolicy()
+	if err != nil {
+		return u, err
+	}
+	if u.Balance < p.MinBalance {
 		return u, entity.RegErr(errors.New("low balance"))
 	}
-	u.Balance -= 300
-	return c.db.CreateUser(u)
+	u.PolicyID = p.ID
+	return c.db.CreateUser(u, p)
 }
 
 func (c Controller) GetUser(id int) (entity.User, error) {
 	return c.db.GetUser(id)
 }
 
+func (c Controller) ListUsers(f entity.UserFilter) (entity.UserPage, error) {
+	err := f.IsValid()
+	if err != nil {
+		return entity.UserPage{}, err
+	}
+	return c.db.ListUsers(f)
+}
+
 func (c Controller) DelUser(id int) error {
 	return c.db.DelUser(id)
 }
 
+// GetRating returns a user's rating with its history by tournament.
+func (c Controller) GetRating(id int) (entity.UserRating, error) {
+	u, err := c.db.GetUser(id)
+	if err != nil {
+		return entity.UserRating{}, err
+	}
+	changes, err := c.db.RatingChanges(id)
+	if err != nil {
+		return entity.UserRating{}, err
+	}
+	return entity.NewUserRating(u, changes), nil
+}
+
+// Ratings lists users from the highest rating down.
+func (c Controller) Ratings(p entity.Page) (entity.UserPage, error) {
+	return c.ListUsers(entity.UserFilter{Page: p, Sort: entity.UserSortRating, Desc: true})
+}
+
+// TakePoints withdraws points from a user's cash. Bonus points are never
+// withdrawn.
 func (c Controller) TakePoints(id, points int) (entity.User, error) {
 	if points <= 0 {
 		return entity.User{}, entity.PointsErr(errors.New("points must be greater than 0"))
 	}
+	u, err := c.db.GetUser(id)
+	if err != nil {
+		return u, err
+	}
+	if points > u.Balance {
+		return u, entity.PointsErr(fmt.Errorf("only cash is withdrawn, the cash balance is %d", u.Balance))
+	}
 	return c.db.TakePoints(id, points)
 }
 
@@ -51,6 +89,22 @@ func (c Controller) FundPoints(id, points int) (entity.User, error) {
 	return c.db.FundPoints(id, points)
 }
 
+func (c Controller) ListTransactions(f entity.TransactionFilter) (entity.TransactionPage, error) {
+	_, err := c.db.GetUser(f.UserID)
+	if err != nil {
+		return entity.TransactionPage{}, err
+	}
+	for _, t := range f.Types {
+		if !t.IsValid() {
+			return entity.TransactionPage{}, entity.ReqErr(fmt.Errorf("unknown transaction type %q", t))
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return entity.TransactionPage{}, entity.ReqErr(errors.New("'from' must be before 'to'"))
+	}
+	return c.db.ListTransactions(f)
+}
+
 func (c Controller) RegTourn(t entity.Tournament) (entity.Tournament, error) {
 	err := t.IsValid()
 	if err != nil {
@@ -59,46 +113,524 @@ func (c Controller) RegTourn(t entity.Tournament) (entity.Tournament, error) {
 	if t.Deposit <= 0 {
 		return t, entity.RegErr(errors.New("deposit must be greater than 0"))
 	}
-	return c.db.CreateTourn(t)
+	switch {
+	case t.Status == "" && t.OpensAt != nil:
+		t.Status = entity.Draft
+	case t.Status == "":
+		t.Status = entity.Open
+	case t.Status == entity.Draft, t.Status == entity.Open:
+	default:
+		return t, entity.RegErr(errors.New("a new tournament must be a draft or open"))
+	}
+	if t.Format == "" {
+		t.Format = entity.FormatStandard
+	}
+	switch t.Format {
+	case entity.FormatStandard:
+	case entity.FormatSitAndGo:
+		if t.MaxPlayers < 2 {
+			return t, entity.RegErr(errors.New("a sit-and-go needs maxPlayers of at least 2"))
+		}
+		if t.Selector == entity.SelectExternal || t.Selector == entity.SelectScore {
+			return t, entity.RegErr(errors.New("a sit-and-go can't wait for results"))
+		}
+		if t.StartsAt != nil || t.EndsAt != nil {
+			return t, entity.RegErr(errors.New("a sit-and-go starts and ends when it is full"))
+		}
+	case entity.FormatBracket, entity.FormatRoundRobin, entity.FormatSwiss:
+		if t.Selector != "" && t.Selector != entity.SelectExternal {
+			return t, entity.RegErr(fmt.Errorf("a %s tournament is ranked by its matches", t.Format))
+		}
+		t.Selector = entity.SelectExternal
+	default:
+		return t, entity.RegErr(fmt.Errorf("unknown format %q", t.Format))
+	}
+	if t.Rounds != 0 && t.Format != entity.FormatSwiss {
+		return t, entity.RegErr(errors.New("only a Swiss tournament has a number of rounds"))
+	}
+	if t.Selector == "" {
+		t.Selector = entity.SelectUniform
+	}
+	if !t.Selector.IsValid() {
+		return t, entity.RegErr(fmt.Errorf("unknown winner selector %q", t.Selector))
+	}
+	// The outcome and the participants are the storage's to keep, whatever
+	// the request says.
+	t.Prize, t.Winner, t.Rake = 0, 0, 0
+	t.Users, t.Waitlist, t.Leaderboard = nil, nil, nil
+	switch {
+	case t.Selector == entity.SelectScore && t.TieBreak == "":
+		t.TieBreak = entity.TieFirstSubmitted
+	case t.Selector != entity.SelectScore && t.TieBreak != "":
+		return t, entity.RegErr(errors.New("only a tournament with the score selector has a tie-break"))
+	}
+	if t.TieBreak != "" && !t.TieBreak.IsValid() {
+		return t, entity.RegErr(fmt.Errorf("unknown tie-break %q", t.TieBreak))
+	}
+	err = t.Payouts.IsValid()
+	if err != nil {
+		return t, err
+	}
+	if len(t.Payouts.Places) == 0 && t.Payouts.TopPercent == 0 {
+		t.Payouts.Places = []int{100}
+	}
+	t.Seed, t.SeedHash, err = newSeed()
+	if err != nil {
+		return t, err
+	}
+	t, err = c.db.CreateTourn(t)
+	t.Seed = ""
+	return t, err
 }
 
+// GetTourn returns a tournament, with the leaderboard if it is ranked by
+// score.
 func (c Controller) GetTourn(id int) (entity.Tournament, error) {
 	t, err := c.db.GetTourn(id)
-	return t, err
+	if err != nil {
+		return t, err
+	}
+	if t.Selector == entity.SelectScore {
+		t.Leaderboard = entity.Leaderboard(t.Users)
+	}
+	return t, nil
 }
 
-func (c Controller) JoinTourn(tID, uID int) (entity.Tournament, error) {
-	t, err := c.db.JoinTourn(tID, uID, func(a int, b int) error {
-		if a < b {
-			return entity.RegErr(errors.New("balance is lower than deposit"))
+func (c Controller) ListTourns(f entity.TournamentFilter) (entity.TournamentPage, error) {
+	err := f.IsValid()
+	if err != nil {
+		return entity.TournamentPage{}, err
+	}
+	return c.db.ListTourns(f)
+}
+
+// OpenTourn opens the registration of a draft tournament.
+func (c Controller) OpenTourn(id int) (entity.Tournament, error) {
+	return c.setTournStatus(id, entity.Open)
+}
+
+// StartTourn closes the registration and starts the tournament. The players
+// of a bracket tournament are seeded into its bracket, those of a league get
+// their schedule and those of a Swiss tournament their first round.
+func (c Controller) StartTourn(id int) (entity.Tournament, error) {
+	err := c.db.StartTourn(id, func(t entity.Tournament, entries []entity.Entry) ([]entity.Match, error) {
+		switch t.Format {
+		case entity.FormatBracket:
+			return seedBracket(entries), nil
+		case entity.FormatRoundRobin:
+			return scheduleLeague(entries), nil
+		case entity.FormatSwiss:
+			if t.SwissRounds(len(entries)) == 0 {
+				return nil, nil
+			}
+			players := make([]int, len(entries))
+			for i, e := range entries {
+				players[i] = e.UserID
+			}
+			return pairSwiss(1, players, nil, nil), nil
 		}
-		return nil
+		return nil, nil
 	})
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	return c.db.GetTourn(id)
+}
+
+func (c Controller) setTournStatus(id int, to entity.Status) (entity.Tournament, error) {
+	err := c.db.SetTournStatus(id, to)
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	return c.db.GetTourn(id)
+}
+
+// JoinTourn registers a user, or puts them on the waitlist of a full
+// tournament. A private tournament needs the user's own invite or an invite
+// code. The join that fills a sit-and-go finishes it, so the user learns the
+// result.
+func (c Controller) JoinTourn(tID, uID int, code string) (entity.JoinResult, error) {
+	t, err := c.db.JoinTourn(tID, uID, code, func(u entity.User, t entity.Tournament, inv *entity.Invite) error {
+		if t.TeamSize != 0 {
+			return entity.RegErr(errors.New("the tournament is for teams, a captain registers the team"))
+		}
+		err := t.Admit(inv)
+		if err != nil {
+			return err
+		}
+		if u.Funds() < t.Deposit {
+			return entity.RegErr(errors.New("balance is lower than deposit"))
+		}
+		return t.AllowsRating(u.Rating)
+	}, rankBy(nil))
+	if err != nil {
+		return entity.JoinResult{Tournament: t}, err
+	}
+	return c.joined(t.ID, uID)
+}
+
+// joined returns the tournament uID has joined, with the result if the join
+// has finished a sit-and-go.
+func (c Controller) joined(tID, uID int) (entity.JoinResult, error) {
+	t, err := c.db.GetTourn(tID)
+	if err != nil || t.Format != entity.FormatSitAndGo || t.Status != entity.Finished {
+		return entity.JoinResult{Tournament: t}, err
+	}
+	r := entity.JoinResult{Tournament: t}
+	for i := range t.Users {
+		if t.Users[i].ID == uID {
+			r.Result = &t.Users[i]
+		}
+	}
+	return r, nil
+}
+
+// SubmitScore records the score of a participant of a running tournament
+// with the score selector. A new score replaces the previous one.
+func (c Controller) SubmitScore(tID, uID, score int) (entity.Tournament, error) {
+	t, err := c.db.GetTourn(tID)
+	if err != nil {
+		return t, err
+	}
+	if t.Selector != entity.SelectScore {
+		return t, entity.ReqErr(errors.New("the tournament isn't ranked by score"))
+	}
+	err = c.db.SubmitScore(tID, uID, score)
+	if err != nil {
+		return t, err
+	}
+	return c.GetTourn(tID)
+}
+
+func (c Controller) LeaveTourn(tID, uID int) (entity.Tournament, error) {
+	t, err := c.db.LeaveTourn(tID, uID)
 	if err != nil {
 		return t, err
 	}
 	return c.db.GetTourn(t.ID)
 }
 
-func (c Controller) FinishTourn(id int) (entity.Tournament, error) {
-	err := c.db.FinishTourn(id, func(users []int) int {
-		rand.Seed(time.Now().UTC().UnixNano())
-		r := rand.Intn(len(users))
-		return users[r]
+// FinishTourn ranks the participants with the tournament's selector and pays
+// out the prize by its payout table. ranking is the result for the external
+// selector and must be empty for the others. Random selectors draw from the
+// tournament's seed and the entries, so the result can be verified once the
+// seed is revealed. Players of a score tournament are rated by their scores.
+func (c Controller) FinishTourn(id int, ranking []int) (entity.Tournament, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return t, err
+	}
+	if t.Format.HasMatches() && len(ranking) != 0 {
+		return t, entity.ReqErr(fmt.Errorf("a %s tournament is ranked by its matches", t.Format))
+	}
+	switch t.Format {
+	case entity.FormatBracket:
+		ranking, err = c.bracketRanking(t)
+	case entity.FormatRoundRobin:
+		ranking, err = c.leagueRanking(t)
+	case entity.FormatSwiss:
+		ranking, err = c.swissRanking(t)
+	}
+	if err != nil {
+		return t, err
+	}
+	err = c.db.FinishTourn(id, rankBy(ranking))
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	return c.db.GetTourn(id)
+}
+
+// rankBy returns the placings of a finishing tournament by its selector, with
+// ranking for the external one, and the rating changes of the score selector.
+func rankBy(ranking []int) func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+	return func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		s, err := newSelector(t, ranking, newDraw(t.Seed, entries).Intn)
+		if err != nil {
+			return nil, nil, err
+		}
+		ranked, err := s.Rank(entries)
+		if err != nil {
+			return nil, nil, err
+		}
+		placings, err := place(t, ranked, len(entries))
+		if err != nil || t.Selector != entity.SelectScore {
+			return placings, nil, err
+		}
+		return placings, rateScores(t.ID, entries), nil
+	}
+}
+
+// place splits the prize among the ranked users. Users below the paid places
+// get their place and nothing else.
+func place(t entity.Tournament, ranking []int, players int) ([]entity.Placing, error) {
+	amounts := t.Payouts.Split(t.Prize, players)
+	if len(ranking) < len(amounts) {
+		return nil, entity.ReqErr(fmt.Errorf("%d places are paid, the ranking has only %d users", len(amounts), len(ranking)))
+	}
+	placings := make([]entity.Placing, len(ranking))
+	for i, uID := range ranking {
+		placings[i] = entity.Placing{UserID: uID, Place: i + 1}
+		if i < len(amounts) {
+			placings[i].Amount = amounts[i]
+		}
+	}
+	return placings, nil
+}
+
+// VerifyTourn recomputes the ranking of a finished tournament from its
+// revealed seed and entries.
+func (c Controller) VerifyTourn(id int) (entity.Proof, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return entity.Proof{}, err
+	}
+	err = t.Status.Require(entity.Finished)
+	if err != nil {
+		return entity.Proof{}, err
+	}
+	entries := make([]entity.Entry, len(t.Users))
+	recorded := make([]int, len(t.Users))
+	n := 0
+	for i, u := range t.Users {
+		entries[i] = entity.Entry{UserID: u.ID, Stake: u.Stake, Score: u.Score, ScoredAt: u.ScoredAt}
+		if u.Place > 0 && u.Place <= len(recorded) {
+			recorded[u.Place-1] = u.ID
+			n++
+		}
+	}
+	p := entity.Proof{
+		TournamentID: t.ID,
+		Selector:     t.Selector,
+		SeedHash:     t.SeedHash,
+		Seed:         t.Seed,
+		Input:        drawInput(entries),
+		Winner:       t.Winner,
+		Ranking:      recorded[:n],
+	}
+	p.SeedMatches = seedHash(t.Seed) == t.SeedHash
+	// The result of an external selector can't be recomputed, so it is
+	// taken as recorded.
+	var ranking []int
+	if t.Selector == entity.SelectExternal {
+		ranking = p.Ranking
+	}
+	s, err := newSelector(t, ranking, newDraw(t.Seed, entries).Intn)
+	if err != nil {
+		return p, err
+	}
+	p.ComputedRanking, err = s.Rank(entries)
+	if err != nil {
+		return p, err
+	}
+	if len(p.ComputedRanking) == 0 {
+		return p, entity.StatusErr(errors.New("the tournament has no players to rank"))
+	}
+	p.Computed = p.ComputedRanking[0]
+	p.Valid = p.SeedMatches && p.Computed == p.Winner && equalRankings(p.Ranking, p.ComputedRanking)
+	return p, nil
+}
+
+// equalRankings reports whether the recorded ranking is the beginning of the
+// computed one. Tournaments finished before placings were recorded only have
+// their winner.
+func equalRankings(recorded, computed []int) bool {
+	if len(recorded) > len(computed) {
+		return false
+	}
+	for i := range recorded {
+		if recorded[i] != computed[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetBracket returns the bracket of a bracket tournament. It is empty until
+// the tournament starts.
+func (c Controller) GetBracket(id int) (entity.Bracket, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return entity.Bracket{}, err
+	}
+	if t.Format != entity.FormatBracket {
+		return entity.Bracket{}, entity.ReqErr(errors.New("the tournament has no bracket"))
+	}
+	matches, err := c.db.GetMatches(id)
+	if err != nil {
+		return entity.Bracket{}, err
+	}
+	return entity.NewBracket(id, matches), nil
+}
+
+// ReportMatch records the winner of a bracket match and moves them on to the
+// next round. The result of the final finishes the tournament.
+func (c Controller) ReportMatch(id int, m entity.Match) (entity.Bracket, error) {
+	b, err := c.GetBracket(id)
+	if err != nil {
+		return b, err
+	}
+	err = c.reportMatch(id, func(matches []entity.Match) ([]entity.Match, error) {
+		return advanceBracket(matches, m)
 	})
+	if err != nil {
+		return b, err
+	}
+	b, err = c.GetBracket(id)
+	if err != nil || b.Winner == 0 {
+		return b, err
+	}
+	_, err = c.FinishTourn(id, nil)
+	return b, err
+}
+
+// bracketRanking ranks the players of a bracket tournament whose final is
+// played. A lone player has nobody to play and wins.
+func (c Controller) bracketRanking(t entity.Tournament) ([]int, error) {
+	matches, err := c.db.GetMatches(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	ranking, ok := entity.NewBracket(t.ID, matches).Ranking()
+	if !ok {
+		return nil, entity.StatusErr(errors.New("the bracket isn't played out"))
+	}
+	if len(matches) == 0 {
+		for _, u := range t.Users {
+			ranking = append(ranking, u.ID)
+		}
+	}
+	return ranking, nil
+}
+
+// reportMatch records a result in the matches of a tournament with record
+// and rates the players of the matches it has played.
+func (c Controller) reportMatch(id int, record func(matches []entity.Match) ([]entity.Match, error)) error {
+	return c.db.ReportMatch(id, func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		changed, err := record(matches)
+		if err != nil {
+			return nil, nil, err
+		}
+		return changed, rateMatches(id, entries, matches, changed), nil
+	})
+}
+
+// GetLeague returns the schedule and the standings of a league. The schedule
+// is empty until the league starts.
+func (c Controller) GetLeague(id int) (entity.League, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return entity.League{}, err
+	}
+	if t.Format != entity.FormatRoundRobin {
+		return entity.League{}, entity.ReqErr(errors.New("the tournament isn't a league"))
+	}
+	matches, err := c.db.GetMatches(id)
+	if err != nil {
+		return entity.League{}, err
+	}
+	return entity.NewLeague(id, t.Users, matches), nil
+}
+
+// ReportLeagueMatch records the result of a league match, a win or a draw.
+// The result of the last match finishes the league.
+func (c Controller) ReportLeagueMatch(id int, m entity.Match) (entity.League, error) {
+	l, err := c.GetLeague(id)
+	if err != nil {
+		return l, err
+	}
+	err = c.reportMatch(id, func(matches []entity.Match) ([]entity.Match, error) {
+		return recordLeagueMatch(matches, m)
+	})
+	if err != nil {
+		return l, err
+	}
+	l, err = c.GetLeague(id)
+	if err != nil || !l.Complete() {
+		return l, err
+	}
+	_, err = c.FinishTourn(id, nil)
+	return l, err
+}
+
+// leagueRanking ranks the players of a league by its standings. Matches
+// left unplayed when the league is finished don't count.
+func (c Controller) leagueRanking(t entity.Tournament) ([]int, error) {
+	matches, err := c.db.GetMatches(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewLeague(t.ID, t.Users, matches).Ranking(), nil
+}
+
+// GetSwiss returns the pairings of every round of a Swiss tournament so far
+// and its standings.
+func (c Controller) GetSwiss(id int) (entity.Swiss, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return entity.Swiss{}, err
+	}
+	if t.Format != entity.FormatSwiss {
+		return entity.Swiss{}, entity.ReqErr(errors.New("the tournament isn't a Swiss tournament"))
+	}
+	matches, err := c.db.GetMatches(id)
+	if err != nil {
+		return entity.Swiss{}, err
+	}
+	return entity.NewSwiss(id, t.Users, matches), nil
+}
+
+// ReportSwissMatch records the result of a match of a Swiss tournament, a win
+// or a draw. The result that completes a round pairs the next one, the
+// result that completes the last round finishes the tournament.
+func (c Controller) ReportSwissMatch(id int, m entity.Match) (entity.Swiss, error) {
+	t, err := c.db.GetTourn(id)
+	if err != nil {
+		return entity.Swiss{}, err
+	}
+	if t.Format != entity.FormatSwiss {
+		return entity.Swiss{}, entity.ReqErr(errors.New("the tournament isn't a Swiss tournament"))
+	}
+	err = c.reportMatch(id, func(matches []entity.Match) ([]entity.Match, error) {
+		return advanceSwiss(t, matches, m)
+	})
+	if err != nil {
+		return entity.Swiss{}, err
+	}
+	s, err := c.GetSwiss(id)
+	if err != nil || s.Played() < t.SwissRounds(len(t.Users)) {
+		return s, err
+	}
+	_, err = c.FinishTourn(id, nil)
+	return s, err
+}
+
+// swissRanking ranks the players of a Swiss tournament by its standings.
+func (c Controller) swissRanking(t entity.Tournament) ([]int, error) {
+	matches, err := c.db.GetMatches(t.ID)
+	if err != nil {
+		return nil, err
+	}
+	return entity.NewSwiss(t.ID, t.Users, matches).Ranking(), nil
+}
+
+func (c Controller) CancelTourn(id int) (entity.Tournament, error) {
+	err := c.db.CancelTourn(id)
 	if err != nil {
 		return entity.Tournament{}, err
 	}
 	return c.db.GetTourn(id)
 }
 
+// DelTourn removes a tournament. An unfinished tournament is cancelled
+// first, so its participants get their deposits back.
 func (c Controller) DelTourn(id int) error {
 	t, err := c.GetTourn(id)
 	if err != nil {
 		return err
 	}
-	if t.Status != entity.Finished {
-		_, err := c.FinishTourn(id)
+	if !t.Status.IsFinal() {
+		err := c.db.CancelTourn(id)
 		if err != nil {
 			return err
 		}
@@ -106,3 +638,7 @@ func (c Controller) DelTourn(id int) error {
 	err = c.db.DelTourn(id)
 	return err
 }
+
+func (c Controller) TrialBalance() (entity.TrialBalance, error) {
+	return c.db.TrialBalance()
+}
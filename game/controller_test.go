@@ -0,0 +1,669 @@
+package game
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+func newUser(t *testing.T, c Controller, balance int) entity.User {
+	t.Helper()
+	u, err := c.RegUser(entity.User{Name: "user", Balance: balance})
+	require.NoError(t, err)
+	return u
+}
+
+// newTourn creates an open tournament with the settings of t.
+func newTourn(t *testing.T, c Controller, tourn entity.Tournament) entity.Tournament {
+	t.Helper()
+	tourn.Name = "tournament"
+	tourn.Status = entity.Open
+	tourn, err := c.RegTourn(tourn)
+	require.NoError(t, err)
+	return tourn
+}
+
+func start(t *testing.T, c Controller, tID int) {
+	t.Helper()
+	_, err := c.StartTourn(tID)
+	require.NoError(t, err)
+}
+
+func join(t *testing.T, c Controller, tID, uID int) entity.JoinResult {
+	t.Helper()
+	r, err := c.JoinTourn(tID, uID, "")
+	require.NoError(t, err)
+	return r
+}
+
+func balance(t *testing.T, c Controller, uID int) int {
+	t.Helper()
+	u, err := c.GetUser(uID)
+	require.NoError(t, err)
+	return u.Balance
+}
+
+func requireBalanced(t *testing.T, c Controller) {
+	t.Helper()
+	tb, err := c.TrialBalance()
+	require.NoError(t, err)
+	require.True(t, tb.Balanced, "total %d", tb.Total)
+}
+
+func TestRegUser(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegUser(entity.User{Name: "poor", Balance: 299})
+	assert.Error(t, err)
+	_, err = c.RegUser(entity.User{Balance: 1000})
+	assert.Error(t, err)
+	u := newUser(t, c, 1000)
+	assert.Equal(t, 700, u.Balance)
+	requireBalanced(t, c)
+
+	_, err = c.TakePoints(u.ID, 0)
+	assert.Error(t, err)
+	_, err = c.FundPoints(u.ID, -1)
+	assert.Error(t, err)
+	u, err = c.TakePoints(u.ID, 700)
+	require.NoError(t, err)
+	assert.Equal(t, 0, u.Balance)
+	requireBalanced(t, c)
+}
+
+func TestJoinTourn(t *testing.T) {
+	c := New(memory.New())
+	u := newUser(t, c, 1000)
+	poor := newUser(t, c, 350)
+	_, err := c.RegTourn(entity.Tournament{Name: "free"})
+	assert.Error(t, err, "a tournament has a deposit")
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100})
+
+	_, err = c.JoinTourn(tr.ID, poor.ID, "")
+	assert.Error(t, err)
+	assert.Equal(t, 50, balance(t, c, poor.ID))
+	tourn := join(t, c, tr.ID, u.ID)
+	assert.Equal(t, 100, tourn.Prize)
+	assert.Len(t, tourn.Users, 1)
+	assert.Equal(t, 600, balance(t, c, u.ID))
+}
+
+func TestLeaveTourn(t *testing.T) {
+	c := New(memory.New())
+	u := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, tr.ID, u.ID)
+
+	tourn, err := c.LeaveTourn(tr.ID, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Empty(t, tourn.Users)
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	requireBalanced(t, c)
+	_, err = c.LeaveTourn(tr.ID, u.ID)
+	assert.Error(t, err)
+}
+
+func TestRegTournIgnoresOutcome(t *testing.T) {
+	c := New(memory.New())
+	u := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 10, Prize: 100000, Rake: 50, Winner: u.ID,
+		Users: []entity.Winner{{ID: u.ID}}, Waitlist: []entity.UserTourn{{ID: u.ID}}})
+	assert.Equal(t, 0, tr.Prize)
+	assert.Equal(t, 0, tr.Rake)
+	assert.Equal(t, 0, tr.Winner)
+	assert.Empty(t, tr.Users)
+	assert.Empty(t, tr.Waitlist)
+	assert.Equal(t, entity.SelectUniform, tr.Selector)
+
+	join(t, c, tr.ID, u.ID)
+	start(t, c, tr.ID)
+	_, err := c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	requireBalanced(t, c)
+
+	_, err = c.RegTourn(entity.Tournament{Name: "cup", Deposit: 10, Selector: "coin"})
+	assert.Error(t, err)
+}
+
+func TestTournLifecycle(t *testing.T) {
+	c := New(memory.New())
+	u := newUser(t, c, 1000)
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Status: entity.Running})
+	assert.Error(t, err, "a new tournament can't be running")
+	tr, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100})
+	require.NoError(t, err)
+	assert.Equal(t, entity.Open, tr.Status, "a tournament without a schedule opens at once")
+	opensAt := time.Now().Add(time.Hour)
+	tr, err = c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, OpensAt: &opensAt})
+	require.NoError(t, err)
+	assert.Equal(t, entity.Draft, tr.Status, "a scheduled tournament waits for opensAt")
+	tr, err = c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Status: entity.Draft})
+	require.NoError(t, err)
+	assert.Equal(t, entity.Draft, tr.Status)
+
+	_, err = c.JoinTourn(tr.ID, u.ID, "")
+	require.Error(t, err, "a draft is closed")
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+	_, err = c.StartTourn(tr.ID)
+	require.Error(t, err, "a draft must be opened first")
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+
+	tourn, err := c.OpenTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Open, tourn.Status)
+	join(t, c, tr.ID, u.ID)
+	start(t, c, tr.ID)
+	_, err = c.JoinTourn(tr.ID, newUser(t, c, 1000).ID, "")
+	assert.Error(t, err, "the registration is closed")
+	_, err = c.LeaveTourn(tr.ID, u.ID)
+	assert.Error(t, err, "a running tournament keeps its players")
+	_, err = c.OpenTourn(tr.ID)
+	assert.Error(t, err)
+
+	tourn, err = c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	_, err = c.CancelTourn(tr.ID)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	requireBalanced(t, c)
+}
+
+func TestFinishTourn(t *testing.T) {
+	c := New(memory.New())
+	var users []entity.User
+	for i := 0; i < 3; i++ {
+		users = append(users, newUser(t, c, 1000))
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100})
+	for _, u := range users {
+		join(t, c, tr.ID, u.ID)
+	}
+
+	start(t, c, tr.ID)
+	tourn, err := c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	total := 0
+	for _, u := range users {
+		want := 600
+		if u.ID == tourn.Winner {
+			want += 300
+		}
+		assert.Equal(t, want, balance(t, c, u.ID))
+		total += balance(t, c, u.ID)
+	}
+	assert.Equal(t, 2100, total, "the winner takes the whole prize")
+	requireBalanced(t, c)
+}
+
+func TestVerifyTourn(t *testing.T) {
+	c := New(memory.New())
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Selector: entity.SelectStake})
+	assert.Len(t, tr.SeedHash, 64)
+	assert.Empty(t, tr.Seed, "the seed is secret until the end")
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+	start(t, c, tr.ID)
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, tr.SeedHash, tourn.SeedHash)
+	assert.Empty(t, tourn.Seed)
+	_, err = c.VerifyTourn(tr.ID)
+	require.Error(t, err, "a running tournament can't be verified")
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+
+	tourn, err = c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, tr.SeedHash, seedHash(tourn.Seed))
+	p, err := c.VerifyTourn(tr.ID)
+	require.NoError(t, err)
+	assert.True(t, p.Valid)
+	assert.True(t, p.SeedMatches)
+	assert.Equal(t, tourn.Winner, p.Computed)
+	assert.Equal(t, tourn.Winner, p.Winner)
+	assert.Equal(t, tourn.Seed, p.Seed)
+	assert.Equal(t, drawInput([]entity.Entry{{UserID: u1.ID, Stake: 100}, {UserID: u2.ID, Stake: 100}}), p.Input)
+	_, err = c.VerifyTourn(42)
+	assert.Error(t, err)
+}
+
+func TestVerifyTournWithoutPlayers(t *testing.T) {
+	db := memory.New()
+	tr, err := db.CreateTourn(entity.Tournament{Name: "cup", Deposit: 100, Status: entity.Finished, Selector: entity.SelectUniform})
+	require.NoError(t, err)
+	_, err = New(db).VerifyTourn(tr.ID)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+}
+
+func TestFinishTournExternal(t *testing.T) {
+	c := New(memory.New())
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Selector: entity.SelectExternal})
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+	start(t, c, tr.ID)
+
+	_, err := c.FinishTourn(tr.ID, nil)
+	assert.Error(t, err, "the external selector needs the winner")
+	_, err = c.FinishTourn(tr.ID, []int{42})
+	assert.Error(t, err)
+	requireBalanced(t, c)
+
+	tourn, err := c.FinishTourn(tr.ID, []int{u2.ID})
+	require.NoError(t, err)
+	assert.Equal(t, u2.ID, tourn.Winner)
+	assert.Equal(t, 600, balance(t, c, u1.ID))
+	assert.Equal(t, 800, balance(t, c, u2.ID))
+	requireBalanced(t, c)
+
+	other := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, other.ID, u1.ID)
+	start(t, c, other.ID)
+	_, err = c.FinishTourn(other.ID, []int{u1.ID})
+	assert.Error(t, err, "only the external selector takes the winner")
+}
+
+func TestFinishTournPayouts(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Payouts: entity.Payouts{Places: []int{60, 30}}})
+	assert.Error(t, err, "the places add up to 100%")
+	var users []entity.User
+	for i := 0; i < 4; i++ {
+		users = append(users, newUser(t, c, 1000))
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Selector: entity.SelectExternal, Payouts: entity.Payouts{Places: []int{50, 30, 20}}})
+	for _, u := range users {
+		join(t, c, tr.ID, u.ID)
+	}
+	start(t, c, tr.ID)
+	_, err = c.FinishTourn(tr.ID, []int{users[3].ID, users[2].ID})
+	assert.Error(t, err, "three places are paid")
+
+	tourn, err := c.FinishTourn(tr.ID, []int{users[3].ID, users[2].ID, users[1].ID})
+	require.NoError(t, err)
+	assert.Equal(t, users[3].ID, tourn.Winner)
+	for i, want := range []int{600, 680, 720, 800} {
+		assert.Equal(t, want, balance(t, c, users[i].ID))
+	}
+	requireBalanced(t, c)
+
+	p, err := c.VerifyTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{users[3].ID, users[2].ID, users[1].ID}, p.Ranking)
+	assert.True(t, p.Valid)
+}
+
+func TestPlace(t *testing.T) {
+	tourn := entity.Tournament{Prize: 300, Payouts: entity.Payouts{Places: []int{70, 30}}}
+	placings, err := place(tourn, []int{7, 4, 9}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Placing{{UserID: 7, Place: 1, Amount: 210}, {UserID: 4, Place: 2, Amount: 90}, {UserID: 9, Place: 3}}, placings)
+
+	_, err = place(tourn, []int{7}, 3)
+	assert.Error(t, err, "the ranking must cover the paid places")
+	placings, err = place(tourn, []int{7}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Placing{{UserID: 7, Place: 1, Amount: 300}}, placings, "a lone player takes it all")
+}
+
+func TestRake(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, RakePercent: 5, RakeFee: 5})
+	assert.Error(t, err)
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, RakeFee: 25, Selector: entity.SelectFirst})
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+	start(t, c, tr.ID)
+
+	tourn, err := c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 150, tourn.Prize)
+	assert.Equal(t, 50, tourn.Rake)
+	assert.Equal(t, 750, balance(t, c, u1.ID), "the winner takes the prize without the rake")
+	assert.Equal(t, 600, balance(t, c, u2.ID))
+	requireBalanced(t, c)
+}
+
+func TestSitAndGo(t *testing.T) {
+	c := New(memory.New())
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	u3 := newUser(t, c, 1000)
+	for _, tourn := range []entity.Tournament{
+		{Name: "cup", Deposit: 100, Format: entity.FormatSitAndGo},
+		{Name: "cup", Deposit: 100, Format: entity.FormatSitAndGo, MaxPlayers: 2, Selector: entity.SelectExternal},
+		{Name: "cup", Deposit: 100, Format: entity.FormatSitAndGo, MaxPlayers: 2, EndsAt: &time.Time{}},
+		{Name: "cup", Deposit: 100, Format: "knockout"},
+	} {
+		_, err := c.RegTourn(tourn)
+		assert.Error(t, err)
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatSitAndGo, MaxPlayers: 2})
+
+	r := join(t, c, tr.ID, u1.ID)
+	assert.Equal(t, entity.Open, r.Status)
+	assert.Nil(t, r.Result)
+	r = join(t, c, tr.ID, u2.ID)
+	assert.Equal(t, entity.Finished, r.Status)
+	require.NotNil(t, r.Result)
+	assert.Equal(t, u2.ID, r.Result.ID)
+	assert.Equal(t, 600+r.Result.Payout, balance(t, c, u2.ID))
+	assert.Equal(t, 1400, balance(t, c, u1.ID)+balance(t, c, u2.ID))
+	requireBalanced(t, c)
+	_, err := c.JoinTourn(tr.ID, u3.ID, "")
+	assert.Error(t, err, "a finished sit-and-go is closed")
+}
+
+func TestScoreTourn(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, TieBreak: entity.TieRandom})
+	assert.Error(t, err, "only a score tournament has a tie-break")
+	_, err = c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Selector: entity.SelectScore, TieBreak: "coin"})
+	assert.Error(t, err)
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	u3 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Selector: entity.SelectScore})
+	assert.Equal(t, entity.TieFirstSubmitted, tr.TieBreak)
+	for _, u := range []entity.User{u1, u2, u3} {
+		join(t, c, tr.ID, u.ID)
+	}
+	start(t, c, tr.ID)
+	_, err = c.SubmitScore(tr.ID, u1.ID, 5)
+	require.NoError(t, err)
+	tourn, err := c.SubmitScore(tr.ID, u2.ID, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Standing{
+		{Rank: 1, UserID: u2.ID, Name: u2.Name, Score: 8},
+		{Rank: 2, UserID: u1.ID, Name: u1.Name, Score: 5},
+	}, tourn.Leaderboard)
+
+	tourn, err = c.FinishTourn(tr.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, u2.ID, tourn.Winner)
+	assert.Equal(t, 900, balance(t, c, u2.ID))
+	requireBalanced(t, c)
+	p, err := c.VerifyTourn(tr.ID)
+	require.NoError(t, err)
+	assert.True(t, p.Valid)
+	assert.Equal(t, []int{u2.ID, u1.ID, u3.ID}, p.ComputedRanking)
+
+	other := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, other.ID, u1.ID)
+	start(t, c, other.ID)
+	_, err = c.SubmitScore(other.ID, u1.ID, 5)
+	assert.Error(t, err, "the tournament isn't ranked by score")
+}
+
+func TestBracket(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Format: entity.FormatBracket, Selector: entity.SelectStake})
+	assert.Error(t, err, "a bracket is ranked by its matches")
+	var users []entity.User
+	for i := 0; i < 3; i++ {
+		users = append(users, newUser(t, c, 1000))
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatBracket, Payouts: entity.Payouts{Places: []int{70, 30}}})
+	assert.Equal(t, entity.SelectExternal, tr.Selector)
+	for _, u := range users {
+		join(t, c, tr.ID, u.ID)
+	}
+	b, err := c.GetBracket(tr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, b.Rounds, "the players are seeded when it starts")
+	start(t, c, tr.ID)
+
+	b, err = c.GetBracket(tr.ID)
+	require.NoError(t, err)
+	require.Len(t, b.Rounds, 2)
+	assert.Equal(t, entity.Match{Round: 1, Slot: 1, Player1: users[1].ID, Player2: users[2].ID}, b.Rounds[0][1])
+	_, err = c.FinishTourn(tr.ID, nil)
+	require.Error(t, err, "the bracket isn't played out")
+	assert.Equal(t, http.StatusConflict, err.(entity.Error).Code)
+	_, err = c.FinishTourn(tr.ID, []int{users[0].ID, users[1].ID})
+	assert.Error(t, err)
+
+	_, err = c.ReportMatch(tr.ID, entity.Match{Round: 1, Slot: 1, Winner: users[2].ID})
+	require.NoError(t, err)
+	b, err = c.ReportMatch(tr.ID, entity.Match{Round: 2, Slot: 0, Winner: users[2].ID})
+	require.NoError(t, err)
+	assert.Equal(t, users[2].ID, b.Winner)
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status, "the final finishes the tournament")
+	assert.Equal(t, users[2].ID, tourn.Winner)
+	assert.Equal(t, 600, balance(t, c, users[1].ID))
+	assert.Equal(t, 690, balance(t, c, users[0].ID), "the loser of the final is second")
+	assert.Equal(t, 810, balance(t, c, users[2].ID))
+	requireBalanced(t, c)
+
+	_, err = c.GetBracket(newTourn(t, c, entity.Tournament{Deposit: 100}).ID)
+	assert.Error(t, err, "the tournament has no bracket")
+}
+
+func TestLeague(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Format: entity.FormatRoundRobin, Selector: entity.SelectScore})
+	assert.Error(t, err, "a league is ranked by its matches")
+	var users []entity.User
+	for i := 0; i < 3; i++ {
+		users = append(users, newUser(t, c, 1000))
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatRoundRobin})
+	for _, u := range users {
+		join(t, c, tr.ID, u.ID)
+	}
+	l, err := c.GetLeague(tr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, l.Rounds, "the schedule is made when it starts")
+	assert.Len(t, l.Standings, 3)
+	start(t, c, tr.ID)
+
+	l, err = c.GetLeague(tr.ID)
+	require.NoError(t, err)
+	require.Len(t, l.Rounds, 3)
+	_, err = c.FinishTourn(tr.ID, []int{users[0].ID})
+	assert.Error(t, err, "a league is ranked by its matches")
+	_, err = c.ReportMatch(tr.ID, entity.Match{Round: 1, Slot: 0, Winner: users[1].ID})
+	assert.Error(t, err, "the tournament has no bracket")
+
+	for _, r := range l.Rounds {
+		m := r[0]
+		m.Winner = m.Player1
+		if m.Player1 == users[2].ID || m.Player2 == users[2].ID {
+			m.Winner = users[2].ID
+		}
+		l, err = c.ReportLeagueMatch(tr.ID, m)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, users[2].ID, l.Standings[0].UserID)
+	assert.Equal(t, 6, l.Standings[0].Points)
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status, "the last match finishes the league")
+	assert.Equal(t, users[2].ID, tourn.Winner)
+	assert.Equal(t, 900, balance(t, c, users[2].ID))
+	requireBalanced(t, c)
+
+	_, err = c.GetLeague(newTourn(t, c, entity.Tournament{Deposit: 100}).ID)
+	assert.Error(t, err, "the tournament isn't a league")
+}
+
+func TestSwiss(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.RegTourn(entity.Tournament{Name: "cup", Deposit: 100, Format: entity.FormatRoundRobin, Rounds: 2})
+	assert.Error(t, err, "only a Swiss tournament has a number of rounds")
+	var users []entity.User
+	for i := 0; i < 3; i++ {
+		users = append(users, newUser(t, c, 1000))
+	}
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatSwiss})
+	for _, u := range users {
+		join(t, c, tr.ID, u.ID)
+	}
+	start(t, c, tr.ID)
+
+	s, err := c.GetSwiss(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, [][]entity.Match{{
+		{Round: 1, Slot: 0, Player1: users[0].ID, Player2: users[1].ID},
+		{Round: 1, Slot: 1, Player1: users[2].ID, Winner: users[2].ID, Bye: true},
+	}}, s.Rounds)
+	_, err = c.ReportLeagueMatch(tr.ID, entity.Match{Round: 1, Slot: 0, Winner: users[0].ID})
+	assert.Error(t, err, "the tournament isn't a league")
+
+	s, err = c.ReportSwissMatch(tr.ID, entity.Match{Round: 1, Slot: 0, Winner: users[0].ID})
+	require.NoError(t, err)
+	require.Len(t, s.Rounds, 2, "the played round pairs the next one")
+	assert.Equal(t, []entity.Match{
+		{Round: 2, Slot: 0, Player1: users[0].ID, Player2: users[2].ID},
+		{Round: 2, Slot: 1, Player1: users[1].ID, Winner: users[1].ID, Bye: true},
+	}, s.Rounds[1])
+
+	s, err = c.ReportSwissMatch(tr.ID, entity.Match{Round: 2, Slot: 0, Winner: users[2].ID})
+	require.NoError(t, err)
+	assert.Len(t, s.Rounds, 2)
+	assert.Equal(t, []int{users[2].ID, users[0].ID, users[1].ID}, s.Ranking())
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status, "the last round finishes the tournament")
+	assert.Equal(t, users[2].ID, tourn.Winner)
+	assert.Equal(t, 900, balance(t, c, users[2].ID))
+	requireBalanced(t, c)
+
+	_, err = c.GetSwiss(newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatRoundRobin}).ID)
+	assert.Error(t, err, "the tournament isn't a Swiss tournament")
+}
+
+func TestRating(t *testing.T) {
+	c := New(memory.New())
+	u1, u2 := newUser(t, c, 1000), newUser(t, c, 1000)
+	assert.Equal(t, entity.InitialRating, u1.Rating)
+	league := newTourn(t, c, entity.Tournament{Deposit: 100, Format: entity.FormatRoundRobin})
+	join(t, c, league.ID, u1.ID)
+	join(t, c, league.ID, u2.ID)
+	start(t, c, league.ID)
+	_, err := c.ReportLeagueMatch(league.ID, entity.Match{Round: 1, Slot: 0, Winner: u1.ID})
+	require.NoError(t, err)
+
+	r, err := c.GetRating(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserRating{UserID: u1.ID, Name: "user", Rating: 1516, History: []entity.TournamentRating{
+		{TournamentID: league.ID, Before: 1500, After: 1516, Games: 1},
+	}}, r)
+	p, err := c.Ratings(entity.Page{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.User{{ID: u1.ID, Name: "user", Balance: 800, Rating: 1516, PolicyID: 1}}, p.Users)
+	assert.Equal(t, 2, p.Total)
+
+	band := newTourn(t, c, entity.Tournament{Deposit: 100, MinRating: 1510})
+	_, err = c.JoinTourn(band.ID, u2.ID, "")
+	assert.Error(t, err, "u2's rating is below the band")
+	join(t, c, band.ID, u1.ID)
+
+	score := newTourn(t, c, entity.Tournament{Deposit: 100, Selector: entity.SelectScore})
+	u3 := newUser(t, c, 1000)
+	for _, u := range []entity.User{u1, u2, u3} {
+		join(t, c, score.ID, u.ID)
+	}
+	start(t, c, score.ID)
+	_, err = c.SubmitScore(score.ID, u2.ID, 10)
+	require.NoError(t, err)
+	_, err = c.SubmitScore(score.ID, u3.ID, 5)
+	require.NoError(t, err)
+	_, err = c.FinishTourn(score.ID, nil)
+	require.NoError(t, err)
+	r, err = c.GetRating(u2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1484+17, r.Rating, "only scored players are rated")
+	assert.Len(t, r.History, 2)
+	r, err = c.GetRating(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1516, r.Rating)
+
+	_, err = c.GetRating(42)
+	assert.Error(t, err)
+}
+
+func TestCancelTourn(t *testing.T) {
+	c := New(memory.New())
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+
+	tourn, err := c.CancelTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Cancelled, tourn.Status)
+	assert.Equal(t, 700, balance(t, c, u1.ID))
+	assert.Equal(t, 700, balance(t, c, u2.ID))
+	requireBalanced(t, c)
+	_, err = c.CancelTourn(tr.ID)
+	assert.Error(t, err)
+}
+
+func TestDelTourn(t *testing.T) {
+	c := New(memory.New())
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+
+	require.NoError(t, c.DelTourn(tr.ID))
+	_, err := c.GetTourn(tr.ID)
+	assert.Error(t, err)
+	assert.Equal(t, 700, balance(t, c, u1.ID), "an unfinished tournament is cancelled first")
+	assert.Equal(t, 700, balance(t, c, u2.ID), "an unfinished tournament is cancelled first")
+	requireBalanced(t, c)
+
+	finished := newTourn(t, c, entity.Tournament{Deposit: 100})
+	join(t, c, finished.ID, u1.ID)
+	start(t, c, finished.ID)
+	tourn, err := c.FinishTourn(finished.ID, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.DelTourn(finished.ID))
+	assert.Equal(t, 700, balance(t, c, tourn.Winner), "the prize stays paid")
+	requireBalanced(t, c)
+}
+
+func TestListTransactions(t *testing.T) {
+	c := New(memory.New())
+	u := newUser(t, c, 1000)
+	page := entity.Page{Limit: 10}
+	noon := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	p, err := c.ListTransactions(entity.TransactionFilter{Page: page, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 2)
+	assert.Equal(t, entity.TxRegFee, p.Transactions[0].Type)
+	assert.Equal(t, -300, p.Transactions[0].Amount)
+
+	for _, f := range []entity.TransactionFilter{
+		{Page: page, UserID: 42},
+		{Page: page, UserID: u.ID, Types: []entity.TransactionType{entity.TxFund, "bet"}},
+		{Page: page, UserID: u.ID, From: noon, To: noon},
+		{Page: page, UserID: u.ID, From: noon, To: noon.Add(-time.Hour)},
+	} {
+		_, err := c.ListTransactions(f)
+		assert.Error(t, err, "%+v", f)
+	}
+	_, err = c.ListTransactions(entity.TransactionFilter{Page: page, UserID: u.ID, From: noon})
+	assert.NoError(t, err)
+}
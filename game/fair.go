@@ -0,0 +1,71 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// newSeed returns a secret server seed and its commitment, the hex encoded
+// SHA-256 of the seed.
+func newSeed() (string, string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+	seed := hex.EncodeToString(b)
+	return seed, seedHash(seed), nil
+}
+
+func seedHash(seed string) string {
+	h := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(h[:])
+}
+
+// drawInput is the participants' part of a draw: "userId:stake" of every
+// entry in the order of registration, separated by commas.
+func drawInput(entries []entity.Entry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		parts[i] = fmt.Sprintf("%d:%d", e.UserID, e.Stake)
+	}
+	return strings.Join(parts, ",")
+}
+
+// draw is a deterministic random source. Its i-th number (from 0) is the first
+// 8 bytes, big-endian, of SHA-256 of "<seed>:<input>:<i>".
+type draw struct {
+	seed  string
+	input string
+	i     int
+}
+
+func newDraw(seed string, entries []entity.Entry) *draw {
+	return &draw{seed: seed, input: drawInput(entries)}
+}
+
+func (d *draw) next() uint64 {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d", d.seed, d.input, d.i)))
+	d.i++
+	return binary.BigEndian.Uint64(h[:8])
+}
+
+// Intn returns a number in [0, n). Numbers that would make the result biased
+// are skipped.
+func (d *draw) Intn(n int) int {
+	un := uint64(n)
+	rem := (math.MaxUint64%un + 1) % un
+	for {
+		v := d.next()
+		if rem == 0 || v <= math.MaxUint64-rem {
+			return int(v % un)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestSeed(t *testing.T) {
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", seedHash("abc"))
+	seed, hash, err := newSeed()
+	require.NoError(t, err)
+	assert.Len(t, seed, 64)
+	assert.Equal(t, seedHash(seed), hash)
+	other, _, err := newSeed()
+	require.NoError(t, err)
+	assert.NotEqual(t, seed, other)
+}
+
+func TestDrawInput(t *testing.T) {
+	assert.Equal(t, "4:100,7:250", drawInput([]entity.Entry{{UserID: 4, Stake: 100}, {UserID: 7, Stake: 250}}))
+	assert.Equal(t, "", drawInput(nil))
+}
+
+func TestDraw(t *testing.T) {
+	entries := []entity.Entry{{UserID: 4, Stake: 100}, {UserID: 7, Stake: 250}}
+	a, b := newDraw("seed", entries), newDraw("seed", entries)
+	var got []int
+	for i := 0; i < 50; i++ {
+		n := a.Intn(3)
+		require.True(t, n >= 0 && n < 3, n)
+		require.Equal(t, n, b.Intn(3), "the same seed and entries draw the same numbers")
+		got = append(got, n)
+	}
+	assert.Contains(t, got, 0)
+	assert.Contains(t, got, 1)
+	assert.Contains(t, got, 2)
+
+	first := newDraw("seed", entries).next()
+	assert.NotEqual(t, first, newDraw("other", entries).next())
+	assert.NotEqual(t, first, newDraw("seed", entries[:1]).next())
+	assert.Equal(t, 0, newDraw("seed", entries).Intn(1))
+}
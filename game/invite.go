@@ -0,0 +1,66 @@
+package game
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// newInviteCode returns a random invite code.
+func newInviteCode() (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CreateInvite adds an invite to a private tournament that isn't over. An
+// invite for nobody in particular without a code gets a random one.
+func (c Controller) CreateInvite(tID int, inv entity.Invite) (entity.Invite, error) {
+	err := inv.IsValid()
+	if err != nil {
+		return inv, err
+	}
+	t, err := c.db.GetTourn(tID)
+	if err != nil {
+		return inv, err
+	}
+	if !t.Private {
+		return inv, entity.RegErr(errors.New("only a private tournament takes invites"))
+	}
+	if t.Status.IsFinal() {
+		return inv, entity.StatusErr(fmt.Errorf("the tournament is %s", t.Status))
+	}
+	if inv.UserID != 0 {
+		_, err = c.db.GetUser(inv.UserID)
+	} else if inv.Code == "" {
+		inv.Code, err = newInviteCode()
+	}
+	if err != nil {
+		return inv, err
+	}
+	inv.TournamentID = tID
+	inv.Uses = 0
+	inv.Revoked = false
+	return c.db.CreateInvite(inv)
+}
+
+// ListInvites lists the invites of a tournament, the oldest first.
+func (c Controller) ListInvites(tID int) ([]entity.Invite, error) {
+	_, err := c.db.GetTourn(tID)
+	if err != nil {
+		return nil, err
+	}
+	return c.db.ListInvites(tID)
+}
+
+// RevokeInvite stops an invite from letting anyone else in. Users it has
+// already let in stay.
+func (c Controller) RevokeInvite(tID, id int) (entity.Invite, error) {
+	return c.db.RevokeInvite(tID, id)
+}
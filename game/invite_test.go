@@ -0,0 +1,82 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+func TestInvite(t *testing.T) {
+	c := New(memory.New())
+	u1, u2, u3 := newUser(t, c, 1000), newUser(t, c, 1000), newUser(t, c, 1000)
+	public := newTourn(t, c, entity.Tournament{Deposit: 100})
+	_, err := c.CreateInvite(public.ID, entity.Invite{})
+	assert.Error(t, err, "only a private tournament takes invites")
+
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, Private: true})
+	_, err = c.CreateInvite(tr.ID, entity.Invite{UserID: 42})
+	assert.Error(t, err, "the user doesn't exist")
+	drawn, err := c.CreateInvite(tr.ID, entity.Invite{MaxUses: 1, Uses: 5, Revoked: true})
+	require.NoError(t, err)
+	assert.Len(t, drawn.Code, 16, "a code is drawn")
+	assert.Equal(t, tr.ID, drawn.TournamentID)
+	assert.Equal(t, 0, drawn.Uses)
+	assert.False(t, drawn.Revoked)
+	personal, err := c.CreateInvite(tr.ID, entity.Invite{UserID: u3.ID})
+	require.NoError(t, err)
+
+	_, err = c.JoinTourn(tr.ID, u1.ID, "")
+	assert.Error(t, err, "the tournament is private")
+	_, err = c.JoinTourn(tr.ID, u1.ID, "nope")
+	assert.Error(t, err)
+	_, err = c.JoinTourn(tr.ID, u1.ID, drawn.Code)
+	require.NoError(t, err)
+	_, err = c.JoinTourn(tr.ID, u2.ID, drawn.Code)
+	assert.Error(t, err, "the invite is used up")
+	_, err = c.RevokeInvite(tr.ID, personal.ID)
+	require.NoError(t, err)
+	_, err = c.JoinTourn(tr.ID, u3.ID, "")
+	assert.Error(t, err, "the invite is revoked")
+	assert.Equal(t, 700, balance(t, c, u3.ID))
+
+	invites, err := c.ListInvites(tr.ID)
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.Equal(t, 1, invites[0].Uses)
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Len(t, tourn.Users, 1, "users an invite has let in stay")
+
+	_, err = c.CancelTourn(tr.ID)
+	require.NoError(t, err)
+	_, err = c.CreateInvite(tr.ID, entity.Invite{Code: "late"})
+	assert.Error(t, err, "the tournament is over")
+	_, err = c.ListInvites(42)
+	assert.Error(t, err)
+}
+
+func TestInviteTeam(t *testing.T) {
+	c := New(memory.New())
+	captain, member := newUser(t, c, 1000), newUser(t, c, 1000)
+	team, err := c.CreateTeam(entity.Team{Name: "red", CaptainID: captain.ID})
+	require.NoError(t, err)
+	_, err = c.JoinTeam(team.ID, member.ID, 1)
+	require.NoError(t, err)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, TeamSize: 2, Private: true})
+	_, err = c.CreateInvite(tr.ID, entity.Invite{UserID: member.ID})
+	require.NoError(t, err)
+
+	_, err = c.RegTeam(tr.ID, team.ID, captain.ID, "", false)
+	assert.Error(t, err, "the invite of a member doesn't let the team in")
+	_, err = c.CreateInvite(tr.ID, entity.Invite{UserID: captain.ID})
+	require.NoError(t, err)
+	_, err = c.RegTeam(tr.ID, team.ID, captain.ID, "", false)
+	require.NoError(t, err)
+	invites, err := c.ListInvites(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, []int{invites[0].Uses, invites[1].Uses})
+}
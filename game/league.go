@@ -0,0 +1,51 @@
+package game
+
+import "github.com/yanrishbe/gaming-website/entity"
+
+// scheduleLeague builds a round-robin schedule where every entry plays every
+// other one once, by the circle method: the first entry stays in place and
+// the others rotate around it from round to round. With an odd number of
+// entries one of them sits out every round.
+func scheduleLeague(entries []entity.Entry) []entity.Match {
+	players := make([]int, len(entries))
+	for i, e := range entries {
+		players[i] = e.UserID
+	}
+	if len(players)%2 == 1 {
+		players = append(players, 0)
+	}
+	n := len(players)
+	var matches []entity.Match
+	for round := 1; round < n; round++ {
+		slot := 0
+		for i := 0; i < n/2; i++ {
+			p1, p2 := players[i], players[n-1-i]
+			if p1 == 0 || p2 == 0 {
+				continue
+			}
+			// The fixed player alternates sides.
+			if i == 0 && round%2 == 0 {
+				p1, p2 = p2, p1
+			}
+			matches = append(matches, entity.Match{Round: round, Slot: slot, Player1: p1, Player2: p2})
+			slot++
+		}
+		last := players[n-1]
+		copy(players[2:], players[1:n-1])
+		players[1] = last
+	}
+	return matches
+}
+
+// recordLeagueMatch records the result r in its match.
+func recordLeagueMatch(matches []entity.Match, r entity.Match) ([]entity.Match, error) {
+	i, err := findMatch(matches, r.Round, r.Slot)
+	if err != nil {
+		return nil, err
+	}
+	m, err := matches[i].Record(r)
+	if err != nil {
+		return nil, err
+	}
+	return []entity.Match{m}, nil
+}
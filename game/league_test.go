@@ -0,0 +1,54 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestScheduleLeague(t *testing.T) {
+	for n := 2; n <= 7; n++ {
+		var uIDs []int
+		for i := 1; i <= n; i++ {
+			uIDs = append(uIDs, i)
+		}
+		matches := scheduleLeague(entries(uIDs...))
+		assert.Len(t, matches, n*(n-1)/2, "n = %d", n)
+		pairs := map[[2]int]bool{}
+		playing := map[int]map[int]bool{}
+		for _, m := range matches {
+			pair := [2]int{m.Player1, m.Player2}
+			if pair[0] > pair[1] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			assert.False(t, pairs[pair], "%v play once", pair)
+			pairs[pair] = true
+			if playing[m.Round] == nil {
+				playing[m.Round] = map[int]bool{}
+			}
+			assert.False(t, playing[m.Round][m.Player1] || playing[m.Round][m.Player2], "a player plays once a round")
+			playing[m.Round][m.Player1], playing[m.Round][m.Player2] = true, true
+		}
+		assert.Len(t, playing, n-1+n%2, "n = %d", n)
+	}
+
+	assert.Equal(t, []entity.Match{
+		{Round: 1, Slot: 0, Player1: 2, Player2: 3},
+		{Round: 2, Slot: 0, Player1: 3, Player2: 1},
+		{Round: 3, Slot: 0, Player1: 1, Player2: 2},
+	}, scheduleLeague(entries(1, 2, 3)), "one player sits out every round, the first one changes sides")
+}
+
+func TestRecordLeagueMatch(t *testing.T) {
+	matches := scheduleLeague(entries(1, 2))
+	changed, err := recordLeagueMatch(matches, entity.Match{Round: 1, Slot: 0, Draw: true})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Match{{Round: 1, Slot: 0, Player1: 1, Player2: 2, Draw: true}}, changed)
+	_, err = recordLeagueMatch(matches, entity.Match{Round: 2, Slot: 0, Winner: 1})
+	assert.Error(t, err, "the match doesn't exist")
+	_, err = recordLeagueMatch(changed, entity.Match{Round: 1, Slot: 0, Winner: 1})
+	assert.Error(t, err, "the match is already decided")
+}
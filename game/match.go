@@ -0,0 +1,17 @@
+package game
+
+import (
+	"errors"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// findMatch returns the index of the match in round and slot.
+func findMatch(matches []entity.Match, round, slot int) (int, error) {
+	for i, m := range matches {
+		if m.Round == round && m.Slot == slot {
+			return i, nil
+		}
+	}
+	return 0, entity.ReqErr(errors.New("the match doesn't exist"))
+}
@@ -0,0 +1,56 @@
+package game
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// CurrentPolicy returns the registration policy in force.
+func (c Controller) CurrentPolicy() (entity.Policy, error) {
+	return c.db.CurrentPolicy()
+}
+
+// GetPolicy returns a version of the registration policy, such as the one a
+// user registered under.
+func (c Controller) GetPolicy(id int) (entity.Policy, error) {
+	return c.db.GetPolicy(id)
+}
+
+// SetPolicy puts a new registration policy in force. Users who have
+// registered already keep the policy they registered under on record. Cash is
+// spent first unless the policy tells otherwise.
+func (c Controller) SetPolicy(p entity.Policy) (entity.Policy, error) {
+	if p.SpendOrder == "" {
+		p.SpendOrder = entity.CashFirst
+	}
+	err := p.IsValid()
+	if err != nil {
+		return p, err
+	}
+	return c.db.SetPolicy(p)
+}
+
+// LoadPolicy puts the registration policy p, read at startup, in force unless
+// it is in force already.
+func (c Controller) LoadPolicy(p entity.Policy) (entity.Policy, error) {
+	current, err := c.db.CurrentPolicy()
+	if err != nil {
+		return current, err
+	}
+	if current.Same(p) {
+		return current, nil
+	}
+	p, err = c.SetPolicy(p)
+	if err != nil {
+		return p, err
+	}
+	logrus.WithFields(logrus.Fields{
+		"fee":        p.Fee,
+		"bonus":      p.Bonus,
+		"minBalance": p.MinBalance,
+		"wagering":   p.Wagering,
+		"spendOrder": p.SpendOrder,
+	}).Info("registration policy changed")
+	return p, nil
+}
@@ -0,0 +1,73 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+func TestRegUserPolicy(t *testing.T) {
+	c := New(memory.New())
+	_, err := c.SetPolicy(entity.Policy{Fee: 100, MinBalance: 50})
+	assert.Error(t, err)
+	p, err := c.SetPolicy(entity.Policy{Fee: 100, Bonus: 50, MinBalance: 200})
+	require.NoError(t, err)
+
+	_, err = c.RegUser(entity.User{Name: "poor", Balance: 199})
+	assert.Error(t, err)
+	u, err := c.RegUser(entity.User{Name: "user", Balance: 200})
+	require.NoError(t, err)
+	assert.Equal(t, 150, u.Balance)
+	assert.Equal(t, p.ID, u.PolicyID)
+	requireBalanced(t, c)
+
+	got, err := c.GetPolicy(u.PolicyID)
+	require.NoError(t, err)
+	assert.True(t, got.Same(p))
+}
+
+func TestLoadPolicy(t *testing.T) {
+	c := New(memory.New())
+	p, err := c.LoadPolicy(entity.DefaultPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.ID, "the same terms don't make a new version")
+
+	p, err = c.LoadPolicy(entity.Policy{Fee: 100, Bonus: 100})
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.ID)
+	current, err := c.CurrentPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, current.ID)
+
+	_, err = c.LoadPolicy(entity.Policy{Fee: -1})
+	assert.Error(t, err)
+}
+
+func TestBonusPoints(t *testing.T) {
+	c := New(memory.New())
+	p, err := c.SetPolicy(entity.Policy{Fee: 100, Bonus: 200, MinBalance: 100, Wagering: 2})
+	require.NoError(t, err)
+	assert.Equal(t, entity.CashFirst, p.SpendOrder, "cash is spent first by default")
+
+	u, err := c.RegUser(entity.User{Name: "user", Balance: 150})
+	require.NoError(t, err)
+	assert.Equal(t, 50, u.Balance)
+	assert.Equal(t, 200, u.Bonus)
+	_, err = c.TakePoints(u.ID, 51)
+	assert.Error(t, err, "bonus points aren't withdrawn")
+
+	expensive := newTourn(t, c, entity.Tournament{Deposit: 300})
+	_, err = c.JoinTourn(expensive.ID, u.ID, "")
+	assert.Error(t, err)
+	tr := newTourn(t, c, entity.Tournament{Deposit: 250})
+	join(t, c, tr.ID, u.ID)
+	u, err = c.GetUser(u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, u.Balance)
+	assert.Equal(t, 0, u.Bonus, "deposits spend cash and bonus points together")
+	requireBalanced(t, c)
+}
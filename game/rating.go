@@ -0,0 +1,103 @@
+package game
+
+import (
+	"math"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// ratingK is the most a rating moves by a single match.
+const ratingK = 32
+
+// expected returns the Elo expectation of the score of a player rated a
+// against a player rated b.
+func expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// rated reports whether the players of entries are rated. Teams aren't, as
+// their captains play for the whole team.
+func rated(entries []entity.Entry) bool {
+	for _, e := range entries {
+		if e.TeamID != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rateMatches returns the changes of the ratings by the matches of changed
+// that have just been played, compared with matches. Byes aren't rated. A
+// match moves the ratings of its players by the same amount in opposite
+// directions.
+func rateMatches(tID int, entries []entity.Entry, matches, changed []entity.Match) []entity.RatingChange {
+	if !rated(entries) {
+		return nil
+	}
+	ratings := map[int]int{}
+	for _, e := range entries {
+		ratings[e.UserID] = e.Rating
+	}
+	var changes []entity.RatingChange
+	for _, m := range changed {
+		if !m.Played() || m.Bye {
+			continue
+		}
+		i, err := findMatch(matches, m.Round, m.Slot)
+		if err == nil && matches[i].Played() {
+			continue
+		}
+		score := 0.5
+		switch m.Winner {
+		case m.Player1:
+			score = 1
+		case m.Player2:
+			score = 0
+		}
+		change := int(math.Round(ratingK * (score - expected(ratings[m.Player1], ratings[m.Player2]))))
+		ratings[m.Player1] += change
+		ratings[m.Player2] -= change
+		changes = append(changes,
+			entity.RatingChange{UserID: m.Player1, TournamentID: tID, Change: change},
+			entity.RatingChange{UserID: m.Player2, TournamentID: tID, Change: -change})
+	}
+	return changes
+}
+
+// rateScores returns the changes of the ratings by the scores of a score
+// tournament. Every player who has submitted a score meets every other one
+// in a virtual match won by the higher score, and the changes are scaled so
+// that the whole tournament moves a rating as much as a single match.
+func rateScores(tID int, entries []entity.Entry) []entity.RatingChange {
+	if !rated(entries) {
+		return nil
+	}
+	var scored []entity.Entry
+	for _, e := range entries {
+		if e.Score != nil {
+			scored = append(scored, e)
+		}
+	}
+	if len(scored) < 2 {
+		return nil
+	}
+	k := ratingK / float64(len(scored)-1)
+	changes := make([]entity.RatingChange, len(scored))
+	for i, a := range scored {
+		sum := 0.0
+		for j, b := range scored {
+			if i == j {
+				continue
+			}
+			score := 0.5
+			if *a.Score > *b.Score {
+				score = 1
+			} else if *a.Score < *b.Score {
+				score = 0
+			}
+			sum += score - expected(a.Rating, b.Rating)
+		}
+		changes[i] = entity.RatingChange{UserID: a.UserID, TournamentID: tID, Change: int(math.Round(k * sum))}
+	}
+	return changes
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestExpected(t *testing.T) {
+	assert.Equal(t, 0.5, expected(1500, 1500))
+	assert.InDelta(t, 10.0/11, expected(1900, 1500), 1e-9)
+	assert.InDelta(t, 1.0/11, expected(1500, 1900), 1e-9)
+	assert.InDelta(t, 1, expected(1234, 1789)+expected(1789, 1234), 1e-9)
+}
+
+func rated1500(uIDs ...int) []entity.Entry {
+	es := entries(uIDs...)
+	for i := range es {
+		es[i].Rating = 1500
+	}
+	return es
+}
+
+func TestRateMatches(t *testing.T) {
+	upset := []entity.Entry{{UserID: 1, Rating: 1400}, {UserID: 2, Rating: 1800}}
+	tests := []struct {
+		name    string
+		entries []entity.Entry
+		matches []entity.Match
+		changed []entity.Match
+		want    map[int]int
+	}{
+		{"win between equals", rated1500(1, 2), nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 1}},
+			map[int]int{1: 16, 2: -16}},
+		{"loss between equals", rated1500(1, 2), nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 2}},
+			map[int]int{1: -16, 2: 16}},
+		{"draw between equals", rated1500(1, 2), nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Draw: true}},
+			map[int]int{1: 0, 2: 0}},
+		{"upset", upset, nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 1}},
+			map[int]int{1: 29, 2: -29}},
+		{"favourite wins", upset, nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 2}},
+			map[int]int{1: -3, 2: 3}},
+		{"draw with the favourite", upset, nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Draw: true}},
+			map[int]int{1: 13, 2: -13}},
+		{"second match rated from the first", rated1500(1, 2, 3), nil,
+			[]entity.Match{{Slot: 0, Player1: 1, Player2: 2, Winner: 1}, {Slot: 1, Player1: 1, Player2: 3, Winner: 3}},
+			map[int]int{1: 16 - 17, 2: -16, 3: 17}},
+		{"bye", rated1500(1), nil,
+			[]entity.Match{{Player1: 1, Winner: 1, Bye: true}},
+			map[int]int{}},
+		{"unplayed", rated1500(1, 2), nil,
+			[]entity.Match{{Player1: 1, Player2: 2}},
+			map[int]int{}},
+		{"already rated", rated1500(1, 2),
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 1}},
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 1}},
+			map[int]int{}},
+		{"teams", []entity.Entry{{UserID: 1, TeamID: 1}, {UserID: 2, TeamID: 2}}, nil,
+			[]entity.Match{{Player1: 1, Player2: 2, Winner: 1}},
+			map[int]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[int]int{}
+			sum := 0
+			for _, c := range rateMatches(7, tt.entries, tt.matches, tt.changed) {
+				assert.Equal(t, 7, c.TournamentID)
+				got[c.UserID] += c.Change
+				sum += c.Change
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, 0, sum, "matches are zero-sum")
+		})
+	}
+}
+
+func TestRateScores(t *testing.T) {
+	score := func(s int) *int {
+		return &s
+	}
+	tests := []struct {
+		name    string
+		entries []entity.Entry
+		want    []entity.RatingChange
+	}{
+		{"ranked by score", []entity.Entry{
+			{UserID: 1, Rating: 1500, Score: score(3)},
+			{UserID: 2, Rating: 1500, Score: score(1)},
+			{UserID: 3, Rating: 1500, Score: score(2)},
+		}, []entity.RatingChange{
+			{UserID: 1, TournamentID: 7, Change: 16},
+			{UserID: 2, TournamentID: 7, Change: -16},
+			{UserID: 3, TournamentID: 7, Change: 0},
+		}},
+		{"tie", []entity.Entry{
+			{UserID: 1, Rating: 1500, Score: score(2)},
+			{UserID: 2, Rating: 1500, Score: score(2)},
+		}, []entity.RatingChange{
+			{UserID: 1, TournamentID: 7, Change: 0},
+			{UserID: 2, TournamentID: 7, Change: 0},
+		}},
+		{"unscored players aren't rated", []entity.Entry{
+			{UserID: 1, Rating: 1400, Score: score(1)},
+			{UserID: 2, Rating: 1500},
+			{UserID: 3, Rating: 1800, Score: score(0)},
+		}, []entity.RatingChange{
+			{UserID: 1, TournamentID: 7, Change: 29},
+			{UserID: 3, TournamentID: 7, Change: -29},
+		}},
+		{"a single score", []entity.Entry{
+			{UserID: 1, Rating: 1500, Score: score(1)},
+			{UserID: 2, Rating: 1500},
+		}, nil},
+		{"teams", []entity.Entry{
+			{UserID: 1, TeamID: 1, Score: score(1)},
+			{UserID: 2, TeamID: 2, Score: score(0)},
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rateScores(7, tt.entries))
+		})
+	}
+}
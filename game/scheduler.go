@@ -0,0 +1,101 @@
+package game
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// Clock tells the scheduler what time it is.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Scheduler moves tournaments along their schedules: it opens the
+// registration at OpensAt, starts the tournament at StartsAt and finishes it
+// at EndsAt. Tournaments with the external selector wait for their result to
+// be posted, except leagues and Swiss tournaments, which end with the
+// standings they have.
+//
+// Any number of instances may run a scheduler on the same storage. Every
+// step is a status transition checked under the storage's lock, so only one
+// instance takes it and the others get a conflict, which they ignore.
+type Scheduler struct {
+	c     Controller
+	clock Clock
+}
+
+func NewScheduler(c Controller, clock Clock) Scheduler {
+	return Scheduler{c: c, clock: clock}
+}
+
+// Run calls Tick every interval until ctx is done.
+func (s Scheduler) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.Tick()
+			if err != nil {
+				logrus.WithError(err).Error("scheduler failed")
+			}
+		}
+	}
+}
+
+// Tick takes every step that is due. A tournament whose next steps are due
+// as well is moved through all of them.
+func (s Scheduler) Tick() error {
+	now := s.clock.Now()
+	ts, err := s.c.db.DueTourns(now)
+	if err != nil {
+		return err
+	}
+	for _, t := range ts {
+		id := t.ID
+		for {
+			next, ok := t.Due(now)
+			if !ok || next == entity.Finished && t.Selector == entity.SelectExternal && !t.Format.HasStandings() {
+				break
+			}
+			t, err = s.step(id, next)
+			if err != nil {
+				if !isConflict(err) {
+					logrus.WithError(err).WithFields(logrus.Fields{"tournament": id, "status": next}).
+						Error("scheduled step failed")
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func (s Scheduler) step(id int, to entity.Status) (entity.Tournament, error) {
+	switch to {
+	case entity.Open:
+		return s.c.OpenTourn(id)
+	case entity.Running:
+		return s.c.StartTourn(id)
+	}
+	return s.c.FinishTourn(id, nil)
+}
+
+// isConflict reports whether err means the tournament has moved on already.
+func isConflict(err error) bool {
+	e, ok := err.(entity.Error)
+	return ok && (e.Type == entity.ErrTransition || e.Type == entity.ErrStatus)
+}
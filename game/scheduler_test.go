@@ -0,0 +1,179 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// staleDB returns the tournaments that were due when it was made, like a
+// storage another scheduler has changed since.
+type staleDB struct {
+	*memory.DB
+	due []entity.Tournament
+}
+
+func (db staleDB) DueTourns(now time.Time) ([]entity.Tournament, error) {
+	return db.due, nil
+}
+
+var epoch = time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func at(d time.Duration) *time.Time {
+	t := epoch.Add(d)
+	return &t
+}
+
+// scheduled registers a draft tournament that opens in an hour, starts in two
+// and ends in three.
+func scheduled(t *testing.T, c Controller, tourn entity.Tournament) entity.Tournament {
+	t.Helper()
+	tourn.Name = "scheduled"
+	tourn.Deposit = 100
+	tourn.OpensAt, tourn.StartsAt, tourn.EndsAt = at(time.Hour), at(2*time.Hour), at(3*time.Hour)
+	tourn, err := c.RegTourn(tourn)
+	require.NoError(t, err)
+	require.Equal(t, entity.Draft, tourn.Status)
+	return tourn
+}
+
+func status(t *testing.T, c Controller, id int) entity.Status {
+	t.Helper()
+	tourn, err := c.GetTourn(id)
+	require.NoError(t, err)
+	return tourn.Status
+}
+
+func TestTick(t *testing.T) {
+	c := New(memory.New())
+	clock := &fakeClock{now: epoch}
+	s := NewScheduler(c, clock)
+	u1 := newUser(t, c, 1000)
+	u2 := newUser(t, c, 1000)
+	tr := scheduled(t, c, entity.Tournament{})
+
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Draft, status(t, c, tr.ID))
+
+	clock.now = *tr.OpensAt
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Open, status(t, c, tr.ID))
+	join(t, c, tr.ID, u1.ID)
+	join(t, c, tr.ID, u2.ID)
+
+	clock.now = tr.StartsAt.Add(time.Minute)
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Running, status(t, c, tr.ID))
+
+	clock.now = *tr.EndsAt
+	require.NoError(t, s.Tick())
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Contains(t, []int{u1.ID, u2.ID}, tourn.Winner)
+	assert.Equal(t, 1400, balance(t, c, u1.ID)+balance(t, c, u2.ID))
+	requireBalanced(t, c)
+
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Finished, status(t, c, tr.ID))
+}
+
+func TestTickCatchesUp(t *testing.T) {
+	c := New(memory.New())
+	s := NewScheduler(c, &fakeClock{now: epoch.Add(4 * time.Hour)})
+	u := newUser(t, c, 1000)
+	tr := scheduled(t, c, entity.Tournament{})
+	late, err := c.RegTourn(entity.Tournament{Name: "late", Deposit: 100, Status: entity.Open,
+		StartsAt: at(2 * time.Hour), EndsAt: at(3 * time.Hour)})
+	require.NoError(t, err)
+	join(t, c, late.ID, u.ID)
+
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Finished, status(t, c, late.ID), "every due step is taken at once")
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	assert.Equal(t, entity.Cancelled, status(t, c, tr.ID), "nobody joined while it was open")
+	requireBalanced(t, c)
+}
+
+func TestTickExternal(t *testing.T) {
+	c := New(memory.New())
+	clock := &fakeClock{now: *at(time.Hour)}
+	s := NewScheduler(c, clock)
+	u := newUser(t, c, 1000)
+	external := scheduled(t, c, entity.Tournament{Selector: entity.SelectExternal})
+	league := scheduled(t, c, entity.Tournament{Format: entity.FormatRoundRobin})
+	require.NoError(t, s.Tick())
+	join(t, c, external.ID, u.ID)
+	join(t, c, league.ID, u.ID)
+
+	clock.now = *at(3 * time.Hour)
+	require.NoError(t, s.Tick())
+	assert.Equal(t, entity.Running, status(t, c, external.ID), "the result is posted")
+	assert.Equal(t, entity.Finished, status(t, c, league.ID), "a league ends with its standings")
+
+	_, err := c.FinishTourn(external.ID, []int{u.ID})
+	require.NoError(t, err)
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	requireBalanced(t, c)
+}
+
+func TestTickConflict(t *testing.T) {
+	db := memory.New()
+	c := New(db)
+	clock := &fakeClock{now: *at(time.Hour)}
+	u := newUser(t, c, 1000)
+	tr := scheduled(t, c, entity.Tournament{})
+	require.NoError(t, NewScheduler(c, clock).Tick())
+	join(t, c, tr.ID, u.ID)
+
+	clock.now = *at(3 * time.Hour)
+	due, err := db.DueTourns(clock.now)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	first := NewScheduler(c, clock)
+	second := NewScheduler(New(staleDB{DB: db, due: due}), clock)
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	require.NoError(t, first.Tick())
+	require.NoError(t, second.Tick())
+	assert.Empty(t, hook.AllEntries(), "the steps taken by the first scheduler are skipped")
+	tourn, err := c.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, u.ID, tourn.Winner)
+	assert.Equal(t, 700, balance(t, c, u.ID), "the prize is paid once")
+	requireBalanced(t, c)
+
+	// Two schedulers ticking at the same time take every step once.
+	tr, err = c.RegTourn(entity.Tournament{Name: "open", Deposit: 100, Status: entity.Open,
+		StartsAt: at(2 * time.Hour), EndsAt: at(3 * time.Hour)})
+	require.NoError(t, err)
+	join(t, c, tr.ID, u.ID)
+	done := make(chan error)
+	for _, s := range []Scheduler{first, NewScheduler(c, clock)} {
+		go func(s Scheduler) {
+			done <- s.Tick()
+		}(s)
+	}
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	assert.Equal(t, entity.Finished, status(t, c, tr.ID))
+	assert.Equal(t, 700, balance(t, c, u.ID))
+	assert.Empty(t, hook.AllEntries())
+	requireBalanced(t, c)
+}
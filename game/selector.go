@@ -0,0 +1,173 @@
+package game
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// WinnerSelector ranks the entries of a tournament, which are in the order of
+// registration. The first user of the ranking is the winner.
+type WinnerSelector interface {
+	Rank(entries []entity.Entry) ([]int, error)
+}
+
+// newSelector returns the selector of the tournament. ranking is the result
+// supplied with the finish request, empty if there is none.
+func newSelector(t entity.Tournament, ranking []int, intn func(n int) int) (WinnerSelector, error) {
+	s := t.Selector
+	if len(ranking) != 0 && s != entity.SelectExternal {
+		return nil, entity.ReqErr(errors.New("the ranking can be supplied only to a tournament with the external selector"))
+	}
+	switch s {
+	case entity.SelectUniform:
+		return uniform{intn: intn}, nil
+	case entity.SelectStake:
+		return stakeWeighted{intn: intn}, nil
+	case entity.SelectFirst:
+		return firstRegistered{}, nil
+	case entity.SelectExternal:
+		return external{ranking: ranking}, nil
+	case entity.SelectScore:
+		return byScore{tieBreak: t.TieBreak, intn: intn}, nil
+	}
+	return nil, entity.ReqErr(errors.New("unknown winner selector"))
+}
+
+// uniform draws the places one by one, every remaining entry having the same
+// chance.
+type uniform struct {
+	intn func(n int) int
+}
+
+func (s uniform) Rank(entries []entity.Entry) ([]int, error) {
+	rest := append([]entity.Entry(nil), entries...)
+	ranking := make([]int, 0, len(entries))
+	for len(rest) > 0 {
+		i := s.intn(len(rest))
+		ranking = append(ranking, rest[i].UserID)
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	return ranking, nil
+}
+
+// stakeWeighted draws the places one by one, the chances of the remaining
+// entries being proportional to their stakes. Entries without a stake take
+// the last places in the order of registration.
+type stakeWeighted struct {
+	intn func(n int) int
+}
+
+func (s stakeWeighted) Rank(entries []entity.Entry) ([]int, error) {
+	total := 0
+	for _, e := range entries {
+		total += e.Stake
+	}
+	if total <= 0 {
+		return nil, entity.ReqErr(errors.New("can't weight by stake, nothing is staked"))
+	}
+	rest := append([]entity.Entry(nil), entries...)
+	ranking := make([]int, 0, len(entries))
+	for total > 0 {
+		r := s.intn(total)
+		i := 0
+		for ; i < len(rest)-1 && r >= rest[i].Stake; i++ {
+			r -= rest[i].Stake
+		}
+		ranking = append(ranking, rest[i].UserID)
+		total -= rest[i].Stake
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	for _, e := range rest {
+		ranking = append(ranking, e.UserID)
+	}
+	return ranking, nil
+}
+
+type firstRegistered struct{}
+
+func (firstRegistered) Rank(entries []entity.Entry) ([]int, error) {
+	ranking := make([]int, len(entries))
+	for i, e := range entries {
+		ranking[i] = e.UserID
+	}
+	return ranking, nil
+}
+
+// external takes the ranking from the finish request. It may rank only the
+// best participants.
+type external struct {
+	ranking []int
+}
+
+func (s external) Rank(entries []entity.Entry) ([]int, error) {
+	if len(s.ranking) == 0 {
+		return nil, entity.ReqErr(errors.New("the winner must be supplied"))
+	}
+	ranked := make(map[int]bool, len(s.ranking))
+	for _, uID := range s.ranking {
+		if ranked[uID] {
+			return nil, entity.ReqErr(errors.New("a user is ranked twice"))
+		}
+		ranked[uID] = true
+	}
+	n := 0
+	for _, e := range entries {
+		if ranked[e.UserID] {
+			n++
+		}
+	}
+	if n != len(s.ranking) {
+		return nil, entity.ReqErr(errors.New("the ranking has a user who isn't a participant"))
+	}
+	return s.ranking, nil
+}
+
+// byScore ranks the entries by their scores, the highest first. Entries
+// without a score take the last places in the order of registration.
+type byScore struct {
+	tieBreak entity.TieBreak
+	intn     func(n int) int
+}
+
+func (s byScore) Rank(entries []entity.Entry) ([]int, error) {
+	sorted := append([]entity.Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Score == nil || b.Score == nil {
+			return a.Score != nil && b.Score == nil
+		}
+		if *a.Score != *b.Score {
+			return *a.Score > *b.Score
+		}
+		if s.tieBreak == entity.TieFirstSubmitted {
+			return a.ScoredAt.Before(*b.ScoredAt)
+		}
+		return false
+	})
+	ranking := make([]int, 0, len(sorted))
+	for i := 0; i < len(sorted); {
+		j := i + 1
+		for j < len(sorted) && tied(sorted[i], sorted[j]) {
+			j++
+		}
+		if s.tieBreak == entity.TieRandom && sorted[i].Score != nil {
+			drawn, err := uniform{intn: s.intn}.Rank(sorted[i:j])
+			if err != nil {
+				return nil, err
+			}
+			ranking = append(ranking, drawn...)
+		} else {
+			for _, e := range sorted[i:j] {
+				ranking = append(ranking, e.UserID)
+			}
+		}
+		i = j
+	}
+	return ranking, nil
+}
+
+func tied(a, b entity.Entry) bool {
+	return a.Score != nil && b.Score != nil && *a.Score == *b.Score
+}
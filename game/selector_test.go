@@ -0,0 +1,154 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// fixed returns an intn that always answers r.
+func fixed(r int) func(n int) int {
+	return func(n int) int {
+		return r
+	}
+}
+
+// answers returns an intn that answers rs one by one.
+func answers(t *testing.T, rs ...int) func(n int) int {
+	return func(n int) int {
+		require.NotEmpty(t, rs, "too many draws")
+		r := rs[0]
+		rs = rs[1:]
+		return r
+	}
+}
+
+func TestUniform(t *testing.T) {
+	entries := []entity.Entry{{UserID: 4, Stake: 100}, {UserID: 7, Stake: 100}, {UserID: 9, Stake: 100}}
+	var sizes []int
+	s, err := newSelector(entity.Tournament{Selector: entity.SelectUniform}, nil, func(n int) int {
+		sizes = append(sizes, n)
+		return n - 1
+	})
+	require.NoError(t, err)
+	ranking, err := s.Rank(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []int{9, 7, 4}, ranking)
+	assert.Equal(t, []int{3, 2, 1}, sizes, "every place is drawn from the rest")
+
+	s, err = newSelector(entity.Tournament{Selector: entity.SelectUniform}, nil, answers(t, 1, 0, 0))
+	require.NoError(t, err)
+	ranking, err = s.Rank(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 4, 9}, ranking)
+}
+
+func TestStakeWeighted(t *testing.T) {
+	entries := []entity.Entry{{UserID: 4, Stake: 100}, {UserID: 7, Stake: 300}, {UserID: 9, Stake: 100}}
+	for r, want := range map[int]int{0: 4, 99: 4, 100: 7, 399: 7, 400: 9, 499: 9} {
+		s, err := newSelector(entity.Tournament{Selector: entity.SelectStake}, nil, func(n int) int {
+			if n == 500 {
+				return r
+			}
+			return 0
+		})
+		require.NoError(t, err)
+		ranking, err := s.Rank(entries)
+		require.NoError(t, err)
+		assert.Equal(t, want, ranking[0], "r = %d", r)
+		assert.Len(t, ranking, 3)
+	}
+
+	s, err := newSelector(entity.Tournament{Selector: entity.SelectStake}, nil, answers(t, 100, 0))
+	require.NoError(t, err)
+	ranking, err := s.Rank([]entity.Entry{{UserID: 4, Stake: 100}, {UserID: 5}, {UserID: 7, Stake: 300}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 4, 5}, ranking, "entries without a stake come last")
+
+	s, err = newSelector(entity.Tournament{Selector: entity.SelectStake}, nil, fixed(0))
+	require.NoError(t, err)
+	_, err = s.Rank([]entity.Entry{{UserID: 4}, {UserID: 7}})
+	assert.Error(t, err, "nothing is staked")
+}
+
+func TestFirstRegistered(t *testing.T) {
+	s, err := newSelector(entity.Tournament{Selector: entity.SelectFirst}, nil, fixed(1))
+	require.NoError(t, err)
+	ranking, err := s.Rank([]entity.Entry{{UserID: 7}, {UserID: 4}})
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 4}, ranking)
+}
+
+func TestExternal(t *testing.T) {
+	entries := []entity.Entry{{UserID: 4}, {UserID: 7}, {UserID: 9}}
+	s, err := newSelector(entity.Tournament{Selector: entity.SelectExternal}, []int{7, 4}, fixed(0))
+	require.NoError(t, err)
+	ranking, err := s.Rank(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 4}, ranking, "only the best may be ranked")
+
+	for _, tt := range []struct {
+		ranking []int
+		msg     string
+	}{
+		{[]int{5}, "the winner isn't a participant"},
+		{[]int{7, 7}, "a user is ranked twice"},
+		{nil, "the winner must be supplied"},
+	} {
+		s, err = newSelector(entity.Tournament{Selector: entity.SelectExternal}, tt.ranking, fixed(0))
+		require.NoError(t, err)
+		_, err = s.Rank(entries)
+		assert.Error(t, err, tt.msg)
+	}
+}
+
+func TestByScore(t *testing.T) {
+	at := func(sec int) *time.Time {
+		t := time.Date(2019, 5, 1, 12, 0, sec, 0, time.UTC)
+		return &t
+	}
+	score := func(s int) *int {
+		return &s
+	}
+	entries := []entity.Entry{
+		{UserID: 1},
+		{UserID: 2, Score: score(10), ScoredAt: at(3)},
+		{UserID: 3, Score: score(20), ScoredAt: at(9)},
+		{UserID: 4, Score: score(10), ScoredAt: at(1)},
+		{UserID: 5},
+	}
+	for tb, want := range map[entity.TieBreak][]int{
+		entity.TieFirstSubmitted:  {3, 4, 2, 1, 5},
+		entity.TieFirstRegistered: {3, 2, 4, 1, 5},
+	} {
+		s, err := newSelector(entity.Tournament{Selector: entity.SelectScore, TieBreak: tb}, nil, fixed(0))
+		require.NoError(t, err)
+		ranking, err := s.Rank(entries)
+		require.NoError(t, err)
+		assert.Equal(t, want, ranking, tb)
+	}
+
+	var sizes []int
+	s, err := newSelector(entity.Tournament{Selector: entity.SelectScore, TieBreak: entity.TieRandom}, nil, func(n int) int {
+		sizes = append(sizes, n)
+		return n - 1
+	})
+	require.NoError(t, err)
+	ranking, err := s.Rank(entries)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 4, 2, 1, 5}, ranking)
+	assert.Equal(t, []int{1, 2, 1}, sizes, "only tied scores are drawn")
+}
+
+func TestNewSelector(t *testing.T) {
+	for _, sel := range []entity.Selector{entity.SelectUniform, entity.SelectStake, entity.SelectFirst} {
+		_, err := newSelector(entity.Tournament{Selector: sel}, []int{4}, fixed(0))
+		assert.Error(t, err, "only the external selector takes a ranking")
+	}
+	_, err := newSelector(entity.Tournament{Selector: "coin"}, nil, fixed(0))
+	assert.Error(t, err)
+}
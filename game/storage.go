@@ -0,0 +1,80 @@
+package game
+
+import (
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// Storage is the persistence layer the Controller runs on top of.
+// postgres.DB and memory.DB both satisfy it.
+type Storage interface {
+	// CreateUser stores a user with the initial balance u.Balance, pays
+	// the signup bonus and charges the fee of the registration policy p.
+	// The bonus is paid in bonus points if p has a wagering requirement.
+	CreateUser(u entity.User, p entity.Policy) (entity.User, error)
+	GetUser(id int) (entity.User, error)
+	ListUsers(f entity.UserFilter) (entity.UserPage, error)
+	DelUser(id int) error
+	// TakePoints takes points from a user's cash.
+	TakePoints(id, points int) (entity.User, error)
+	FundPoints(id, points int) (entity.User, error)
+	ListTransactions(f entity.TransactionFilter) (entity.TransactionPage, error)
+
+	CreateTourn(t entity.Tournament) (entity.Tournament, error)
+	GetTourn(id int) (entity.Tournament, error)
+	ListTourns(f entity.TournamentFilter) (entity.TournamentPage, error)
+	SetTournStatus(tID int, to entity.Status) error
+	// StartTourn closes the registration. prepare returns the matches the
+	// tournament is played in, if any.
+	StartTourn(tID int, prepare func(t entity.Tournament, entries []entity.Entry) ([]entity.Match, error)) error
+	GetMatches(tID int) ([]entity.Match, error)
+	// ReportMatch records a result in the matches of a running tournament.
+	// report returns the matches it has changed or added and the changes of
+	// the players' ratings.
+	ReportMatch(tID int, report func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error)) error
+	// JoinTourn registers a user if check, given the user, the tournament
+	// and the invite with code, or else the user's own invite, if any, lets
+	// them in. The join counts as a use of the invite. Deposits spend cash
+	// and bonus points in the spend order of the policy in force. The join
+	// that fills a sit-and-go finishes it with the placings rank returns, in
+	// the same transaction, so either both happen or neither does.
+	JoinTourn(tID, uID int, code string, check func(u entity.User, t entity.Tournament, inv *entity.Invite) error, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error)
+	// RegTeam registers a team for a tournament. pay, given the tournament,
+	// the team, its members as users and the invite the captain has like
+	// JoinTourn finds it, returns what every member pays. A registration
+	// that fills a sit-and-go finishes it like a join does.
+	RegTeam(tID, teamID int, code string, pay func(t entity.Tournament, team entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error), rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error)
+	LeaveTourn(tID, uID int) (entity.Tournament, error)
+	SubmitScore(tID, uID, score int) error
+	// FinishTourn pays out the placings rank returns and applies the
+	// changes of the players' ratings. The deposits count toward the
+	// wagering requirements of those who paid them.
+	FinishTourn(tID int, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) error
+	CancelTourn(tID int) error
+	DelTourn(id int) error
+	// DueTourns returns the tournaments, without participants, whose next
+	// scheduled step is due at now.
+	DueTourns(now time.Time) ([]entity.Tournament, error)
+
+	CreateInvite(inv entity.Invite) (entity.Invite, error)
+	ListInvites(tID int) ([]entity.Invite, error)
+	RevokeInvite(tID, id int) (entity.Invite, error)
+
+	CreateTeam(t entity.Team) (entity.Team, error)
+	GetTeam(id int) (entity.Team, error)
+	JoinTeam(teamID int, m entity.TeamMember) error
+	SetTeamShare(teamID, uID, share int) error
+	LeaveTeam(teamID, uID int) error
+
+	// RatingChanges returns the changes of a user's rating, oldest first.
+	RatingChanges(uID int) ([]entity.RatingChange, error)
+
+	// CurrentPolicy returns the latest version of the registration policy.
+	CurrentPolicy() (entity.Policy, error)
+	GetPolicy(id int) (entity.Policy, error)
+	// SetPolicy stores p as the latest version of the registration policy.
+	SetPolicy(p entity.Policy) (entity.Policy, error)
+
+	TrialBalance() (entity.TrialBalance, error)
+}
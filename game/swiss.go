@@ -0,0 +1,157 @@
+package game
+
+import "github.com/yanrishbe/gaming-website/entity"
+
+// pairSwiss pairs a round of a Swiss tournament. ranked lists the players
+// from the top of the standings down, points holds their points and played
+// the matches of the rounds before. Players are paired score group by score
+// group from the top, see pairGroups. With an odd number of players the
+// lowest ranked player of those who have had the fewest byes gets one,
+// unless another of them leaves a round without rematches.
+func pairSwiss(round int, ranked []int, points map[int]int, played []entity.Match) []entity.Match {
+	met := map[[2]int]bool{}
+	byes := map[int]int{}
+	for _, m := range played {
+		if m.Bye {
+			byes[m.Player1]++
+			continue
+		}
+		met[[2]int{m.Player1, m.Player2}] = true
+		met[[2]int{m.Player2, m.Player1}] = true
+	}
+
+	if len(ranked)%2 == 0 {
+		pairs, _ := pairGroups(ranked, points, met)
+		return swissRound(round, pairs, 0)
+	}
+	fewest := byes[ranked[0]]
+	for _, p := range ranked {
+		if byes[p] < fewest {
+			fewest = byes[p]
+		}
+	}
+	bye := -1
+	for i := len(ranked) - 1; i >= 0; i-- {
+		if byes[ranked[i]] > fewest {
+			continue
+		}
+		if bye < 0 {
+			bye = i
+		}
+		pairs, rematches := pairGroups(without(ranked, i), points, met)
+		if rematches == 0 {
+			return swissRound(round, pairs, ranked[i])
+		}
+	}
+	pairs, _ := pairGroups(without(ranked, bye), points, met)
+	return swissRound(round, pairs, ranked[bye])
+}
+
+// pairGroups pairs the players, listed by rank, in groups of the same points
+// from the top down. Within a group every player meets the best ranked player
+// below them they haven't met yet. Those left over float down to the next
+// group, where they are paired first. Players still left at the bottom have
+// all met and are paired again, each with the one closest in points. It
+// returns the players in pairs and the number of rematches.
+func pairGroups(players []int, points map[int]int, met map[[2]int]bool) ([]int, int) {
+	var pairs, floating []int
+	for start := 0; start < len(players); {
+		end := start + 1
+		for end < len(players) && points[players[end]] == points[players[start]] {
+			end++
+		}
+		group := append(floating, players[start:end]...)
+		var paired []int
+		paired, floating = pairUnmet(group, met)
+		pairs = append(pairs, paired...)
+		start = end
+	}
+	rematches := 0
+	for len(floating) > 0 {
+		p := floating[0]
+		closest := 1
+		for i := 2; i < len(floating); i++ {
+			if abs(points[floating[i]]-points[p]) < abs(points[floating[closest]]-points[p]) {
+				closest = i
+			}
+		}
+		pairs = append(pairs, p, floating[closest])
+		floating = without(floating[1:], closest-1)
+		rematches++
+	}
+	return pairs, rematches
+}
+
+// pairUnmet pairs every player of a group, listed by rank, with the best
+// ranked player below them they haven't met. It returns the players in pairs
+// and those left over, by rank.
+func pairUnmet(group []int, met map[[2]int]bool) (pairs, left []int) {
+	for len(group) > 0 {
+		p := group[0]
+		j := 1
+		for j < len(group) && met[[2]int{p, group[j]}] {
+			j++
+		}
+		if j == len(group) {
+			left = append(left, p)
+			group = group[1:]
+			continue
+		}
+		pairs = append(pairs, p, group[j])
+		group = without(group[1:], j-1)
+	}
+	return pairs, left
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func without(players []int, i int) []int {
+	rest := make([]int, 0, len(players)-1)
+	rest = append(rest, players[:i]...)
+	return append(rest, players[i+1:]...)
+}
+
+// swissRound builds the matches of a round from players in pairs. The bye,
+// if any, comes last and is decided right away.
+func swissRound(round int, pairs []int, bye int) []entity.Match {
+	var matches []entity.Match
+	for i := 0; i+1 < len(pairs); i += 2 {
+		matches = append(matches, entity.Match{Round: round, Slot: i / 2, Player1: pairs[i], Player2: pairs[i+1]})
+	}
+	if bye != 0 {
+		matches = append(matches, entity.Match{Round: round, Slot: len(matches), Player1: bye, Winner: bye, Bye: true})
+	}
+	return matches
+}
+
+// advanceSwiss records the result r in its match of a Swiss tournament. The
+// result that completes a round pairs the next one, unless it was the last.
+// It returns the matches it has changed or added.
+func advanceSwiss(t entity.Tournament, matches []entity.Match, r entity.Match) ([]entity.Match, error) {
+	i, err := findMatch(matches, r.Round, r.Slot)
+	if err != nil {
+		return nil, err
+	}
+	m, err := matches[i].Record(r)
+	if err != nil {
+		return nil, err
+	}
+	played := make([]entity.Match, len(matches))
+	copy(played, matches)
+	played[i] = m
+	changed := []entity.Match{m}
+	s := entity.NewSwiss(t.ID, t.Users, played)
+	if s.Played() == len(s.Rounds) && len(s.Rounds) < t.SwissRounds(len(t.Users)) {
+		points := map[int]int{}
+		for _, row := range s.Standings {
+			points[row.UserID] = row.Points
+		}
+		changed = append(changed, pairSwiss(len(s.Rounds)+1, s.Ranking(), points, played)...)
+	}
+	return changed, nil
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func match(p1, p2 int) entity.Match {
+	return entity.Match{Player1: p1, Player2: p2, Winner: p1}
+}
+
+func bye(p int) entity.Match {
+	return entity.Match{Player1: p, Winner: p, Bye: true}
+}
+
+func TestPairSwiss(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranked []int
+		points map[int]int
+		played []entity.Match
+		want   [][2]int
+	}{
+		{"first round", []int{1, 2, 3, 4}, nil, nil,
+			[][2]int{{1, 2}, {3, 4}}},
+		{"rematch avoided", []int{1, 2, 3, 4}, nil, []entity.Match{match(1, 2), match(3, 4)},
+			[][2]int{{1, 3}, {2, 4}}},
+		{"rematch unavoidable", []int{1, 2}, map[int]int{1: 3}, []entity.Match{match(1, 2)},
+			[][2]int{{1, 2}}},
+		{"score groups", []int{1, 3, 2, 4}, map[int]int{1: 3, 3: 3}, []entity.Match{match(1, 2), match(3, 4)},
+			[][2]int{{1, 3}, {2, 4}}},
+		{"odd one out floats down", []int{1, 2, 3, 4, 5, 6}, map[int]int{1: 3, 2: 3, 3: 3},
+			[]entity.Match{match(1, 4), match(2, 5), match(3, 6)},
+			[][2]int{{1, 2}, {3, 4}, {5, 6}}},
+		{"player who has met the group floats down", []int{1, 2, 3, 4}, map[int]int{1: 6, 2: 3, 3: 3},
+			[]entity.Match{match(1, 2), match(3, 4), match(1, 3), match(2, 4)},
+			[][2]int{{2, 3}, {1, 4}}},
+		{"rematch with the closest points", []int{1, 2, 3, 4}, map[int]int{1: 9, 2: 6, 3: 3},
+			[]entity.Match{match(1, 2), match(3, 4), match(1, 3), match(2, 4), match(1, 4), match(2, 3)},
+			[][2]int{{1, 2}, {3, 4}}},
+		{"bye to the last", []int{1, 2, 3}, nil, nil,
+			[][2]int{{1, 2}, {3, 0}}},
+		{"one bye each", []int{1, 3, 2}, map[int]int{1: 3, 3: 3}, []entity.Match{match(1, 2), bye(3)},
+			[][2]int{{1, 3}, {2, 0}}},
+		{"bye moves up to avoid a rematch", []int{1, 2, 3}, map[int]int{1: 3}, []entity.Match{match(1, 2)},
+			[][2]int{{1, 3}, {2, 0}}},
+		{"everyone has had a bye", []int{1, 3, 2, 4, 5}, map[int]int{1: 6, 3: 6, 2: 3, 4: 3, 5: 3},
+			[]entity.Match{bye(1), bye(2), bye(3), bye(4), bye(5), match(1, 2), match(3, 4)},
+			[][2]int{{1, 3}, {2, 4}, {5, 0}}},
+		{"everyone has had a bye, some two", []int{1, 2, 3, 4, 5}, nil,
+			[]entity.Match{bye(1), bye(2), bye(3), bye(4), bye(5), bye(5)},
+			[][2]int{{1, 2}, {3, 5}, {4, 0}}},
+		{"everyone has met and had a bye", []int{1, 2, 3}, nil,
+			[]entity.Match{match(1, 2), bye(3), match(1, 3), bye(2), match(2, 3), bye(1)},
+			[][2]int{{1, 2}, {3, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []entity.Match
+			for i, p := range tt.want {
+				m := entity.Match{Round: 4, Slot: i, Player1: p[0], Player2: p[1]}
+				if p[1] == 0 {
+					m.Winner, m.Bye = p[0], true
+				}
+				want = append(want, m)
+			}
+			assert.Equal(t, want, pairSwiss(4, tt.ranked, tt.points, tt.played))
+		})
+	}
+}
+
+func TestPairSwissIsBounded(t *testing.T) {
+	// Everyone has met everyone, which an exhaustive search for a pairing
+	// without rematches would take forever to find out.
+	var ranked []int
+	var played []entity.Match
+	for p := 1; p <= 41; p++ {
+		ranked = append(ranked, p)
+		for q := 1; q < p; q++ {
+			played = append(played, match(q, p))
+		}
+	}
+	matches := pairSwiss(41, ranked, nil, played)
+	require.Len(t, matches, 21)
+	assert.Equal(t, entity.Match{Round: 41, Slot: 0, Player1: 1, Player2: 2}, matches[0], "rematches go by points")
+	assert.Equal(t, entity.Match{Round: 41, Slot: 20, Player1: 41, Winner: 41, Bye: true}, matches[20])
+}
@@ -0,0 +1,118 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// CreateTeam creates a team with its captain as the first member.
+func (c Controller) CreateTeam(t entity.Team) (entity.Team, error) {
+	err := t.IsValid()
+	if err != nil {
+		return t, err
+	}
+	t.Members = []entity.TeamMember{{UserID: t.CaptainID, Share: 1}}
+	t, err = c.db.CreateTeam(t)
+	if err != nil {
+		return t, err
+	}
+	return c.db.GetTeam(t.ID)
+}
+
+func (c Controller) GetTeam(id int) (entity.Team, error) {
+	return c.db.GetTeam(id)
+}
+
+// JoinTeam adds a user to a team with a share of its prizes, 1 if share is 0.
+func (c Controller) JoinTeam(teamID, uID, share int) (entity.Team, error) {
+	if share == 0 {
+		share = 1
+	}
+	if share < 0 {
+		return entity.Team{}, entity.RegErr(errors.New("share must be greater than 0"))
+	}
+	err := c.db.JoinTeam(teamID, entity.TeamMember{UserID: uID, Share: share})
+	if err != nil {
+		return entity.Team{}, err
+	}
+	return c.db.GetTeam(teamID)
+}
+
+// SetTeamShare changes a member's share of the prizes the team wins from now
+// on. Tournaments the team has entered keep the shares it entered with.
+func (c Controller) SetTeamShare(teamID, uID, share int) (entity.Team, error) {
+	if share <= 0 {
+		return entity.Team{}, entity.RegErr(errors.New("share must be greater than 0"))
+	}
+	err := c.db.SetTeamShare(teamID, uID, share)
+	if err != nil {
+		return entity.Team{}, err
+	}
+	return c.db.GetTeam(teamID)
+}
+
+// LeaveTeam removes a member other than the captain from a team.
+func (c Controller) LeaveTeam(teamID, uID int) (entity.Team, error) {
+	err := c.db.LeaveTeam(teamID, uID)
+	if err != nil {
+		return entity.Team{}, err
+	}
+	return c.db.GetTeam(teamID)
+}
+
+// RegTeam registers a team for a team tournament on behalf of its captain.
+// Every member pays the deposit, or the captain pays it for the whole team,
+// and the team takes a single seat. The registration that fills a sit-and-go
+// finishes it. A private tournament needs the captain's own invite or an
+// invite code.
+func (c Controller) RegTeam(tID, teamID, captainID int, code string, captainPays bool) (entity.JoinResult, error) {
+	t, err := c.db.RegTeam(tID, teamID, code, func(t entity.Tournament, team entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error) {
+		if t.TeamSize == 0 {
+			return nil, entity.RegErr(errors.New("the tournament isn't for teams"))
+		}
+		if team.CaptainID != captainID {
+			return nil, entity.ReqErr(errors.New("only the captain registers the team"))
+		}
+		err := t.Admit(inv)
+		if err != nil {
+			return nil, err
+		}
+		if len(team.Members) != t.TeamSize {
+			return nil, entity.RegErr(fmt.Errorf("the tournament is for teams of %d members, the team has %d", t.TeamSize, len(team.Members)))
+		}
+		return teamStakes(t, team, users, captainPays)
+	}, rankBy(nil))
+	if err != nil {
+		return entity.JoinResult{Tournament: t}, err
+	}
+	return c.joined(t.ID, captainID)
+}
+
+// teamStakes returns what every member of team pays to enter t, the deposit
+// each, or the deposits of the whole team from the captain. users are the
+// members in the order of team.Members. Every member must be within the
+// rating band of the tournament.
+func teamStakes(t entity.Tournament, team entity.Team, users []entity.User, captainPays bool) ([]entity.Member, error) {
+	seats := 1
+	if captainPays {
+		seats = len(team.Members)
+	}
+	members := make([]entity.Member, len(team.Members))
+	for i, m := range team.Members {
+		if t.AllowsRating(users[i].Rating) != nil {
+			return nil, entity.RegErr(fmt.Errorf("the rating of %s is out of the tournament's band", users[i].Name))
+		}
+		members[i] = entity.Member{UserID: m.UserID, Name: users[i].Name, Share: m.Share}
+		if captainPays && m.UserID != team.CaptainID {
+			continue
+		}
+		members[i].Stake = t.Deposit * seats
+		members[i].Rake = t.EntryRake() * seats
+		if users[i].Funds() < members[i].Stake {
+			return nil, entity.RegErr(fmt.Errorf("the balance of %s is lower than the stake", users[i].Name))
+		}
+	}
+	return members, nil
+}
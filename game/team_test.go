@@ -0,0 +1,113 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+func TestTeam(t *testing.T) {
+	c := New(memory.New())
+	captain, member := newUser(t, c, 1000), newUser(t, c, 1000)
+	_, err := c.CreateTeam(entity.Team{Name: "red"})
+	assert.Error(t, err, "a team needs a captain")
+	team, err := c.CreateTeam(entity.Team{Name: "red", CaptainID: captain.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []entity.TeamMember{{UserID: captain.ID, Name: "user", Share: 1}}, team.Members)
+
+	_, err = c.JoinTeam(team.ID, member.ID, -1)
+	assert.Error(t, err)
+	team, err = c.JoinTeam(team.ID, member.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, team.Members[1].Share, "the share defaults to 1")
+	_, err = c.SetTeamShare(team.ID, member.ID, 0)
+	assert.Error(t, err)
+	team, err = c.SetTeamShare(team.ID, member.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, team.Members[1].Share)
+	_, err = c.LeaveTeam(team.ID, captain.ID)
+	assert.Error(t, err, "the captain stays")
+
+	solo := newTourn(t, c, entity.Tournament{Deposit: 100})
+	_, err = c.RegTeam(solo.ID, team.ID, captain.ID, "", false)
+	assert.Error(t, err, "the tournament isn't for teams")
+	trio := newTourn(t, c, entity.Tournament{Deposit: 100, TeamSize: 3})
+	_, err = c.RegTeam(trio.ID, team.ID, captain.ID, "", false)
+	assert.Error(t, err, "the team is too small")
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, TeamSize: 2})
+	_, err = c.RegTeam(tr.ID, team.ID, member.ID, "", false)
+	assert.Error(t, err, "only the captain registers the team")
+	band := newTourn(t, c, entity.Tournament{Deposit: 100, TeamSize: 2, MaxRating: 1400})
+	_, err = c.RegTeam(band.ID, team.ID, captain.ID, "", false)
+	assert.Error(t, err, "the members are out of the band")
+	dear := newTourn(t, c, entity.Tournament{Deposit: 400, TeamSize: 2})
+	_, err = c.RegTeam(dear.ID, team.ID, captain.ID, "", true)
+	assert.Error(t, err, "the captain can't pay 800 for the team")
+	assert.Equal(t, 700, balance(t, c, captain.ID))
+
+	r, err := c.RegTeam(tr.ID, team.ID, captain.ID, "", false)
+	require.NoError(t, err)
+	require.Len(t, r.Users, 1, "a team takes a single seat")
+	assert.Equal(t, team.ID, r.Users[0].TeamID)
+	assert.Equal(t, []entity.Member{
+		{UserID: captain.ID, Name: "user", Stake: 100, Share: 1},
+		{UserID: member.ID, Name: "user", Stake: 100, Share: 3},
+	}, r.Users[0].Members)
+	_, err = c.RegTeam(tr.ID, team.ID, captain.ID, "", false)
+	assert.Error(t, err, "the team is already registered")
+	_, err = c.LeaveTourn(tr.ID, member.ID)
+	assert.Error(t, err, "only the captain withdraws the team")
+	_, err = c.LeaveTourn(tr.ID, captain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 700, balance(t, c, captain.ID))
+	assert.Equal(t, 700, balance(t, c, member.ID))
+	requireBalanced(t, c)
+}
+
+func TestTeamPrizeSplit(t *testing.T) {
+	c := New(memory.New())
+	captain := newUser(t, c, 1000)
+	member := newUser(t, c, 1000)
+	rival := newUser(t, c, 1000)
+	rivalMate := newUser(t, c, 1000)
+
+	team, err := c.CreateTeam(entity.Team{Name: "a", CaptainID: captain.ID})
+	require.NoError(t, err)
+	_, err = c.JoinTeam(team.ID, member.ID, 2)
+	require.NoError(t, err)
+	rivals, err := c.CreateTeam(entity.Team{Name: "b", CaptainID: rival.ID})
+	require.NoError(t, err)
+	_, err = c.JoinTeam(rivals.ID, rivalMate.ID, 1)
+	require.NoError(t, err)
+
+	tr := newTourn(t, c, entity.Tournament{Deposit: 100, TeamSize: 2, Selector: entity.SelectExternal})
+	_, err = c.JoinTourn(tr.ID, captain.ID, "")
+	assert.Error(t, err, "a captain registers the team")
+	_, err = c.RegTeam(tr.ID, team.ID, captain.ID, "", false)
+	require.NoError(t, err)
+	_, err = c.RegTeam(tr.ID, rivals.ID, rival.ID, "", true)
+	require.NoError(t, err)
+	assert.Equal(t, 600, balance(t, c, captain.ID))
+	assert.Equal(t, 600, balance(t, c, member.ID))
+	assert.Equal(t, 500, balance(t, c, rival.ID), "the captain pays for the team")
+	assert.Equal(t, 700, balance(t, c, rivalMate.ID))
+
+	_, err = c.SetTeamShare(team.ID, member.ID, 5)
+	require.NoError(t, err)
+	start(t, c, tr.ID)
+	tourn, err := c.FinishTourn(tr.ID, []int{captain.ID, rival.ID})
+	require.NoError(t, err)
+	// 400 points split 1:2 by the shares at registration, the remainder to
+	// the captain.
+	assert.Equal(t, 600+134, balance(t, c, captain.ID))
+	assert.Equal(t, 600+266, balance(t, c, member.ID))
+	assert.Equal(t, 266, tourn.Users[0].Members[1].Payout)
+	r, err := c.GetRating(captain.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.InitialRating, r.Rating, "team tournaments aren't rated")
+	requireBalanced(t, c)
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// transfer moves amount from one account to another. Postings on user cash
+// and bonus accounts also change users' balances and are written to their
+// transaction history. Callers check that no user balance goes negative
+// beforehand.
+func (db *DB) transfer(from, to entity.Account, amount int, typ entity.TransactionType, tID int) {
+	if amount == 0 {
+		return
+	}
+	db.accounts[from] -= amount
+	db.accounts[to] += amount
+	if uID, ok := from.UserID(); ok {
+		db.changeBalance(uID, -amount, from.IsBonus(), typ, tID)
+	}
+	if uID, ok := to.UserID(); ok {
+		db.changeBalance(uID, amount, to.IsBonus(), typ, tID)
+	}
+}
+
+func (db *DB) TrialBalance() (entity.TrialBalance, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tb := entity.TrialBalance{Accounts: []entity.AccountBalance{}}
+	for a, balance := range db.accounts {
+		tb.Accounts = append(tb.Accounts, entity.AccountBalance{Account: a, Balance: balance})
+		tb.Total += balance
+	}
+	sort.Slice(tb.Accounts, func(i, j int) bool {
+		return tb.Accounts[i].Account < tb.Accounts[j].Account
+	})
+	tb.Balanced = tb.Total == 0
+	return tb, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// requireBalanced checks that the books net to zero and that every user's
+// cash and bonus points match their ledger accounts.
+func requireBalanced(t *testing.T, db *DB) {
+	t.Helper()
+	tb, err := db.TrialBalance()
+	require.NoError(t, err)
+	require.True(t, tb.Balanced, "total %d", tb.Total)
+	accounts := map[entity.Account]int{}
+	for _, a := range tb.Accounts {
+		accounts[a.Account] = a.Balance
+	}
+	for _, u := range db.users {
+		require.Equal(t, accounts[entity.UserAccount(u.ID)], u.Balance, "balance of user %d", u.ID)
+		require.Equal(t, accounts[entity.BonusAccount(u.ID)], u.Bonus, "bonus of user %d", u.ID)
+	}
+}
+
+func TestTrialBalance(t *testing.T) {
+	db := New()
+	tb, err := db.TrialBalance()
+	require.NoError(t, err)
+	assert.Equal(t, entity.TrialBalance{Accounts: []entity.AccountBalance{}, Balanced: true}, tb)
+
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u.ID)
+	_, err = db.TakePoints(u.ID, 50)
+	require.NoError(t, err)
+
+	tb, err = db.TrialBalance()
+	require.NoError(t, err)
+	assert.Equal(t, []entity.AccountBalance{
+		{Account: entity.EscrowAccount(tr.ID), Balance: 100},
+		{Account: entity.HouseAccount, Balance: -950},
+		{Account: entity.FeesAccount, Balance: 300},
+		{Account: entity.UserAccount(u.ID), Balance: 550},
+	}, tb.Accounts)
+	assert.True(t, tb.Balanced)
+
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u.ID)))
+	requireBalanced(t, db)
+	require.NoError(t, db.DelTourn(tr.ID))
+	require.NoError(t, db.DelUser(u.ID))
+	requireBalanced(t, db)
+	tb, err = db.TrialBalance()
+	require.NoError(t, err)
+	assert.Contains(t, tb.Accounts, entity.AccountBalance{Account: entity.HouseAccount, Balance: -300}, "the closed account goes back to the house")
+}
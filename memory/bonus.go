@@ -0,0 +1,55 @@
+package memory
+
+import "github.com/yanrishbe/gaming-website/entity"
+
+// grantBonus pays the signup bonus of p to a new user, in bonus points with
+// the wagering requirement of p, or else in cash.
+func (db *DB) grantBonus(uID int, p entity.Policy) {
+	if p.Wagering == 0 {
+		db.transfer(entity.HouseAccount, entity.UserAccount(uID), p.Bonus, entity.TxBonus, 0)
+		return
+	}
+	db.transfer(entity.HouseAccount, entity.BonusAccount(uID), p.Bonus, entity.TxBonus, 0)
+	u := db.users[uID]
+	u.Wagering = p.Bonus * p.Wagering
+	db.users[uID] = u
+}
+
+// deposit charges a user amount for a tournament, in cash and bonus points
+// by the spend order of the policy in force, and returns the part paid in
+// bonus points. Callers check that the user has the funds.
+func (db *DB) deposit(tID, uID, amount int) int {
+	cash, bonus := db.currentPolicy().Charge(db.users[uID], amount)
+	db.transfer(entity.UserAccount(uID), entity.EscrowAccount(tID), cash, entity.TxDeposit, tID)
+	db.transfer(entity.BonusAccount(uID), entity.EscrowAccount(tID), bonus, entity.TxDeposit, tID)
+	return bonus
+}
+
+// wager counts a deposit of amount toward the user's wagering requirement.
+// The bonus points convert to cash once it is met.
+func (db *DB) wager(tID, uID, amount int) {
+	u := db.users[uID]
+	if u.Wagering == 0 {
+		return
+	}
+	u.Wagering -= amount
+	if u.Wagering < 0 {
+		u.Wagering = 0
+	}
+	db.users[uID] = u
+	if u.Wagering == 0 {
+		db.transfer(entity.BonusAccount(uID), entity.UserAccount(uID), u.Bonus, entity.TxConvert, tID)
+	}
+}
+
+// payPrize pays a payer of an entry their part of a prize in cash. While
+// their wagering requirement is unmet, the part won with bonus points is paid
+// in bonus points.
+func (db *DB) payPrize(tID int, m entity.Member, amount int) {
+	bonus := 0
+	if db.users[m.UserID].Wagering > 0 {
+		bonus = m.BonusPart(amount)
+	}
+	db.transfer(entity.EscrowAccount(tID), entity.UserAccount(m.UserID), amount-bonus, entity.TxPrize, tID)
+	db.transfer(entity.EscrowAccount(tID), entity.BonusAccount(m.UserID), bonus, entity.TxPrize, tID)
+}
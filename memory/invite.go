@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// invite returns a copy of the invite a join with code uses and its index,
+// or nil and -1 if there is none. Without a code it is the user's own
+// invite. A revoked invite is only returned if there is no other.
+func (t *tournament) invite(uID int, code string) (*entity.Invite, int) {
+	found := -1
+	for i, inv := range t.invites {
+		if code != "" && inv.Code != code || code == "" && inv.UserID != uID {
+			continue
+		}
+		if found == -1 || t.invites[found].Revoked {
+			found = i
+		}
+	}
+	if found == -1 {
+		return nil, -1
+	}
+	inv := t.invites[found]
+	return &inv, found
+}
+
+func (t *tournament) useInvite(i int) {
+	if i != -1 {
+		t.invites[i].Uses++
+	}
+}
+
+// removeInvites removes the user's own invites.
+func (t *tournament) removeInvites(uID int) {
+	invites := t.invites[:0]
+	for _, inv := range t.invites {
+		if inv.UserID != uID {
+			invites = append(invites, inv)
+		}
+	}
+	t.invites = invites
+}
+
+func (db *DB) CreateInvite(inv entity.Invite) (entity.Invite, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tr, err := db.tourn(inv.TournamentID)
+	if err != nil {
+		return inv, err
+	}
+	for _, other := range tr.invites {
+		if other.Revoked {
+			continue
+		}
+		if inv.Code != "" && other.Code == inv.Code {
+			return inv, entity.RegErr(errors.New("the invite code is taken"))
+		}
+		if inv.UserID != 0 && other.UserID == inv.UserID {
+			return inv, entity.RegErr(errors.New("user is already invited"))
+		}
+	}
+	db.inviteID++
+	inv.ID = db.inviteID
+	inv.CreatedAt = time.Now().UTC()
+	tr.invites = append(tr.invites, inv)
+	return inv, nil
+}
+
+func (db *DB) ListInvites(tID int) ([]entity.Invite, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]entity.Invite{}, tr.invites...), nil
+}
+
+func (db *DB) RevokeInvite(tID, id int) (entity.Invite, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return entity.Invite{}, err
+	}
+	for i := range tr.invites {
+		if tr.invites[i].ID == id {
+			tr.invites[i].Revoked = true
+			return tr.invites[i], nil
+		}
+	}
+	return entity.Invite{}, entity.ReqErr(errors.New("invite doesn't exist"))
+}
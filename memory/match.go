@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (t *tournament) match(round, slot int) *entity.Match {
+	for i, m := range t.matches {
+		if m.Round == round && m.Slot == slot {
+			return &t.matches[i]
+		}
+	}
+	return nil
+}
+
+func (db *DB) GetMatches(tID int) ([]entity.Match, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]entity.Match, len(tr.matches))
+	copy(matches, tr.matches)
+	return matches, nil
+}
+
+// ReportMatch records a result in the matches of a running tournament. The
+// matches report has changed replace the stored ones, new ones are added.
+func (db *DB) ReportMatch(tID int, report func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error)) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Require(entity.Running)
+	if err != nil {
+		return err
+	}
+	matches := make([]entity.Match, len(tr.matches))
+	copy(matches, tr.matches)
+	changed, ratings, err := report(db.entries(tr), matches)
+	if err != nil {
+		return err
+	}
+	err = checkRatings(tr, ratings)
+	if err != nil {
+		return err
+	}
+	db.changeRatings(ratings)
+	for _, m := range changed {
+		if stored := tr.match(m.Round, m.Slot); stored != nil {
+			*stored = m
+		} else {
+			tr.matches = append(tr.matches, m)
+		}
+	}
+	sort.Slice(tr.matches, func(i, j int) bool {
+		a, b := tr.matches[i], tr.matches[j]
+		return a.Round < b.Round || a.Round == b.Round && a.Slot < b.Slot
+	})
+	return nil
+}
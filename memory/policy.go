@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db *DB) policy(id int) (entity.Policy, error) {
+	if id <= 0 || id > len(db.policies) {
+		return entity.Policy{}, entity.ReqErr(errors.New("policy doesn't exist"))
+	}
+	return db.policies[id-1], nil
+}
+
+func (db *DB) currentPolicy() entity.Policy {
+	return db.policies[len(db.policies)-1]
+}
+
+func (db *DB) setPolicy(p entity.Policy) entity.Policy {
+	p.ID = len(db.policies) + 1
+	p.CreatedAt = time.Now().UTC()
+	db.policies = append(db.policies, p)
+	return p
+}
+
+func (db *DB) CurrentPolicy() (entity.Policy, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.currentPolicy(), nil
+}
+
+func (db *DB) GetPolicy(id int) (entity.Policy, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.policy(id)
+}
+
+func (db *DB) SetPolicy(p entity.Policy) (entity.Policy, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.setPolicy(p), nil
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestPolicy(t *testing.T) {
+	db := New()
+	p, err := db.CurrentPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.ID)
+	assert.True(t, p.Same(entity.DefaultPolicy), "the default policy is in force")
+
+	set, err := db.SetPolicy(entity.Policy{Fee: 100, Bonus: 50, MinBalance: 200})
+	require.NoError(t, err)
+	assert.NotEqual(t, p.ID, set.ID)
+	assert.False(t, set.CreatedAt.IsZero())
+	current, err := db.CurrentPolicy()
+	require.NoError(t, err)
+	assert.Equal(t, set.ID, current.ID)
+	assert.True(t, current.Same(set))
+	old, err := db.GetPolicy(p.ID)
+	require.NoError(t, err)
+	assert.True(t, old.Same(entity.DefaultPolicy), "old versions stay on record")
+	_, err = db.GetPolicy(set.ID + 1)
+	assert.Error(t, err)
+
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: 200, PolicyID: set.ID}, set)
+	require.NoError(t, err)
+	assert.Equal(t, 150, u.Balance)
+	assert.Equal(t, set.ID, u.PolicyID)
+	var types []entity.TransactionType
+	for _, tx := range transactions(t, db, entity.TransactionFilter{UserID: u.ID}) {
+		types = append(types, tx.Type)
+	}
+	assert.Equal(t, []entity.TransactionType{entity.TxRegFee, entity.TxBonus, entity.TxRegistration}, types)
+
+	_, err = db.CreateUser(entity.User{Name: "user", Balance: 200, PolicyID: set.ID + 1}, set)
+	assert.Error(t, err, "the policy doesn't exist")
+	_, err = db.CreateUser(entity.User{Name: "user", Balance: 40, PolicyID: set.ID}, set)
+	assert.Error(t, err, "the balance can't cover the fee")
+}
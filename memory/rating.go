@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// checkRatings returns an error if a change is for a user who isn't a
+// participant of the tournament.
+func checkRatings(tr *tournament, changes []entity.RatingChange) error {
+	for _, c := range changes {
+		if !tr.hasUser(c.UserID) {
+			return entity.DBErr(fmt.Errorf("user %d rated in tournament %d isn't a participant", c.UserID, tr.ID))
+		}
+	}
+	return nil
+}
+
+// changeRatings applies the changes to the users' ratings and records them.
+func (db *DB) changeRatings(changes []entity.RatingChange) {
+	for _, c := range changes {
+		u := db.users[c.UserID]
+		u.Rating += c.Change
+		db.users[c.UserID] = u
+		c.Rating = u.Rating
+		c.CreatedAt = time.Now().UTC()
+		db.ratings = append(db.ratings, c)
+	}
+}
+
+func (db *DB) RatingChanges(uID int) ([]entity.RatingChange, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	changes := []entity.RatingChange{}
+	for _, c := range db.ratings {
+		if c.UserID == uID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
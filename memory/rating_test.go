@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func rating(t *testing.T, db *DB, uID int) int {
+	t.Helper()
+	u, err := db.GetUser(uID)
+	require.NoError(t, err)
+	return u.Rating
+}
+
+func TestRatingChanges(t *testing.T) {
+	db := New()
+	var users []entity.User
+	for _, r := range []int{1500, 1600, 1500} {
+		u, err := db.CreateUser(entity.User{Name: "user", Balance: 700, Rating: r, PolicyID: 1}, free)
+		require.NoError(t, err)
+		users = append(users, u)
+	}
+	u1, u2, outsider := users[0], users[1], users[2]
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Format: entity.FormatRoundRobin, Selector: entity.SelectExternal})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	require.NoError(t, db.StartTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Match, error) {
+		return []entity.Match{{Round: 1, Slot: 0, Player1: u1.ID, Player2: u2.ID}}, nil
+	}))
+
+	played := entity.Match{Round: 1, Slot: 0, Player1: u1.ID, Player2: u2.ID, Winner: u1.ID}
+	assert.Error(t, db.ReportMatch(tr.ID, func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		return []entity.Match{played}, []entity.RatingChange{{UserID: outsider.ID, TournamentID: tr.ID, Change: 5}}, nil
+	}), "only participants are rated")
+	assert.Equal(t, 1500, rating(t, db, outsider.ID))
+	matches, err := db.GetMatches(tr.ID)
+	require.NoError(t, err)
+	assert.False(t, matches[0].Played(), "the result isn't recorded either")
+
+	require.NoError(t, db.ReportMatch(tr.ID, func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		assert.Equal(t, []int{1500, 1600}, []int{entries[0].Rating, entries[1].Rating}, "entries carry the ratings")
+		return []entity.Match{played}, []entity.RatingChange{
+			{UserID: u1.ID, TournamentID: tr.ID, Change: 20},
+			{UserID: u2.ID, TournamentID: tr.ID, Change: -20},
+		}, nil
+	}))
+	assert.Equal(t, 1520, rating(t, db, u1.ID))
+	assert.Equal(t, 1580, rating(t, db, u2.ID))
+
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		assert.Equal(t, 1520, entries[0].Rating)
+		return []entity.Placing{{UserID: u1.ID, Place: 1, Amount: tourn.Prize}},
+			[]entity.RatingChange{{UserID: u2.ID, TournamentID: tr.ID, Change: 3}}, nil
+	}))
+	assert.Equal(t, 1583, rating(t, db, u2.ID))
+
+	changes, err := db.RatingChanges(u2.ID)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	for i, want := range []entity.RatingChange{
+		{UserID: u2.ID, TournamentID: tr.ID, Change: -20, Rating: 1580},
+		{UserID: u2.ID, TournamentID: tr.ID, Change: 3, Rating: 1583},
+	} {
+		assert.False(t, changes[i].CreatedAt.IsZero())
+		changes[i].CreatedAt = want.CreatedAt
+		assert.Equal(t, want, changes[i])
+	}
+	changes, err = db.RatingChanges(outsider.ID)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
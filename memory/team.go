@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db *DB) team(id int) (*entity.Team, error) {
+	t, ok := db.teams[id]
+	if !ok {
+		return nil, entity.ReqErr(errors.New("team doesn't exist"))
+	}
+	return t, nil
+}
+
+func member(t *entity.Team, uID int) (int, bool) {
+	for i, m := range t.Members {
+		if m.UserID == uID {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func removeMember(t *entity.Team, uID int) {
+	if i, ok := member(t, uID); ok {
+		t.Members = append(t.Members[:i], t.Members[i+1:]...)
+	}
+}
+
+func (db *DB) CreateTeam(t entity.Team) (entity.Team, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.users[t.CaptainID]; !ok {
+		return t, entity.ReqErr(errors.New("the captain doesn't exist"))
+	}
+	db.teamID++
+	t.ID = db.teamID
+	t.Members = append([]entity.TeamMember(nil), t.Members...)
+	db.teams[t.ID] = &t
+	return t, nil
+}
+
+func (db *DB) GetTeam(id int) (entity.Team, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if id <= 0 {
+		return entity.Team{}, entity.InvIDErr(errors.New("expected id greater than 0"))
+	}
+	tm, err := db.team(id)
+	if err != nil {
+		return entity.Team{}, err
+	}
+	t := *tm
+	t.Members = make([]entity.TeamMember, len(tm.Members))
+	for i, m := range tm.Members {
+		m.Name = db.users[m.UserID].Name
+		t.Members[i] = m
+	}
+	return t, nil
+}
+
+func (db *DB) JoinTeam(teamID int, m entity.TeamMember) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.team(teamID)
+	if err != nil {
+		return err
+	}
+	if _, ok := db.users[m.UserID]; !ok {
+		return entity.ReqErr(errors.New("the user doesn't exist"))
+	}
+	if _, ok := member(t, m.UserID); ok {
+		return entity.RegErr(fmt.Errorf("user is already a member of the team"))
+	}
+	t.Members = append(t.Members, m)
+	return nil
+}
+
+func (db *DB) SetTeamShare(teamID, uID, share int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.team(teamID)
+	if err != nil {
+		return err
+	}
+	i, ok := member(t, uID)
+	if !ok {
+		return entity.ReqErr(errors.New("user is not a member of the team"))
+	}
+	t.Members[i].Share = share
+	return nil
+}
+
+func (db *DB) LeaveTeam(teamID, uID int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	t, err := db.team(teamID)
+	if err != nil {
+		return err
+	}
+	if t.CaptainID == uID {
+		return entity.ReqErr(errors.New("the captain can't leave the team"))
+	}
+	if _, ok := member(t, uID); !ok {
+		return entity.ReqErr(errors.New("user is not a member of the team"))
+	}
+	removeMember(t, uID)
+	return nil
+}
+
+// RegTeam registers a team for an open tournament. The members pay what pay
+// returns, all of them or none, and the team takes a single seat. A full
+// tournament has no waitlist for teams.
+func (db *DB) RegTeam(tID, teamID int, code string, pay func(t entity.Tournament, team entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error), rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return entity.Tournament{ID: tID}, err
+	}
+	t := tr.Tournament
+	err = t.Status.Require(entity.Open)
+	if err != nil {
+		return t, err
+	}
+	tm, err := db.team(teamID)
+	if err != nil {
+		return t, err
+	}
+	team := *tm
+	team.Members = make([]entity.TeamMember, len(tm.Members))
+	users := make([]entity.User, len(tm.Members))
+	for i, m := range tm.Members {
+		if tr.hasUser(m.UserID) {
+			return t, entity.RegErr(fmt.Errorf("user %d is already registered", m.UserID))
+		}
+		users[i] = db.users[m.UserID]
+		m.Name = users[i].Name
+		team.Members[i] = m
+	}
+
+	inv, i := tr.invite(team.CaptainID, code)
+	members, err := pay(t, team, users, inv)
+	if err != nil {
+		return t, err
+	}
+	if t.IsFull(len(tr.entries)) {
+		return t, entity.RegErr(errors.New("the tournament is full"))
+	}
+	e := entity.Entry{UserID: team.CaptainID, TeamID: team.ID}
+	for _, m := range members {
+		if db.users[m.UserID].Funds()-m.Stake < 0 {
+			return t, entity.DBErr(errors.New("can't update user's balance: balance must not be negative"))
+		}
+		e.Stake += m.Stake
+		e.Rake += m.Rake
+	}
+	var placings []entity.Placing
+	var ratings []entity.RatingChange
+	if tr.GoesWhenFull(len(tr.entries) + 1) {
+		placings, ratings, err = db.settle(tr.with(e), rank)
+		if err != nil {
+			return t, err
+		}
+	}
+
+	tr.useInvite(i)
+	for _, m := range members {
+		m.BonusStake = db.deposit(tID, m.UserID, m.Stake)
+		e.BonusStake += m.BonusStake
+		db.transfer(entity.EscrowAccount(tID), entity.RakeAccount, m.Rake, entity.TxRake, tID)
+		m.Name = ""
+		e.Members = append(e.Members, m)
+	}
+	tr.entries = append(tr.entries, e)
+	tr.Prize += e.Stake - e.Rake
+	tr.Rake += e.Rake
+	if placings != nil {
+		db.finish(tr, placings, ratings)
+	}
+	return tr.Tournament, nil
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func newTeam(t *testing.T, db *DB, captainID int, members ...int) entity.Team {
+	t.Helper()
+	team, err := db.CreateTeam(entity.Team{Name: "team", CaptainID: captainID, Members: []entity.TeamMember{{UserID: captainID, Share: 1}}})
+	require.NoError(t, err)
+	for _, uID := range members {
+		require.NoError(t, db.JoinTeam(team.ID, entity.TeamMember{UserID: uID, Share: 1}))
+	}
+	return team
+}
+
+// everyone makes every member of a team pay the deposit.
+func everyone(t entity.Tournament, team entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error) {
+	members := make([]entity.Member, len(team.Members))
+	for i, m := range team.Members {
+		members[i] = entity.Member{UserID: m.UserID, Stake: t.Deposit, Rake: t.EntryRake(), Share: m.Share}
+	}
+	return members, nil
+}
+
+func TestTeam(t *testing.T) {
+	db := New()
+	captain, member := newUser(t, db, 700), newUser(t, db, 700)
+	_, err := db.CreateTeam(entity.Team{Name: "team", CaptainID: 42})
+	assert.Error(t, err, "the captain doesn't exist")
+	team := newTeam(t, db, captain.ID)
+
+	assert.Error(t, db.JoinTeam(team.ID, entity.TeamMember{UserID: captain.ID, Share: 1}), "the captain is a member")
+	assert.Error(t, db.JoinTeam(team.ID, entity.TeamMember{UserID: 42, Share: 1}))
+	assert.Error(t, db.JoinTeam(42, entity.TeamMember{UserID: member.ID, Share: 1}))
+	require.NoError(t, db.JoinTeam(team.ID, entity.TeamMember{UserID: member.ID, Share: 1}))
+	require.NoError(t, db.SetTeamShare(team.ID, member.ID, 3))
+	assert.Error(t, db.SetTeamShare(team.ID, 42, 3))
+	got, err := db.GetTeam(team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Team{ID: team.ID, Name: "team", CaptainID: captain.ID, Members: []entity.TeamMember{
+		{UserID: captain.ID, Name: "user", Share: 1},
+		{UserID: member.ID, Name: "user", Share: 3},
+	}}, got)
+
+	assert.Error(t, db.LeaveTeam(team.ID, captain.ID), "the captain can't leave")
+	require.NoError(t, db.LeaveTeam(team.ID, member.ID))
+	assert.Error(t, db.LeaveTeam(team.ID, member.ID), "not a member any more")
+	got, err = db.GetTeam(team.ID)
+	require.NoError(t, err)
+	assert.Len(t, got.Members, 1)
+	_, err = db.GetTeam(0)
+	assert.Error(t, err)
+}
+
+func TestRegTeam(t *testing.T) {
+	db := New()
+	captain, member, rival := newUser(t, db, 700), newUser(t, db, 700), newUser(t, db, 700)
+	team := newTeam(t, db, captain.ID, member.ID)
+	require.NoError(t, db.SetTeamShare(team.ID, member.ID, 2))
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, RakePercent: 10, TeamSize: 2, MaxPlayers: 1, Selector: entity.SelectExternal})
+
+	_, err := db.RegTeam(tr.ID, team.ID, "", func(tourn entity.Tournament, tm entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error) {
+		assert.Equal(t, 2, tourn.TeamSize)
+		assert.Equal(t, []int{captain.ID, member.ID}, []int{users[0].ID, users[1].ID})
+		assert.Equal(t, 700, users[1].Balance)
+		return nil, entity.RegErr(errors.New("no"))
+	}, first)
+	assert.Error(t, err)
+	assert.Equal(t, 700, balance(t, db, captain.ID))
+	_, err = db.RegTeam(tr.ID, team.ID, "", func(tourn entity.Tournament, tm entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error) {
+		return []entity.Member{{UserID: captain.ID, Stake: 800, Share: 1}, {UserID: member.ID, Share: 2}}, nil
+	}, first)
+	assert.Error(t, err, "the balance can't go negative")
+	assert.Equal(t, 700, balance(t, db, captain.ID))
+
+	tourn, err := db.RegTeam(tr.ID, team.ID, "", everyone, first)
+	require.NoError(t, err)
+	assert.Equal(t, 180, tourn.Prize)
+	assert.Equal(t, 20, tourn.Rake)
+	assert.Equal(t, 600, balance(t, db, captain.ID))
+	assert.Equal(t, 600, balance(t, db, member.ID))
+	_, err = db.RegTeam(tr.ID, team.ID, "", everyone, first)
+	assert.Error(t, err, "the members are already registered")
+	_, err = db.JoinTourn(tr.ID, member.ID, "", admit, first)
+	assert.Error(t, err, "a member is already registered")
+	_, err = db.RegTeam(tr.ID, newTeam(t, db, rival.ID).ID, "", everyone, first)
+	assert.Error(t, err, "a full tournament has no waitlist for teams")
+	_, err = db.LeaveTourn(tr.ID, member.ID)
+	assert.Error(t, err, "only the captain withdraws the team")
+
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	require.Len(t, tourn.Users, 1)
+	assert.Equal(t, captain.ID, tourn.Users[0].ID)
+	assert.Equal(t, team.ID, tourn.Users[0].TeamID)
+	assert.Equal(t, []entity.Member{
+		{UserID: captain.ID, Name: "user", Stake: 100, Rake: 10, Share: 1},
+		{UserID: member.ID, Name: "user", Stake: 100, Rake: 10, Share: 2},
+	}, tourn.Users[0].Members)
+
+	err = db.DelUser(captain.ID)
+	assert.Error(t, err, "the captain is registered")
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(captain.ID)))
+	assert.Equal(t, 600+60, balance(t, db, captain.ID))
+	assert.Equal(t, 600+120, balance(t, db, member.ID))
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{60, 120}, []int{tourn.Users[0].Members[0].Payout, tourn.Users[0].Members[1].Payout})
+	requireBalanced(t, db)
+}
+
+func TestRegTeamSitAndGo(t *testing.T) {
+	db := New()
+	a1, a2, b1, b2 := newUser(t, db, 700), newUser(t, db, 700), newUser(t, db, 700), newUser(t, db, 700)
+	teamA, teamB := newTeam(t, db, a1.ID, a2.ID), newTeam(t, db, b1.ID, b2.ID)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Format: entity.FormatSitAndGo, MaxPlayers: 2, TeamSize: 2})
+	tourn, err := db.RegTeam(tr.ID, teamA.ID, "", everyone, first)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Open, tourn.Status)
+
+	tourn, err = db.RegTeam(tr.ID, teamB.ID, "", everyone, first)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status, "the registration that fills it finishes it")
+	assert.Equal(t, 800, balance(t, db, a1.ID))
+	assert.Equal(t, 800, balance(t, db, a2.ID))
+	assert.Equal(t, 600, balance(t, db, b1.ID))
+	requireBalanced(t, db)
+}
+
+func TestLeaveTournTeam(t *testing.T) {
+	db := New()
+	captain, member := newUser(t, db, 700), newUser(t, db, 700)
+	team := newTeam(t, db, captain.ID, member.ID)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, RakeFee: 10, TeamSize: 2})
+	_, err := db.RegTeam(tr.ID, team.ID, "", func(tourn entity.Tournament, tm entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error) {
+		return []entity.Member{{UserID: captain.ID, Stake: 200, Rake: 20, Share: 1}, {UserID: member.ID, Share: 1}}, nil
+	}, first)
+	require.NoError(t, err)
+	assert.Equal(t, 500, balance(t, db, captain.ID), "the captain pays for the team")
+	assert.Equal(t, 700, balance(t, db, member.ID))
+
+	tourn, err := db.LeaveTourn(tr.ID, captain.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tourn.Users)
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Equal(t, 700, balance(t, db, captain.ID))
+	requireBalanced(t, db)
+}
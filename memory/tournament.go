@@ -0,0 +1,485 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// tournament keeps the tournament without Users, its entries in the order
+// of registration, the ids of waitlisted users, its matches ordered by round
+// and slot, its invites and, once it is finished, the placings.
+type tournament struct {
+	entity.Tournament
+	entries  []entity.Entry
+	waitlist []int
+	matches  []entity.Match
+	invites  []entity.Invite
+	placings []entity.Placing
+}
+
+func (t *tournament) waiting(uID int) bool {
+	for _, id := range t.waitlist {
+		if id == uID {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tournament) removeWaiting(uID int) {
+	for i, id := range t.waitlist {
+		if id == uID {
+			t.waitlist = append(t.waitlist[:i], t.waitlist[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *tournament) placing(uID int) entity.Placing {
+	for _, p := range t.placings {
+		if p.UserID == uID {
+			return p
+		}
+	}
+	return entity.Placing{}
+}
+
+func (t *tournament) entry(uID int) (entity.Entry, bool) {
+	for _, e := range t.entries {
+		if e.UserID == uID {
+			return e, true
+		}
+	}
+	return entity.Entry{}, false
+}
+
+func (t *tournament) removeEntry(uID int) {
+	for i, e := range t.entries {
+		if e.UserID == uID {
+			t.entries = append(t.entries[:i], t.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+// hasUser reports whether the user takes part, alone or in a team.
+func (t *tournament) hasUser(uID int) bool {
+	for _, e := range t.entries {
+		for _, m := range e.Payers() {
+			if m.UserID == uID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// entries returns a copy of the entries of the tournament with the current
+// ratings of the users.
+func (db *DB) entries(tr *tournament) []entity.Entry {
+	entries := make([]entity.Entry, len(tr.entries))
+	for i, e := range tr.entries {
+		e.Rating = db.users[e.UserID].Rating
+		entries[i] = e
+	}
+	return entries
+}
+
+func (db *DB) tourn(id int) (*tournament, error) {
+	tr, ok := db.tourns[id]
+	if !ok {
+		return nil, entity.ReqErr(errors.New("tournament doesn't exist"))
+	}
+	return tr, nil
+}
+
+func (db *DB) CreateTourn(t entity.Tournament) (entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if t.Deposit < 0 {
+		return t, entity.DBErr(errors.New("can't create tournament: deposit must not be negative"))
+	}
+	db.tournID++
+	// Like the columns postgres.DB inserts, only the settings are stored.
+	tr := &tournament{Tournament: entity.Tournament{
+		ID:          db.tournID,
+		Name:        t.Name,
+		Deposit:     t.Deposit,
+		Status:      t.Status,
+		Selector:    t.Selector,
+		Format:      t.Format,
+		Payouts:     t.Payouts,
+		Rounds:      t.Rounds,
+		TieBreak:    t.TieBreak,
+		RakePercent: t.RakePercent,
+		RakeFee:     t.RakeFee,
+		OpensAt:     t.OpensAt,
+		StartsAt:    t.StartsAt,
+		EndsAt:      t.EndsAt,
+		MinPlayers:  t.MinPlayers,
+		MaxPlayers:  t.MaxPlayers,
+		MinRating:   t.MinRating,
+		MaxRating:   t.MaxRating,
+		TeamSize:    t.TeamSize,
+		Private:     t.Private,
+		SeedHash:    t.SeedHash,
+		Seed:        t.Seed,
+	}}
+	db.tourns[tr.ID] = tr
+	return tr.Tournament, nil
+}
+
+func (db *DB) GetTourn(id int) (entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if id <= 0 {
+		return entity.Tournament{}, entity.InvIDErr(errors.New("expected id greater than 0"))
+	}
+	tr, err := db.tourn(id)
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	t := tr.Tournament
+	if t.Status != entity.Finished {
+		t.Seed = ""
+	}
+	t.Users = []entity.Winner{}
+	for _, e := range tr.entries {
+		p := tr.placing(e.UserID)
+		w := entity.Winner{
+			ID:       e.UserID,
+			Name:     db.users[e.UserID].Name,
+			Stake:    e.Stake,
+			Winner:   e.UserID == t.Winner,
+			Place:    p.Place,
+			Payout:   p.Amount,
+			Score:    e.Score,
+			ScoredAt: e.ScoredAt,
+			TeamID:   e.TeamID,
+		}
+		if e.TeamID != 0 {
+			amounts := e.Split(p.Amount)
+			for i, m := range e.Members {
+				m.Name = db.users[m.UserID].Name
+				m.Payout = amounts[i]
+				w.Members = append(w.Members, m)
+			}
+		}
+		t.Users = append(t.Users, w)
+	}
+	for _, uID := range tr.waitlist {
+		t.Waitlist = append(t.Waitlist, entity.UserTourn{ID: uID, Name: db.users[uID].Name})
+	}
+	return t, nil
+}
+
+// SetTournStatus moves a tournament to a status that doesn't involve any
+// points, that is opens its registration or starts it.
+func (db *DB) SetTournStatus(tID int, to entity.Status) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Transition(to)
+	if err != nil {
+		return err
+	}
+	tr.Status = to
+	return nil
+}
+
+func (db *DB) StartTourn(tID int, prepare func(t entity.Tournament, entries []entity.Entry) ([]entity.Match, error)) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Transition(entity.Running)
+	if err != nil {
+		return err
+	}
+	matches, err := prepare(tr.Tournament, db.entries(tr))
+	if err != nil {
+		return err
+	}
+	tr.matches = matches
+	tr.Status = entity.Running
+	return nil
+}
+
+func (db *DB) JoinTourn(tID, uID int, code string, check func(u entity.User, t entity.Tournament, inv *entity.Invite) error, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return entity.Tournament{ID: tID}, err
+	}
+	t := tr.Tournament
+	err = t.Status.Require(entity.Open)
+	if err != nil {
+		return t, err
+	}
+	u, ok := db.users[uID]
+	if !ok {
+		return t, entity.ReqErr(errors.New("the user doesn't exist"))
+	}
+	if tr.hasUser(uID) {
+		return t, entity.RegErr(fmt.Errorf("user is already registered"))
+	}
+	if tr.waiting(uID) {
+		return t, entity.RegErr(fmt.Errorf("user is already on the waitlist"))
+	}
+
+	inv, i := tr.invite(uID, code)
+	err = check(u, t, inv)
+	if err != nil {
+		return t, err
+	}
+	if t.IsFull(len(tr.entries)) {
+		tr.useInvite(i)
+		tr.waitlist = append(tr.waitlist, uID)
+		return tr.Tournament, nil
+	}
+	if u.Funds()-t.Deposit < 0 {
+		return t, entity.DBErr(errors.New("can't update user's balance: balance must not be negative"))
+	}
+
+	var placings []entity.Placing
+	var ratings []entity.RatingChange
+	if tr.GoesWhenFull(len(tr.entries) + 1) {
+		placings, ratings, err = db.settle(tr.with(entity.Entry{UserID: uID, Stake: t.Deposit, Rake: t.EntryRake()}), rank)
+		if err != nil {
+			return t, err
+		}
+	}
+	tr.useInvite(i)
+	db.enter(tr, uID)
+	if placings != nil {
+		db.finish(tr, placings, ratings)
+	}
+	return tr.Tournament, nil
+}
+
+// with returns a copy of tr with the entry e registered, to settle a
+// sit-and-go that e fills before e is charged.
+func (t *tournament) with(e entity.Entry) *tournament {
+	next := *t
+	next.entries = append(append([]entity.Entry(nil), t.entries...), e)
+	next.Prize += e.Stake - e.Rake
+	next.Rake += e.Rake
+	return &next
+}
+
+// enter charges the deposit and registers the user.
+func (db *DB) enter(tr *tournament, uID int) {
+	rake := tr.EntryRake()
+	bonus := db.deposit(tr.ID, uID, tr.Deposit)
+	db.transfer(entity.EscrowAccount(tr.ID), entity.RakeAccount, rake, entity.TxRake, tr.ID)
+	tr.entries = append(tr.entries, entity.Entry{UserID: uID, Stake: tr.Deposit, BonusStake: bonus, Rake: rake})
+	tr.Prize += tr.Deposit - rake
+	tr.Rake += rake
+}
+
+// promote moves users from the head of the waitlist into the free seats.
+// Users who can't pay the deposit any more are dropped from the waitlist.
+func (db *DB) promote(tr *tournament) {
+	for len(tr.waitlist) > 0 && !tr.IsFull(len(tr.entries)) {
+		uID := tr.waitlist[0]
+		tr.waitlist = tr.waitlist[1:]
+		if db.users[uID].Funds() >= tr.Deposit {
+			db.enter(tr, uID)
+		}
+	}
+}
+
+// LeaveTourn unregisters a user from an open tournament and refunds the
+// deposit from the prize and the rake. The seat goes to the first user on the
+// waitlist. A waitlisted user just leaves the waitlist.
+func (db *DB) LeaveTourn(tID, uID int) (entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return entity.Tournament{ID: tID}, err
+	}
+	err = tr.Status.Require(entity.Open)
+	if err != nil {
+		return tr.Tournament, err
+	}
+	if tr.waiting(uID) {
+		tr.removeWaiting(uID)
+		return tr.Tournament, nil
+	}
+	e, ok := tr.entry(uID)
+	if !ok && tr.hasUser(uID) {
+		return tr.Tournament, entity.ReqErr(errors.New("only the captain withdraws the team"))
+	} else if !ok {
+		return tr.Tournament, entity.ReqErr(errors.New("user is not registered"))
+	}
+
+	tr.removeEntry(uID)
+	db.refund(tID, e)
+	tr.Prize -= e.Stake - e.Rake
+	tr.Rake -= e.Rake
+	db.promote(tr)
+	return tr.Tournament, nil
+}
+
+func (db *DB) SubmitScore(tID, uID, score int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Require(entity.Running)
+	if err != nil {
+		return err
+	}
+	for i := range tr.entries {
+		if tr.entries[i].UserID == uID {
+			now := time.Now()
+			tr.entries[i].Score = &score
+			tr.entries[i].ScoredAt = &now
+			return nil
+		}
+	}
+	return entity.ReqErr(errors.New("user is not registered"))
+}
+
+// refund returns the whole stake of an entry, its rake included, to those
+// who paid it. Bonus points go back as bonus points, or as cash if the
+// payer's wagering requirement has been met meanwhile.
+func (db *DB) refund(tID int, e entity.Entry) {
+	for _, m := range e.Payers() {
+		cash, bonus := m.Stake-m.BonusStake, m.BonusStake
+		if db.users[m.UserID].Wagering == 0 {
+			cash, bonus = m.Stake, 0
+		}
+		db.transfer(entity.RakeAccount, entity.EscrowAccount(tID), m.Rake, entity.TxRefund, tID)
+		db.transfer(entity.EscrowAccount(tID), entity.UserAccount(m.UserID), cash, entity.TxRefund, tID)
+		db.transfer(entity.EscrowAccount(tID), entity.BonusAccount(m.UserID), bonus, entity.TxRefund, tID)
+	}
+}
+
+// FinishTourn pays out the prize of a running tournament by the placings
+// ranked among its entries, which are in the order of registration. The
+// prize of a team is split among its members. The deposits count toward the
+// wagering requirements of those who paid them. A tournament without a quorum
+// is cancelled instead.
+func (db *DB) FinishTourn(tID int, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Transition(entity.Finished)
+	if err != nil {
+		return err
+	}
+	if !tr.HasQuorum(len(tr.entries)) {
+		db.cancel(tr)
+		return nil
+	}
+	placings, ratings, err := db.settle(tr, rank)
+	if err != nil {
+		return err
+	}
+	db.finish(tr, placings, ratings)
+	return nil
+}
+
+// settle ranks the entries of tr by rank and checks that the placings pay out
+// the prize among its participants. Nothing is changed, so a tournament may
+// be settled before the finish is certain.
+func (db *DB) settle(tr *tournament, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) ([]entity.Placing, []entity.RatingChange, error) {
+	placings, ratings, err := rank(tr.Tournament, db.entries(tr))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = checkRatings(tr, ratings)
+	if err != nil {
+		return nil, nil, err
+	}
+	paid := 0
+	for _, p := range placings {
+		if _, ok := tr.entry(p.UserID); !ok {
+			return nil, nil, entity.DBErr(fmt.Errorf("user %d placed %d isn't a participant", p.UserID, p.Place))
+		}
+		paid += p.Amount
+	}
+	if len(placings) == 0 || paid != tr.Prize {
+		return nil, nil, entity.DBErr(fmt.Errorf("placings pay out %d points, the prize is %d", paid, tr.Prize))
+	}
+	return placings, ratings, nil
+}
+
+// finish pays out the settled placings of tr and applies the rating changes.
+func (db *DB) finish(tr *tournament, placings []entity.Placing, ratings []entity.RatingChange) {
+	for _, p := range placings {
+		e, _ := tr.entry(p.UserID)
+		payers := e.Payers()
+		for i, amount := range e.Split(p.Amount) {
+			db.payPrize(tr.ID, payers[i], amount)
+		}
+	}
+	for _, e := range tr.entries {
+		for _, m := range e.Payers() {
+			db.wager(tr.ID, m.UserID, m.Stake)
+		}
+	}
+	db.changeRatings(ratings)
+	tr.placings = placings
+	tr.waitlist = nil
+	tr.Winner = placings[0].UserID
+	tr.Status = entity.Finished
+}
+
+// CancelTourn refunds the deposits of all participants of an unfinished
+// tournament and marks it cancelled. The tournament and its participants are
+// kept.
+func (db *DB) CancelTourn(tID int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tr, err := db.tourn(tID)
+	if err != nil {
+		return err
+	}
+	err = tr.Status.Transition(entity.Cancelled)
+	if err != nil {
+		return err
+	}
+	db.cancel(tr)
+	return nil
+}
+
+func (db *DB) cancel(tr *tournament) {
+	for _, e := range tr.entries {
+		db.refund(tr.ID, e)
+	}
+	tr.waitlist = nil
+	tr.Status = entity.Cancelled
+	tr.Prize = 0
+	tr.Rake = 0
+}
+
+func (db *DB) DelTourn(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	delete(db.tourns, id)
+	return nil
+}
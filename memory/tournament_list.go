@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (t *tournament) summary() entity.TournamentSummary {
+	return entity.TournamentSummary{
+		ID:           t.ID,
+		Name:         t.Name,
+		Deposit:      t.Deposit,
+		Prize:        t.Prize,
+		Rake:         t.Rake,
+		Participants: len(t.entries),
+		Status:       t.Status,
+		Format:       t.Format,
+		Winner:       t.Winner,
+	}
+}
+
+func (db *DB) ListTourns(f entity.TournamentFilter) (entity.TournamentPage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	p := entity.TournamentPage{Tournaments: []entity.TournamentSummary{}}
+
+	var ts []entity.TournamentSummary
+	for _, tr := range db.tourns {
+		if tr.Private {
+			continue
+		}
+		t := tr.summary()
+		if f.Match(t) {
+			ts = append(ts, t)
+		}
+	}
+	p.Total = len(ts)
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].ID > ts[j].ID
+	})
+	if f.Cursor.ID != 0 {
+		ts = ts[sort.Search(len(ts), func(i int) bool {
+			return ts[i].ID < f.Cursor.ID
+		}):]
+	}
+	if len(ts) > f.Limit {
+		ts = ts[:f.Limit]
+		p.NextCursor = entity.Cursor{ID: ts[f.Limit-1].ID}.Encode()
+	}
+	p.Tournaments = append(p.Tournaments, ts...)
+	return p, nil
+}
+
+func (db *DB) DueTourns(now time.Time) ([]entity.Tournament, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	ts := []entity.Tournament{}
+	for _, tr := range db.tourns {
+		if _, ok := tr.Due(now); ok {
+			ts = append(ts, tr.Tournament)
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].ID < ts[j].ID
+	})
+	return ts, nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestListTourns(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 1000)
+	var ids []int
+	for _, deposit := range []int{100, 200, 300} {
+		ids = append(ids, newTourn(t, db, entity.Tournament{Deposit: deposit}).ID)
+	}
+	join(t, db, ids[0], u.ID)
+	start(t, db, ids[0])
+	require.NoError(t, db.FinishTourn(ids[0], first))
+	join(t, db, ids[1], u.ID)
+
+	f := entity.TournamentFilter{Page: entity.Page{Limit: 2}}
+	p, err := db.ListTourns(f)
+	require.NoError(t, err)
+	assert.Equal(t, 3, p.Total)
+	require.Len(t, p.Tournaments, 2)
+	assert.Equal(t, ids[2], p.Tournaments[0].ID, "newest first")
+	assert.Equal(t, entity.TournamentSummary{ID: ids[1], Name: "tournament", Deposit: 200, Prize: 200, Participants: 1, Status: entity.Open}, p.Tournaments[1])
+	f.Cursor, err = entity.DecodeCursor(p.NextCursor)
+	require.NoError(t, err)
+	p, err = db.ListTourns(f)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.TournamentSummary{{ID: ids[0], Name: "tournament", Deposit: 100, Prize: 100, Participants: 1, Status: entity.Finished, Winner: u.ID}}, p.Tournaments)
+	assert.Empty(t, p.NextCursor)
+
+	p, err = db.ListTourns(entity.TournamentFilter{Page: entity.Page{Limit: 10}, Statuses: []entity.Status{entity.Open}, MinDeposit: intPtr(250)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Total)
+	require.Len(t, p.Tournaments, 1)
+	assert.Equal(t, ids[2], p.Tournaments[0].ID)
+
+	p, err = db.ListTourns(entity.TournamentFilter{Page: entity.Page{Limit: 10}, MaxDeposit: intPtr(50)})
+	require.NoError(t, err)
+	assert.Equal(t, entity.TournamentPage{Tournaments: []entity.TournamentSummary{}}, p)
+}
+
+func TestDueTourns(t *testing.T) {
+	db := New()
+	now := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	create := func(tourn entity.Tournament) int {
+		tourn.Name = "scheduled"
+		tourn.Deposit = 100
+		tourn, err := db.CreateTourn(tourn)
+		require.NoError(t, err)
+		return tourn.ID
+	}
+	opens := create(entity.Tournament{Status: entity.Draft, OpensAt: &past})
+	create(entity.Tournament{Status: entity.Draft, OpensAt: &future})
+	starts := create(entity.Tournament{Status: entity.Open, StartsAt: &now})
+	create(entity.Tournament{Status: entity.Open, EndsAt: &past})
+	ends := create(entity.Tournament{Status: entity.Open, Format: entity.FormatRoundRobin, Selector: entity.SelectExternal, EndsAt: &past})
+	require.NoError(t, db.SetTournStatus(ends, entity.Running))
+
+	due, err := db.DueTourns(now)
+	require.NoError(t, err)
+	var ids []int
+	for _, tourn := range due {
+		ids = append(ids, tourn.ID)
+		assert.Empty(t, tourn.Users)
+	}
+	assert.Equal(t, []int{opens, starts, ends}, ids, "an open tournament waits for startsAt")
+	require.Len(t, due, 3)
+	assert.Equal(t, entity.FormatRoundRobin, due[2].Format, "the scheduler finishes leagues")
+	assert.Equal(t, entity.SelectExternal, due[2].Selector)
+	require.NotNil(t, due[2].EndsAt)
+	assert.True(t, past.Equal(*due[2].EndsAt))
+
+	due, err = db.DueTourns(past.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due)
+}
@@ -0,0 +1,447 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// newTourn creates an open tournament with the settings of t.
+func newTourn(t *testing.T, db *DB, tourn entity.Tournament) entity.Tournament {
+	t.Helper()
+	tourn.Name = "tournament"
+	tourn.Status = entity.Open
+	if tourn.Selector == "" {
+		tourn.Selector = entity.SelectUniform
+	}
+	tourn, err := db.CreateTourn(tourn)
+	require.NoError(t, err)
+	return tourn
+}
+
+func admit(u entity.User, t entity.Tournament, inv *entity.Invite) error {
+	return nil
+}
+
+func join(t *testing.T, db *DB, tID, uID int) entity.Tournament {
+	t.Helper()
+	tourn, err := db.JoinTourn(tID, uID, "", admit, first)
+	require.NoError(t, err)
+	return tourn
+}
+
+func start(t *testing.T, db *DB, tID int) {
+	t.Helper()
+	require.NoError(t, db.SetTournStatus(tID, entity.Running))
+}
+
+// first pays the whole prize to the first registered participant.
+func first(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+	return []entity.Placing{{UserID: entries[0].UserID, Place: 1, Amount: t.Prize}}, nil, nil
+}
+
+// pick pays the whole prize to the user uID.
+func pick(uID int) func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+	return func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		return []entity.Placing{{UserID: uID, Place: 1, Amount: t.Prize}}, nil, nil
+	}
+}
+
+func TestSetTournStatus(t *testing.T) {
+	db := New()
+	tr, err := db.CreateTourn(entity.Tournament{Name: "cup", Deposit: 100, Status: entity.Draft})
+	require.NoError(t, err)
+	assert.Error(t, db.SetTournStatus(tr.ID, entity.Running), "a draft must be opened first")
+	require.NoError(t, db.SetTournStatus(tr.ID, entity.Open))
+	require.NoError(t, db.SetTournStatus(tr.ID, entity.Running))
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Running, tourn.Status)
+	assert.Error(t, db.SetTournStatus(tr.ID, entity.Open))
+	assert.Error(t, db.SetTournStatus(tr.ID+100, entity.Open))
+}
+
+func TestJoinTourn(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+
+	tr = join(t, db, tr.ID, u.ID)
+	assert.Equal(t, 100, tr.Prize)
+	assert.Equal(t, 600, balance(t, db, u.ID))
+	_, err := db.JoinTourn(tr.ID, u.ID, "", admit, first)
+	assert.Error(t, err, "a user joins once")
+
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, tourn.Prize)
+	assert.Equal(t, []entity.Winner{{ID: u.ID, Name: u.Name, Stake: 100}}, tourn.Users)
+
+	_, err = db.JoinTourn(42, u.ID, "", admit, first)
+	assert.Error(t, err)
+	_, err = db.JoinTourn(tr.ID, 42, "", admit, first)
+	assert.Error(t, err)
+}
+
+func TestJoinTournRejected(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+	poor := newUser(t, db, 50)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+
+	_, err := db.JoinTourn(tr.ID, u.ID, "", func(joiner entity.User, tourn entity.Tournament, inv *entity.Invite) error {
+		assert.Equal(t, u.ID, joiner.ID)
+		assert.Equal(t, 700, joiner.Balance)
+		assert.Equal(t, 100, tourn.Deposit)
+		return entity.RegErr(errors.New("no"))
+	}, first)
+	require.Error(t, err)
+	_, err = db.JoinTourn(tr.ID, poor.ID, "", admit, first)
+	require.Error(t, err)
+	assert.Equal(t, 700, balance(t, db, u.ID))
+	assert.Equal(t, 50, balance(t, db, poor.ID))
+	requireBalanced(t, db)
+
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Empty(t, tourn.Users)
+}
+
+func TestLeaveTourn(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+
+	tourn, err := db.LeaveTourn(tr.ID, u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100, tourn.Prize)
+	assert.Equal(t, 700, balance(t, db, u1.ID))
+	refund := transactions(t, db, entity.TransactionFilter{UserID: u1.ID})[0]
+	assert.Equal(t, entity.TxRefund, refund.Type)
+	assert.Equal(t, 100, refund.Amount)
+	assert.Equal(t, tr.ID, refund.TournamentID)
+	requireBalanced(t, db)
+	_, err = db.LeaveTourn(tr.ID, u1.ID)
+	assert.Error(t, err, "a user leaves once")
+	_, err = db.LeaveTourn(tr.ID+100, u2.ID)
+	assert.Error(t, err)
+
+	join(t, db, tr.ID, u1.ID)
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u2.ID)))
+	_, err = db.LeaveTourn(tr.ID, u1.ID)
+	assert.Error(t, err, "a finished tournament keeps its players")
+	assert.Equal(t, 600, balance(t, db, u1.ID))
+}
+
+func TestFinishTourn(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	empty := newTourn(t, db, entity.Tournament{Deposit: 100})
+	start(t, db, empty.ID)
+	require.NoError(t, db.FinishTourn(empty.ID, first))
+	tourn, err := db.GetTourn(empty.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Cancelled, tourn.Status, "nobody has joined")
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	assert.Error(t, db.FinishTourn(tr.ID, first), "an open tournament isn't running")
+
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		assert.Equal(t, entity.SelectUniform, tourn.Selector)
+		assert.Equal(t, 200, tourn.Prize)
+		assert.Equal(t, []entity.Entry{{UserID: u1.ID, Stake: 100}, {UserID: u2.ID, Stake: 100}}, entries)
+		return []entity.Placing{{UserID: u2.ID, Place: 1, Amount: 200}, {UserID: u1.ID, Place: 2}}, nil, nil
+	}))
+	assert.Equal(t, 600, balance(t, db, u1.ID))
+	assert.Equal(t, 800, balance(t, db, u2.ID))
+	requireBalanced(t, db)
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, u2.ID, tourn.Winner)
+	assert.Equal(t, []entity.Winner{{ID: u1.ID, Name: u1.Name, Stake: 100, Place: 2}, {ID: u2.ID, Name: u2.Name, Stake: 100, Winner: true, Place: 1, Payout: 200}}, tourn.Users)
+
+	_, err = db.JoinTourn(tr.ID, newUser(t, db, 700).ID, "", admit, first)
+	assert.Error(t, err, "a finished tournament is closed")
+}
+
+func TestFinishTournPlacings(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	u3 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	join(t, db, tr.ID, u3.ID)
+	start(t, db, tr.ID)
+
+	placings := func(p ...entity.Placing) func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		return func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+			return p, nil, nil
+		}
+	}
+	assert.Error(t, db.FinishTourn(tr.ID, placings()), "somebody wins")
+	assert.Error(t, db.FinishTourn(tr.ID, placings(entity.Placing{UserID: u1.ID, Place: 1, Amount: 200})), "the whole prize is paid")
+	assert.Error(t, db.FinishTourn(tr.ID, placings(entity.Placing{UserID: u1.ID, Place: 1, Amount: 200}, entity.Placing{UserID: 42, Place: 2, Amount: 100})))
+	assert.Equal(t, 600, balance(t, db, u1.ID))
+	requireBalanced(t, db)
+
+	require.NoError(t, db.FinishTourn(tr.ID, placings(
+		entity.Placing{UserID: u2.ID, Place: 1, Amount: 200},
+		entity.Placing{UserID: u3.ID, Place: 2, Amount: 100},
+		entity.Placing{UserID: u1.ID, Place: 3},
+	)))
+	assert.Equal(t, 600, balance(t, db, u1.ID))
+	assert.Equal(t, 800, balance(t, db, u2.ID))
+	assert.Equal(t, 700, balance(t, db, u3.ID))
+	requireBalanced(t, db)
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u2.ID, tourn.Winner)
+	assert.Equal(t, []entity.Winner{
+		{ID: u1.ID, Name: u1.Name, Stake: 100, Place: 3},
+		{ID: u2.ID, Name: u2.Name, Stake: 100, Winner: true, Place: 1, Payout: 200},
+		{ID: u3.ID, Name: u3.Name, Stake: 100, Place: 2, Payout: 100},
+	}, tourn.Users)
+}
+
+func TestTournSeed(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Seed: "seed", SeedHash: "hash"})
+	join(t, db, tr.ID, u.ID)
+	start(t, db, tr.ID)
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "hash", tourn.SeedHash)
+	assert.Empty(t, tourn.Seed, "the seed is secret until the end")
+
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		assert.Equal(t, "seed", tourn.Seed, "the ranking is drawn from the seed")
+		return pick(u.ID)(tourn, entries)
+	}))
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "seed", tourn.Seed)
+}
+
+func TestRake(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	u3 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, RakePercent: 10})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	tourn := join(t, db, tr.ID, u3.ID)
+	assert.Equal(t, 270, tourn.Prize)
+	assert.Equal(t, 30, tourn.Rake)
+	assert.Equal(t, 600, balance(t, db, u1.ID), "the rake is part of the deposit")
+	requireBalanced(t, db)
+
+	tourn, err := db.LeaveTourn(tr.ID, u3.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 180, tourn.Prize)
+	assert.Equal(t, 20, tourn.Rake)
+	assert.Equal(t, 700, balance(t, db, u3.ID), "the rake is refunded too")
+	requireBalanced(t, db)
+
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		assert.Equal(t, []entity.Entry{{UserID: u1.ID, Stake: 100, Rake: 10}, {UserID: u2.ID, Stake: 100, Rake: 10}}, entries)
+		return pick(u1.ID)(tourn, entries)
+	}))
+	assert.Equal(t, 780, balance(t, db, u1.ID))
+	requireBalanced(t, db)
+	tb, err := db.TrialBalance()
+	require.NoError(t, err)
+	assert.Contains(t, tb.Accounts, entity.AccountBalance{Account: entity.RakeAccount, Balance: 20})
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, tourn.RakePercent)
+	assert.Equal(t, 20, tourn.Rake)
+
+	fee := newTourn(t, db, entity.Tournament{Deposit: 100, RakeFee: 15})
+	join(t, db, fee.ID, u3.ID)
+	require.NoError(t, db.CancelTourn(fee.ID))
+	assert.Equal(t, 700, balance(t, db, u3.ID))
+	requireBalanced(t, db)
+	tourn, err = db.GetTourn(fee.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, tourn.Rake)
+}
+
+func TestWaitlist(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	poor := newUser(t, db, 150)
+	u3 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, MaxPlayers: 1})
+	join(t, db, tr.ID, u1.ID)
+	tourn := join(t, db, tr.ID, u2.ID)
+	assert.Equal(t, 100, tourn.Prize, "a waitlisted user pays nothing")
+	join(t, db, tr.ID, poor.ID)
+	join(t, db, tr.ID, u3.ID)
+	_, err := db.JoinTourn(tr.ID, u2.ID, "", admit, first)
+	assert.Error(t, err, "a user waits once")
+	assert.Equal(t, 700, balance(t, db, u2.ID))
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UserTourn{{ID: u2.ID, Name: u2.Name}, {ID: poor.ID, Name: poor.Name}, {ID: u3.ID, Name: u3.Name}}, tourn.Waitlist)
+
+	_, err = db.LeaveTourn(tr.ID, u2.ID)
+	require.NoError(t, err, "a waitlisted user leaves the waitlist")
+	_, err = db.TakePoints(poor.ID, 100)
+	require.NoError(t, err)
+	_, err = db.LeaveTourn(tr.ID, u1.ID)
+	require.NoError(t, err)
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Winner{{ID: u3.ID, Name: u3.Name, Stake: 100}}, tourn.Users, "the seat goes to the first user who can pay")
+	assert.Empty(t, tourn.Waitlist)
+	assert.Equal(t, 100, tourn.Prize)
+	assert.Equal(t, 600, balance(t, db, u3.ID))
+	assert.Equal(t, 50, balance(t, db, poor.ID))
+	requireBalanced(t, db)
+}
+
+func TestMinPlayers(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, MinPlayers: 2, MaxPlayers: 1})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	start(t, db, tr.ID)
+
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		t.Error("a tournament below the minimum isn't ranked")
+		return first(tourn, entries)
+	}))
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Cancelled, tourn.Status)
+	assert.Equal(t, 2, tourn.MinPlayers)
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Empty(t, tourn.Waitlist)
+	assert.Equal(t, 700, balance(t, db, u1.ID))
+	assert.Equal(t, 700, balance(t, db, u2.ID))
+	requireBalanced(t, db)
+}
+
+func TestSitAndGo(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Format: entity.FormatSitAndGo, MaxPlayers: 2})
+	assert.Equal(t, entity.Open, join(t, db, tr.ID, u1.ID).Status)
+
+	_, err := db.JoinTourn(tr.ID, u2.ID, "", admit, func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		return nil, nil, errors.New("can't rank")
+	})
+	assert.Error(t, err)
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Open, tourn.Status, "a failed finish undoes the join")
+	assert.Len(t, tourn.Users, 1)
+	assert.Equal(t, 700, balance(t, db, u2.ID))
+	requireBalanced(t, db)
+
+	tourn = join(t, db, tr.ID, u2.ID)
+	assert.Equal(t, entity.Finished, tourn.Status, "the join that fills it finishes it")
+	tourn, err = db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, u1.ID, tourn.Winner)
+	assert.Equal(t, entity.FormatSitAndGo, tourn.Format)
+	assert.Equal(t, 800, balance(t, db, u1.ID))
+	assert.Equal(t, 600, balance(t, db, u2.ID))
+	requireBalanced(t, db)
+}
+
+func TestSubmitScore(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Selector: entity.SelectScore, TieBreak: entity.TieRandom})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	assert.Error(t, db.SubmitScore(tr.ID, u1.ID, 10), "the tournament isn't running")
+	start(t, db, tr.ID)
+
+	require.NoError(t, db.SubmitScore(tr.ID, u1.ID, 10))
+	require.NoError(t, db.SubmitScore(tr.ID, u1.ID, 7))
+	assert.Error(t, db.SubmitScore(tr.ID, newUser(t, db, 700).ID, 10))
+	assert.Error(t, db.SubmitScore(tr.ID+100, u1.ID, 10))
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.TieRandom, tourn.TieBreak)
+	require.NotNil(t, tourn.Users[0].Score)
+	assert.Equal(t, 7, *tourn.Users[0].Score, "a new score replaces the previous one")
+	assert.NotNil(t, tourn.Users[0].ScoredAt)
+	assert.Nil(t, tourn.Users[1].Score)
+
+	require.NoError(t, db.FinishTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error) {
+		require.NotNil(t, entries[0].Score)
+		assert.Equal(t, 7, *entries[0].Score)
+		assert.Nil(t, entries[1].Score)
+		return first(tourn, entries)
+	}))
+	assert.Error(t, db.SubmitScore(tr.ID, u2.ID, 10), "the tournament is finished")
+}
+
+func TestCancelTourn(t *testing.T) {
+	db := New()
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+
+	require.NoError(t, db.CancelTourn(tr.ID))
+	assert.Equal(t, 700, balance(t, db, u1.ID))
+	assert.Equal(t, 700, balance(t, db, u2.ID))
+	requireBalanced(t, db)
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Cancelled, tourn.Status)
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Len(t, tourn.Users, 2, "participants are kept")
+
+	assert.Error(t, db.CancelTourn(tr.ID), "a tournament is cancelled once")
+	assert.Error(t, db.FinishTourn(tr.ID, first))
+	_, err = db.JoinTourn(tr.ID, newUser(t, db, 700).ID, "", admit, first)
+	assert.Error(t, err)
+	_, err = db.LeaveTourn(tr.ID, u1.ID)
+	assert.Error(t, err)
+	assert.Error(t, db.CancelTourn(tr.ID+100))
+
+	finished := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, finished.ID, u1.ID)
+	start(t, db, finished.ID)
+	require.NoError(t, db.FinishTourn(finished.ID, pick(u1.ID)))
+	assert.Error(t, db.CancelTourn(finished.ID), "a finished tournament keeps its prize")
+	assert.Equal(t, 700, balance(t, db, u1.ID))
+}
+
+func TestDelTourn(t *testing.T) {
+	db := New()
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	require.NoError(t, db.DelTourn(tr.ID))
+	_, err := db.GetTourn(tr.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// changeBalance adds amount to the user's cash, or bonus points if bonus is
+// set, and records the change in the user's transaction history. Use
+// transfer to keep the books balanced.
+func (db *DB) changeBalance(uID, amount int, bonus bool, typ entity.TransactionType, tID int) {
+	u := db.users[uID]
+	balance := &u.Balance
+	if bonus {
+		balance = &u.Bonus
+	}
+	*balance += amount
+	db.users[uID] = u
+	db.txs = append(db.txs, entity.Transaction{
+		ID:           len(db.txs) + 1,
+		UserID:       uID,
+		Type:         typ,
+		Amount:       amount,
+		Bonus:        bonus,
+		Balance:      *balance,
+		TournamentID: tID,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+func (db *DB) ListTransactions(f entity.TransactionFilter) (entity.TransactionPage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	p := entity.TransactionPage{Transactions: []entity.Transaction{}}
+	for i := len(db.txs) - 1; i >= 0; i-- {
+		t := db.txs[i]
+		if f.Cursor.ID != 0 && t.ID >= f.Cursor.ID {
+			continue
+		}
+		if !f.Match(t) {
+			continue
+		}
+		if len(p.Transactions) == f.Limit {
+			p.NextCursor = entity.Cursor{ID: p.Transactions[f.Limit-1].ID}.Encode()
+			break
+		}
+		p.Transactions = append(p.Transactions, t)
+	}
+	return p, nil
+}
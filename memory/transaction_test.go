@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func transactions(t *testing.T, db *DB, f entity.TransactionFilter) []entity.Transaction {
+	t.Helper()
+	if f.Limit == 0 {
+		f.Limit = entity.MaxLimit
+	}
+	p, err := db.ListTransactions(f)
+	require.NoError(t, err)
+	return p.Transactions
+}
+
+func TestLedger(t *testing.T) {
+	db := New()
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	other := newUser(t, db, 500)
+	_, err = db.TakePoints(u.ID, 200)
+	require.NoError(t, err)
+	_, err = db.FundPoints(u.ID, 50)
+	require.NoError(t, err)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u.ID)
+	join(t, db, tr.ID, other.ID)
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u.ID)))
+
+	var got []entity.Transaction
+	for _, tx := range transactions(t, db, entity.TransactionFilter{UserID: u.ID}) {
+		assert.Equal(t, u.ID, tx.UserID)
+		assert.False(t, tx.CreatedAt.IsZero())
+		tx.ID, tx.UserID, tx.CreatedAt = 0, 0, time.Time{}
+		got = append(got, tx)
+	}
+	assert.Equal(t, []entity.Transaction{
+		{Type: entity.TxPrize, Amount: 200, Balance: 650, TournamentID: tr.ID},
+		{Type: entity.TxDeposit, Amount: -100, Balance: 450, TournamentID: tr.ID},
+		{Type: entity.TxFund, Amount: 50, Balance: 550},
+		{Type: entity.TxTake, Amount: -200, Balance: 500},
+		{Type: entity.TxRegFee, Amount: -300, Balance: 700},
+		{Type: entity.TxRegistration, Amount: 1000, Balance: 1000},
+	}, got, "newest first")
+	assert.Equal(t, 650, balance(t, db, u.ID))
+
+	_, err = db.TakePoints(u.ID, 1000)
+	require.Error(t, err)
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: u.ID}), 6, "a failed change isn't recorded")
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: other.ID}), 2)
+}
+
+func TestListTransactions(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 1000)
+	for i := 1; i <= 5; i++ {
+		_, err := db.FundPoints(u.ID, i)
+		require.NoError(t, err)
+	}
+
+	p, err := db.ListTransactions(entity.TransactionFilter{Page: entity.Page{Limit: 2}, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 2)
+	assert.Equal(t, 5, p.Transactions[0].Amount)
+	assert.Equal(t, 4, p.Transactions[1].Amount)
+	c, err := entity.DecodeCursor(p.NextCursor)
+	require.NoError(t, err)
+
+	p, err = db.ListTransactions(entity.TransactionFilter{Page: entity.Page{Limit: 3, Cursor: c}, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 3)
+	assert.Equal(t, 3, p.Transactions[0].Amount)
+	assert.Equal(t, 1, p.Transactions[2].Amount)
+	require.NotEmpty(t, p.NextCursor, "the registration is left")
+	c, err = entity.DecodeCursor(p.NextCursor)
+	require.NoError(t, err)
+
+	p, err = db.ListTransactions(entity.TransactionFilter{Page: entity.Page{Limit: 3, Cursor: c}, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 1)
+	assert.Equal(t, entity.TxRegistration, p.Transactions[0].Type)
+	assert.Empty(t, p.NextCursor)
+
+	funds := transactions(t, db, entity.TransactionFilter{UserID: u.ID, Types: []entity.TransactionType{entity.TxFund}})
+	assert.Len(t, funds, 5)
+	from := funds[1].CreatedAt
+	for _, tx := range transactions(t, db, entity.TransactionFilter{UserID: u.ID, From: from}) {
+		assert.False(t, tx.CreatedAt.Before(from))
+	}
+	to := funds[0].CreatedAt
+	before := transactions(t, db, entity.TransactionFilter{UserID: u.ID, To: to})
+	assert.NotEmpty(t, before)
+	for _, tx := range before {
+		assert.True(t, tx.CreatedAt.Before(to))
+	}
+}
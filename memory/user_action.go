@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// DB is an in-memory storage with the same semantics as postgres.DB.
+// Every method holds the lock for its whole duration and validates
+// everything before it changes any state, so multi-step operations behave
+// like a single transaction.
+type DB struct {
+	mu       sync.Mutex
+	users    map[int]entity.User
+	tourns   map[int]*tournament
+	teams    map[int]*entity.Team
+	txs      []entity.Transaction
+	ratings  []entity.RatingChange
+	policies []entity.Policy
+	accounts map[entity.Account]int
+	userID   int
+	tournID  int
+	teamID   int
+	inviteID int
+}
+
+// New returns an empty storage with the default registration policy in
+// force.
+func New() *DB {
+	db := &DB{
+		users:    map[int]entity.User{},
+		tourns:   map[int]*tournament{},
+		teams:    map[int]*entity.Team{},
+		accounts: map[entity.Account]int{},
+	}
+	db.setPolicy(entity.DefaultPolicy)
+	return db
+}
+
+func (db *DB) CreateUser(u entity.User, p entity.Policy) (entity.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	cash := u.Balance - p.Fee
+	if p.Wagering == 0 {
+		cash += p.Bonus
+	}
+	if u.Balance < 0 || cash < 0 {
+		return u, entity.DBErr(errors.New("balance must not be negative"))
+	}
+	if _, err := db.policy(u.PolicyID); err != nil {
+		return u, entity.DBErr(fmt.Errorf("can't create user: unknown policy %d", u.PolicyID))
+	}
+	db.userID++
+	u.ID = db.userID
+	initial := u.Balance
+	u.Balance = 0
+	db.users[u.ID] = u
+	db.transfer(entity.HouseAccount, entity.UserAccount(u.ID), initial, entity.TxRegistration, 0)
+	db.grantBonus(u.ID, p)
+	db.transfer(entity.UserAccount(u.ID), entity.FeesAccount, p.Fee, entity.TxRegFee, 0)
+	return db.users[u.ID], nil
+}
+
+func (db *DB) GetUser(id int) (entity.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.getUser(id)
+}
+
+func (db *DB) getUser(id int) (entity.User, error) {
+	if id <= 0 {
+		return entity.User{}, entity.InvIDErr(errors.New("expected id greater than 0"))
+	}
+	u, ok := db.users[id]
+	if !ok {
+		return entity.User{}, entity.UserNotFoundErr(errors.New("user not found"))
+	}
+	return u, nil
+}
+
+// DelUser removes a user and returns the rest of the balance, bonus points
+// included, to the house.
+func (db *DB) DelUser(id int) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	u, err := db.getUser(id)
+	if err != nil {
+		return err
+	}
+	for _, t := range db.tourns {
+		if t.hasUser(u.ID) {
+			return entity.DBErr(fmt.Errorf("delete constraint on a dependent table: user %d is registered in tournament %d", u.ID, t.ID))
+		}
+	}
+	for _, t := range db.teams {
+		if t.CaptainID == u.ID {
+			return entity.DBErr(fmt.Errorf("delete constraint on a dependent table: user %d is the captain of team %d", u.ID, t.ID))
+		}
+	}
+	for _, t := range db.tourns {
+		t.removeWaiting(u.ID)
+		t.removeInvites(u.ID)
+	}
+	for _, t := range db.teams {
+		removeMember(t, u.ID)
+	}
+	db.transfer(entity.UserAccount(u.ID), entity.HouseAccount, u.Balance, entity.TxClose, 0)
+	db.transfer(entity.BonusAccount(u.ID), entity.HouseAccount, u.Bonus, entity.TxClose, 0)
+	delete(db.users, u.ID)
+	return nil
+}
+
+func (db *DB) TakePoints(id, points int) (entity.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	u, err := db.getUser(id)
+	if err != nil {
+		return u, err
+	}
+	if u.Balance-points < 0 {
+		return u, entity.DBErr(errors.New("balance must not be negative"))
+	}
+	db.transfer(entity.UserAccount(u.ID), entity.HouseAccount, points, entity.TxTake, 0)
+	return db.users[u.ID], nil
+}
+
+func (db *DB) FundPoints(id, points int) (entity.User, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	u, err := db.getUser(id)
+	if err != nil {
+		return u, err
+	}
+	db.transfer(entity.HouseAccount, entity.UserAccount(u.ID), points, entity.TxFund, 0)
+	return db.users[u.ID], nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// free is the default policy without its fee, so that users keep the balance
+// they are created with.
+var free = entity.Policy{ID: 1}
+
+func newUser(t *testing.T, db *DB, balance int) entity.User {
+	t.Helper()
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: balance, PolicyID: 1}, free)
+	require.NoError(t, err)
+	return u
+}
+
+func balance(t *testing.T, db *DB, uID int) int {
+	t.Helper()
+	u, err := db.GetUser(uID)
+	require.NoError(t, err)
+	return u.Balance
+}
+
+func TestCreateUser(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+	assert.Equal(t, 1, u.ID)
+	assert.Equal(t, 2, newUser(t, db, 0).ID)
+
+	got, err := db.GetUser(u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u, got)
+
+	_, err = db.CreateUser(entity.User{Name: "user", Balance: -1, PolicyID: 1}, free)
+	assert.Error(t, err)
+	_, err = db.CreateUser(entity.User{Name: "user", Balance: 200, PolicyID: 1}, entity.DefaultPolicy)
+	assert.Error(t, err, "the fee is charged from the initial balance")
+	u, err = db.CreateUser(entity.User{Name: "user", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, 3, u.ID)
+	assert.Equal(t, 700, balance(t, db, u.ID))
+	_, err = db.GetUser(0)
+	assert.Error(t, err)
+	_, err = db.GetUser(42)
+	assert.Error(t, err)
+}
+
+func TestTakeFundPoints(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+
+	_, err := db.TakePoints(u.ID, 701)
+	require.Error(t, err)
+	assert.Equal(t, 700, balance(t, db, u.ID))
+	u, err = db.TakePoints(u.ID, 200)
+	require.NoError(t, err)
+	assert.Equal(t, 500, u.Balance)
+	u, err = db.FundPoints(u.ID, 400)
+	require.NoError(t, err)
+	assert.Equal(t, 900, u.Balance)
+	assert.Equal(t, 900, balance(t, db, u.ID))
+	requireBalanced(t, db)
+
+	_, err = db.TakePoints(42, 1)
+	assert.Error(t, err)
+	_, err = db.FundPoints(42, 1)
+	assert.Error(t, err)
+}
+
+func TestDelUser(t *testing.T) {
+	db := New()
+	u := newUser(t, db, 700)
+	other := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, other.ID)
+
+	require.NoError(t, db.DelUser(u.ID))
+	_, err := db.GetUser(u.ID)
+	assert.Error(t, err)
+	assert.Error(t, db.DelUser(u.ID))
+	assert.Error(t, db.DelUser(other.ID), "a registered user stays")
+}
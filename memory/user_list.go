@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db *DB) ListUsers(f entity.UserFilter) (entity.UserPage, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	p := entity.UserPage{Users: []entity.User{}}
+
+	var users []entity.User
+	for _, u := range db.users {
+		if f.Match(u) {
+			users = append(users, u)
+		}
+	}
+	p.Total = len(users)
+	sort.Slice(users, func(i, j int) bool {
+		return f.Less(users[i], users[j])
+	})
+	if f.Cursor.ID != 0 {
+		after := f.CursorUser()
+		users = users[sort.Search(len(users), func(i int) bool {
+			return f.Less(after, users[i])
+		}):]
+	}
+	if len(users) > f.Limit {
+		users = users[:f.Limit]
+		p.NextCursor = f.CursorOf(users[f.Limit-1]).Encode()
+	}
+	p.Users = append(p.Users, users...)
+	return p, nil
+}
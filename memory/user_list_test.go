@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func names(users []entity.User) []string {
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	return names
+}
+
+func TestListUsers(t *testing.T) {
+	db := New()
+	for _, u := range []entity.User{
+		{Name: "carol", Balance: 300},
+		{Name: "alice", Balance: 500},
+		{Name: "bob", Balance: 300},
+		{Name: "alex", Balance: 100},
+	} {
+		u.PolicyID = 1
+		_, err := db.CreateUser(u, free)
+		require.NoError(t, err)
+	}
+
+	f := entity.UserFilter{Page: entity.Page{Limit: 3}, Sort: entity.UserSortBalance, Desc: true}
+	p, err := db.ListUsers(f)
+	require.NoError(t, err)
+	assert.Equal(t, 4, p.Total)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names(p.Users), "ties go by id in the same direction")
+	require.NotEmpty(t, p.NextCursor)
+	f.Cursor, err = entity.DecodeCursor(p.NextCursor)
+	require.NoError(t, err)
+	p, err = db.ListUsers(f)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alex"}, names(p.Users))
+	assert.Empty(t, p.NextCursor)
+
+	p, err = db.ListUsers(entity.UserFilter{Page: entity.Page{Limit: 10}, Sort: entity.UserSortName, NamePrefix: "al"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.Total)
+	assert.Equal(t, []string{"alex", "alice"}, names(p.Users))
+
+	p, err = db.ListUsers(entity.UserFilter{Page: entity.Page{Limit: 10}, Sort: entity.UserSortID, MinBalance: intPtr(300), MaxBalance: intPtr(300)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"carol", "bob"}, names(p.Users))
+
+	p, err = db.ListUsers(entity.UserFilter{Page: entity.Page{Limit: 10}, Sort: entity.UserSortID, NamePrefix: "dave"})
+	require.NoError(t, err)
+	assert.Equal(t, entity.UserPage{Users: []entity.User{}}, p)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
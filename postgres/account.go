@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// transfer posts a balanced journal that moves amount from one account to
+// another within tx. Postings on user cash and bonus accounts also change
+// users' balances and are written to their transaction history.
+func transfer(tx *sql.Tx, from, to entity.Account, amount int, typ entity.TransactionType, tID int) error {
+	if amount == 0 {
+		return nil
+	}
+	var jID int
+	err := tx.QueryRow(`
+		INSERT INTO journals (type, tournament_id)
+		VALUES ($1, $2)
+		RETURNING id`, typ, nullID(tID)).Scan(&jID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't create journal: %v", err))
+	}
+	_, err = tx.Exec(`
+		INSERT INTO postings (journal_id, account, amount)
+		VALUES ($1, $2, $3), ($1, $4, $5)`, jID, from, -amount, to, amount)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't post journal: %v", err))
+	}
+	if uID, ok := from.UserID(); ok {
+		err = changeBalance(tx, uID, -amount, from.IsBonus(), typ, tID)
+		if err != nil {
+			return err
+		}
+	}
+	if uID, ok := to.UserID(); ok {
+		err = changeBalance(tx, uID, amount, to.IsBonus(), typ, tID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db DB) TrialBalance() (entity.TrialBalance, error) {
+	tb := entity.TrialBalance{Accounts: []entity.AccountBalance{}}
+	rows, err := db.db.Query(`
+		SELECT account, SUM(amount)
+		FROM postings
+		GROUP BY account
+		ORDER BY account`)
+	if err != nil {
+		return tb, entity.DBErr(fmt.Errorf("can't get account balances: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a entity.AccountBalance
+		err := rows.Scan(&a.Account, &a.Balance)
+		if err != nil {
+			return tb, entity.DBErr(fmt.Errorf("can't get account balances: %v", err))
+		}
+		tb.Accounts = append(tb.Accounts, a)
+		tb.Total += a.Balance
+	}
+	err = rows.Err()
+	if err != nil {
+		return tb, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	tb.Balanced = tb.Total == 0
+	return tb, nil
+}
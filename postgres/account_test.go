@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// requireBalanced checks that the books net to zero and that every user's
+// cash and bonus points match their ledger accounts.
+func requireBalanced(t *testing.T, db DB) {
+	t.Helper()
+	tb, err := db.TrialBalance()
+	require.NoError(t, err)
+	require.True(t, tb.Balanced, "total %d", tb.Total)
+	accounts := map[entity.Account]int{}
+	for _, a := range tb.Accounts {
+		accounts[a.Account] = a.Balance
+	}
+	rows, err := db.db.Query(`SELECT id, balance, bonus FROM users`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var id, balance, bonus int
+		require.NoError(t, rows.Scan(&id, &balance, &bonus))
+		require.Equal(t, accounts[entity.UserAccount(id)], balance, "balance of user %d", id)
+		require.Equal(t, accounts[entity.BonusAccount(id)], bonus, "bonus of user %d", id)
+	}
+	require.NoError(t, rows.Err())
+}
+
+func TestTrialBalance(t *testing.T) {
+	db := migratedDB(t)
+	u, err := db.CreateUser(entity.User{Name: "alice", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u.ID)
+	_, err = db.TakePoints(u.ID, 50)
+	require.NoError(t, err)
+
+	tb, err := db.TrialBalance()
+	require.NoError(t, err)
+	assert.Equal(t, []entity.AccountBalance{
+		{Account: entity.EscrowAccount(tr.ID), Balance: 100},
+		{Account: entity.HouseAccount, Balance: -950},
+		{Account: entity.FeesAccount, Balance: 300},
+		{Account: entity.UserAccount(u.ID), Balance: 550},
+	}, tb.Accounts)
+	assert.True(t, tb.Balanced)
+
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u.ID)))
+	requireBalanced(t, db)
+	require.NoError(t, db.DelTourn(tr.ID))
+	require.NoError(t, db.DelUser(u.ID))
+	requireBalanced(t, db)
+	tb, err = db.TrialBalance()
+	require.NoError(t, err)
+	assert.Contains(t, tb.Accounts, entity.AccountBalance{Account: entity.HouseAccount, Balance: -300}, "the closed account goes back to the house")
+}
+
+func TestPostingsBalanced(t *testing.T) {
+	db := migratedDB(t)
+	tx, err := db.db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var jID int
+	require.NoError(t, tx.QueryRow(`INSERT INTO journals (type) VALUES ('fund') RETURNING id`).Scan(&jID))
+	_, err = tx.Exec(`INSERT INTO postings (journal_id, account, amount) VALUES ($1, 'house', -100), ($1, 'user:1', 90)`, jID)
+	require.NoError(t, err, "the check is deferred to the commit")
+	assert.Error(t, tx.Commit(), "an unbalanced journal is rejected")
+	assert.Equal(t, 0, count(t, db, "postings"))
+
+	newUser(t, db, 100)
+	_, err = db.db.Exec(`UPDATE postings SET amount = 0`)
+	assert.Error(t, err, "postings are append-only")
+	_, err = db.db.Exec(`DELETE FROM postings`)
+	assert.Error(t, err, "postings are append-only")
+	requireBalanced(t, db)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// grantBonus pays the signup bonus of p to a new user, in bonus points with
+// the wagering requirement of p, or else in cash.
+func grantBonus(tx *sql.Tx, uID int, p entity.Policy) error {
+	if p.Wagering == 0 {
+		return transfer(tx, entity.HouseAccount, entity.UserAccount(uID), p.Bonus, entity.TxBonus, 0)
+	}
+	err := transfer(tx, entity.HouseAccount, entity.BonusAccount(uID), p.Bonus, entity.TxBonus, 0)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE users
+		SET wagering = $1
+		WHERE id = $2`, p.Bonus*p.Wagering, uID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't set the wagering requirement: %v", err))
+	}
+	return nil
+}
+
+// deposit charges a user amount for a tournament, in cash and bonus points
+// by the spend order of the policy in force, and returns the part paid in
+// bonus points.
+func deposit(tx *sql.Tx, tID, uID, amount int) (int, error) {
+	p, err := currentPolicy(tx)
+	if err != nil {
+		return 0, err
+	}
+	u, err := getUser(tx, uID)
+	if err != nil {
+		return 0, err
+	}
+	cash, bonus := p.Charge(u, amount)
+	err = transfer(tx, entity.UserAccount(uID), entity.EscrowAccount(tID), cash, entity.TxDeposit, tID)
+	if err != nil {
+		return 0, err
+	}
+	err = transfer(tx, entity.BonusAccount(uID), entity.EscrowAccount(tID), bonus, entity.TxDeposit, tID)
+	if err != nil {
+		return 0, err
+	}
+	return bonus, nil
+}
+
+// wager counts a deposit of amount toward the user's wagering requirement.
+// The bonus points convert to cash once it is met.
+func wager(tx *sql.Tx, tID, uID, amount int) error {
+	var wagering, bonus int
+	err := tx.QueryRow(`
+		UPDATE users
+		SET wagering = GREATEST(wagering - $1, 0)
+		WHERE id = $2 AND wagering > 0
+		RETURNING wagering, bonus`, amount, uID).Scan(&wagering, &bonus)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return entity.DBErr(fmt.Errorf("can't count the wager: %v", err))
+	}
+	if wagering != 0 {
+		return nil
+	}
+	return transfer(tx, entity.BonusAccount(uID), entity.UserAccount(uID), bonus, entity.TxConvert, tID)
+}
+
+// payPrize pays a payer of an entry their part of a prize in cash. While
+// their wagering requirement is unmet, the part won with bonus points is paid
+// in bonus points.
+func payPrize(tx *sql.Tx, tID int, m entity.Member, amount int) error {
+	u, err := getUser(tx, m.UserID)
+	if err != nil {
+		return err
+	}
+	bonus := 0
+	if u.Wagering > 0 {
+		bonus = m.BonusPart(amount)
+	}
+	err = transfer(tx, entity.EscrowAccount(tID), entity.UserAccount(m.UserID), amount-bonus, entity.TxPrize, tID)
+	if err != nil {
+		return err
+	}
+	return transfer(tx, entity.EscrowAccount(tID), entity.BonusAccount(m.UserID), bonus, entity.TxPrize, tID)
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestCreateUserBonus(t *testing.T) {
+	db := migratedDB(t)
+	cash, err := db.SetPolicy(entity.Policy{Fee: 100, Bonus: 50, SpendOrder: entity.CashFirst})
+	require.NoError(t, err)
+	wagered, err := db.SetPolicy(entity.Policy{Fee: 100, Bonus: 50, Wagering: 3, SpendOrder: entity.CashFirst})
+	require.NoError(t, err)
+
+	u, err := db.CreateUser(entity.User{Name: "cash", Balance: 100, PolicyID: cash.ID}, cash)
+	require.NoError(t, err)
+	assert.Equal(t, 50, u.Balance)
+	assert.Equal(t, 0, u.Bonus)
+
+	u, err = db.CreateUser(entity.User{Name: "bonus", Balance: 100, PolicyID: wagered.ID}, wagered)
+	require.NoError(t, err)
+	assert.Equal(t, 0, u.Balance)
+	assert.Equal(t, 50, u.Bonus)
+	assert.Equal(t, 150, u.Wagering)
+	_, err = db.CreateUser(entity.User{Name: "poor", Balance: 99, PolicyID: wagered.ID}, wagered)
+	assert.Error(t, err, "bonus points don't pay the fee")
+	requireBalanced(t, db)
+}
+
+func TestBonusPoints(t *testing.T) {
+	db := migratedDB(t)
+	p, err := db.SetPolicy(entity.Policy{Fee: 100, Bonus: 200, MinBalance: 100, Wagering: 2, SpendOrder: entity.BonusFirst})
+	require.NoError(t, err)
+	var users []entity.User
+	for i := 0; i < 2; i++ {
+		u, err := db.CreateUser(entity.User{Name: "user", Balance: 150, PolicyID: p.ID}, p)
+		require.NoError(t, err)
+		users = append(users, u)
+	}
+	u1, u2 := users[0], users[1]
+	tr := newTourn(t, db, entity.Tournament{Deposit: 150})
+
+	join(t, db, tr.ID, u1.ID)
+	u, err := db.GetUser(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50, u.Balance)
+	assert.Equal(t, 50, u.Bonus, "bonus points are spent first")
+
+	_, err = db.LeaveTourn(tr.ID, u1.ID)
+	require.NoError(t, err)
+	u, err = db.GetUser(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 200, u.Bonus, "bonus points are refunded as bonus points")
+
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u1.ID)))
+
+	u, err = db.GetUser(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50, u.Balance, "a prize won with bonus points isn't cash")
+	assert.Equal(t, 350, u.Bonus)
+	assert.Equal(t, 250, u.Wagering)
+	requireBalanced(t, db)
+
+	_, err = db.SetPolicy(entity.Policy{Fee: 100, Bonus: 200, MinBalance: 100, Wagering: 2, SpendOrder: entity.CashFirst})
+	require.NoError(t, err)
+	tr = newTourn(t, db, entity.Tournament{Deposit: 300})
+	join(t, db, tr.ID, u1.ID)
+	u, err = db.GetUser(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, u.Balance, "cash is spent first")
+	assert.Equal(t, 100, u.Bonus)
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u1.ID)))
+
+	u, err = db.GetUser(u1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, u.Wagering)
+	assert.Equal(t, 0, u.Bonus, "the bonus converts once the requirement is met")
+	assert.Equal(t, 400, u.Balance)
+	converted := transactions(t, db, entity.TransactionFilter{UserID: u1.ID, Types: []entity.TransactionType{entity.TxConvert}})
+	require.Len(t, converted, 2, "out of the bonus account and into the cash")
+	requireBalanced(t, db)
+
+	u, err = db.CreateUser(entity.User{Name: "user", Balance: 150, PolicyID: p.ID}, p)
+	require.NoError(t, err)
+	require.NoError(t, db.DelUser(u.ID), "bonus points go back to the house")
+	requireBalanced(t, db)
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+const inviteColumns = `id, tournament_id, COALESCE(code, ''), COALESCE(user_id, 0), max_uses, uses, revoked, created_at`
+
+func scanInvite(row interface{ Scan(...interface{}) error }) (entity.Invite, error) {
+	var inv entity.Invite
+	err := row.Scan(&inv.ID, &inv.TournamentID, &inv.Code, &inv.UserID, &inv.MaxUses, &inv.Uses, &inv.Revoked, &inv.CreatedAt)
+	return inv, err
+}
+
+func (db DB) CreateInvite(inv entity.Invite) (entity.Invite, error) {
+	code := sql.NullString{String: inv.Code, Valid: inv.Code != ""}
+	inv, err := scanInvite(db.db.QueryRow(`
+		INSERT INTO invites (tournament_id, code, user_id, max_uses)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING `+inviteColumns, inv.TournamentID, code, nullID(inv.UserID), inv.MaxUses))
+	if err == sql.ErrNoRows && code.Valid {
+		return inv, entity.RegErr(errors.New("the invite code is taken"))
+	} else if err == sql.ErrNoRows {
+		return inv, entity.RegErr(errors.New("user is already invited"))
+	} else if err != nil {
+		return inv, entity.DBErr(fmt.Errorf("can't create invite: %v", err))
+	}
+	return inv, nil
+}
+
+func (db DB) ListInvites(tID int) ([]entity.Invite, error) {
+	rows, err := db.db.Query(`
+		SELECT `+inviteColumns+`
+		FROM invites
+		WHERE tournament_id = $1
+		ORDER BY id`, tID)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get invites: %v", err))
+	}
+	defer rows.Close()
+	invites := []entity.Invite{}
+	for rows.Next() {
+		inv, err := scanInvite(rows)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get invites: %v", err))
+		}
+		invites = append(invites, inv)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return invites, nil
+}
+
+func (db DB) RevokeInvite(tID, id int) (entity.Invite, error) {
+	inv, err := scanInvite(db.db.QueryRow(`
+		UPDATE invites
+		SET revoked = TRUE
+		WHERE tournament_id = $1 AND id = $2
+		RETURNING `+inviteColumns, tID, id))
+	if err == sql.ErrNoRows {
+		return inv, entity.ReqErr(errors.New("invite doesn't exist"))
+	} else if err != nil {
+		return inv, entity.DBErr(fmt.Errorf("can't revoke invite: %v", err))
+	}
+	return inv, nil
+}
+
+// findInvite returns the invite a join with code uses, or nil if there is
+// none. Without a code it is the user's own invite. A revoked invite is
+// only returned if there is no other.
+func findInvite(tx *sql.Tx, tID, uID int, code string) (*entity.Invite, error) {
+	where, arg := "user_id = $2", interface{}(uID)
+	if code != "" {
+		where, arg = "code = $2", code
+	}
+	inv, err := scanInvite(tx.QueryRow(`
+		SELECT `+inviteColumns+`
+		FROM invites
+		WHERE tournament_id = $1 AND `+where+`
+		ORDER BY revoked, id DESC
+		LIMIT 1`, tID, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get invite: %v", err))
+	}
+	return &inv, nil
+}
+
+// useInvite counts a join the invite has let in.
+func useInvite(tx *sql.Tx, inv *entity.Invite) error {
+	if inv == nil {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE invites
+		SET uses = uses + 1
+		WHERE id = $1`, inv.ID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't use invite: %v", err))
+	}
+	return nil
+}
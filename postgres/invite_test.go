@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestInvites(t *testing.T) {
+	db := migratedDB(t)
+	u1, u2, u3 := newUser(t, db, 700), newUser(t, db, 700), newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Private: true, MaxPlayers: 1})
+	code, err := db.CreateInvite(entity.Invite{TournamentID: tr.ID, Code: "vip", MaxUses: 2})
+	require.NoError(t, err)
+	assert.False(t, code.CreatedAt.IsZero())
+	_, err = db.CreateInvite(entity.Invite{TournamentID: tr.ID, Code: "vip"})
+	assert.Error(t, err, "the invite code is taken")
+	personal, err := db.CreateInvite(entity.Invite{TournamentID: tr.ID, UserID: u3.ID})
+	require.NoError(t, err)
+	_, err = db.CreateInvite(entity.Invite{TournamentID: tr.ID, UserID: u3.ID})
+	assert.Error(t, err, "user is already invited")
+	_, err = db.CreateInvite(entity.Invite{TournamentID: 42, Code: "vip"})
+	assert.Error(t, err)
+
+	var got []*entity.Invite
+	check := func(u entity.User, tourn entity.Tournament, inv *entity.Invite) error {
+		got = append(got, inv)
+		return tourn.Admit(inv)
+	}
+	_, err = db.JoinTourn(tr.ID, u1.ID, "", check, first)
+	assert.Error(t, err, "u1 has no invite of their own")
+	_, err = db.JoinTourn(tr.ID, u1.ID, "vip", check, first)
+	require.NoError(t, err)
+	_, err = db.JoinTourn(tr.ID, u2.ID, "vip", check, first)
+	require.NoError(t, err, "the waitlist uses the invite too")
+	_, err = db.JoinTourn(tr.ID, u3.ID, "", check, first)
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+	assert.Nil(t, got[0])
+	assert.Equal(t, 0, got[1].Uses)
+	assert.Equal(t, 1, got[2].Uses)
+	assert.Equal(t, u3.ID, got[3].UserID)
+
+	revoked, err := db.RevokeInvite(tr.ID, personal.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked.Revoked)
+	_, err = db.RevokeInvite(tr.ID, 42)
+	assert.Error(t, err)
+	invites, err := db.ListInvites(tr.ID)
+	require.NoError(t, err)
+	require.Len(t, invites, 2)
+	assert.Equal(t, 2, invites[0].Uses)
+	assert.Equal(t, 1, invites[1].Uses)
+	assert.True(t, invites[1].Revoked)
+
+	u4 := newUser(t, db, 700)
+	old, err := db.CreateInvite(entity.Invite{TournamentID: tr.ID, UserID: u4.ID})
+	require.NoError(t, err)
+	_, err = db.RevokeInvite(tr.ID, old.ID)
+	require.NoError(t, err)
+	_, err = db.CreateInvite(entity.Invite{TournamentID: tr.ID, UserID: u4.ID})
+	require.NoError(t, err, "a revoked invite doesn't count")
+	_, err = db.JoinTourn(tr.ID, u4.ID, "", func(u entity.User, tourn entity.Tournament, inv *entity.Invite) error {
+		require.NotNil(t, inv)
+		assert.False(t, inv.Revoked, "the live invite is found first")
+		return nil
+	}, first)
+	require.NoError(t, err)
+}
+
+func TestPrivateTournsAreNotListed(t *testing.T) {
+	db := migratedDB(t)
+	newTourn(t, db, entity.Tournament{Deposit: 100, Private: true})
+	public := newTourn(t, db, entity.Tournament{Deposit: 100})
+	p, err := db.ListTourns(entity.TournamentFilter{Page: entity.Page{Limit: 10}})
+	require.NoError(t, err)
+	require.Len(t, p.Tournaments, 1)
+	assert.Equal(t, public.ID, p.Tournaments[0].ID)
+	assert.Equal(t, 1, p.Total)
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// querier is either the database or a transaction.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (db DB) GetMatches(tID int) ([]entity.Match, error) {
+	return getMatches(db.db, tID)
+}
+
+func getMatches(q querier, tID int) ([]entity.Match, error) {
+	rows, err := q.Query(`
+		SELECT round, slot, COALESCE(player1, 0), COALESCE(player2, 0), COALESCE(winner, 0), bye, draw
+		FROM matches
+		WHERE tournament_id = $1
+		ORDER BY round, slot`, tID)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get matches: %v", err))
+	}
+	defer rows.Close()
+	matches := []entity.Match{}
+	for rows.Next() {
+		var m entity.Match
+		err := rows.Scan(&m.Round, &m.Slot, &m.Player1, &m.Player2, &m.Winner, &m.Bye, &m.Draw)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get matches: %v", err))
+		}
+		matches = append(matches, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return matches, nil
+}
+
+// saveMatches stores new matches and updates the ones that exist.
+func saveMatches(tx *sql.Tx, tID int, matches []entity.Match) error {
+	for _, m := range matches {
+		_, err := tx.Exec(`
+			INSERT INTO matches (tournament_id, round, slot, player1, player2, winner, bye, draw)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (tournament_id, round, slot) DO UPDATE
+			SET player1 = EXCLUDED.player1, player2 = EXCLUDED.player2, winner = EXCLUDED.winner,
+				bye = EXCLUDED.bye, draw = EXCLUDED.draw`,
+			tID, m.Round, m.Slot, nullID(m.Player1), nullID(m.Player2), nullID(m.Winner), m.Bye, m.Draw)
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("can't save match %d/%d: %v", m.Round, m.Slot, err))
+		}
+	}
+	return nil
+}
+
+// ReportMatch records a result in the matches of a running tournament. The
+// tournament stays locked while report works, so results of the same
+// tournament are recorded one by one.
+func (db DB) ReportMatch(tID int, report func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error)) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Require(entity.Running)
+	if err != nil {
+		return err
+	}
+	entries, err := getEntries(tx, tID)
+	if err != nil {
+		return err
+	}
+	matches, err := getMatches(tx, tID)
+	if err != nil {
+		return err
+	}
+	changed, ratings, err := report(entries, matches)
+	if err != nil {
+		return err
+	}
+	err = saveMatches(tx, tID, changed)
+	if err != nil {
+		return err
+	}
+	err = changeRatings(tx, tID, ratings)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// report returns a report that changes the matches to changed.
+func report(changed ...entity.Match) func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+	return func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		return changed, nil, nil
+	}
+}
+
+func TestReportMatch(t *testing.T) {
+	db := migratedDB(t)
+	u1 := newUser(t, db, 700)
+	u2 := newUser(t, db, 700)
+	u3 := newUser(t, db, 700)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100, Format: entity.FormatBracket, Selector: entity.SelectExternal})
+	join(t, db, tr.ID, u1.ID)
+	join(t, db, tr.ID, u2.ID)
+	join(t, db, tr.ID, u3.ID)
+	assert.Error(t, db.ReportMatch(tr.ID, report()), "the tournament isn't running")
+
+	seeded := []entity.Match{
+		{Round: 1, Slot: 0, Player1: u1.ID, Winner: u1.ID, Bye: true},
+		{Round: 1, Slot: 1, Player1: u2.ID, Player2: u3.ID},
+		{Round: 2, Slot: 0, Player1: u1.ID},
+	}
+	require.NoError(t, db.StartTourn(tr.ID, func(tourn entity.Tournament, entries []entity.Entry) ([]entity.Match, error) {
+		assert.Len(t, entries, 3)
+		return seeded, nil
+	}))
+	tourn, err := db.GetTourn(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, entity.Running, tourn.Status)
+
+	assert.Error(t, db.ReportMatch(tr.ID, func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		return nil, nil, errors.New("no")
+	}))
+	matches, err := db.GetMatches(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, seeded, matches)
+
+	require.NoError(t, db.ReportMatch(tr.ID, func(entries []entity.Entry, matches []entity.Match) ([]entity.Match, []entity.RatingChange, error) {
+		assert.Equal(t, seeded, matches)
+		return []entity.Match{
+			{Round: 3, Slot: 0, Player1: u2.ID, Winner: u2.ID, Bye: true},
+			{Round: 1, Slot: 1, Player1: u2.ID, Player2: u3.ID, Winner: u3.ID},
+			{Round: 2, Slot: 0, Player1: u1.ID, Player2: u3.ID},
+		}, nil, nil
+	}))
+	matches, err = db.GetMatches(tr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Match{
+		{Round: 1, Slot: 0, Player1: u1.ID, Winner: u1.ID, Bye: true},
+		{Round: 1, Slot: 1, Player1: u2.ID, Player2: u3.ID, Winner: u3.ID},
+		{Round: 2, Slot: 0, Player1: u1.ID, Player2: u3.ID},
+		{Round: 3, Slot: 0, Player1: u2.ID, Winner: u2.ID, Bye: true},
+	}, matches, "new matches are added in their place")
+}
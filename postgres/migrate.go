@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// migrationLock is the key of the advisory lock that keeps several
+// instances from migrating the same database at once.
+const migrationLock = 7295013
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+type appliedMigration struct {
+	version int
+	dirty   bool
+}
+
+// Migrate applies every migration that is not applied yet.
+func (db DB) Migrate() error {
+	return db.withMigrationLock(func(conn *sql.Conn) error {
+		applied, err := checkSchema(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations[len(applied):] {
+			err = up(conn, m)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateDown reverts the last n applied migrations.
+func (db DB) MigrateDown(n int) error {
+	if n <= 0 {
+		return entity.ReqErr(errors.New("number of migrations must be greater than 0"))
+	}
+	return db.withMigrationLock(func(conn *sql.Conn) error {
+		applied, err := checkSchema(conn)
+		if err != nil {
+			return err
+		}
+		if n > len(applied) {
+			n = len(applied)
+		}
+		for i := len(applied) - 1; i >= len(applied)-n; i-- {
+			err = down(conn, migrations[i])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrationVersion returns the latest applied migration and whether it failed
+// half way.
+func (db DB) MigrationVersion() (int, bool, error) {
+	var version int
+	var dirty bool
+	err := db.withMigrationLock(func(conn *sql.Conn) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return nil
+		}
+		last := applied[len(applied)-1]
+		version, dirty = last.version, last.dirty
+		return nil
+	})
+	return version, dirty, err
+}
+
+// ForceVersion marks migrations up to version as cleanly applied and the rest
+// as not applied without running any of them. It is the way out of a dirty
+// schema once it has been repaired by hand.
+func (db DB) ForceVersion(version int) error {
+	if version < 0 || version > len(migrations) {
+		return entity.ReqErr(fmt.Errorf("unknown migration version %d", version))
+	}
+	return db.withMigrationLock(func(conn *sql.Conn) error {
+		tx, err := conn.BeginTx(context.Background(), nil)
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+		}
+		defer tx.Rollback()
+		_, err = tx.Exec(`DELETE FROM schema_migrations`)
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("can't reset schema_migrations: %v", err))
+		}
+		for _, m := range migrations[:version] {
+			_, err = tx.Exec(`
+				INSERT INTO schema_migrations (version, name, dirty)
+				VALUES ($1, $2, FALSE)`, m.version, m.name)
+			if err != nil {
+				return entity.DBErr(fmt.Errorf("can't force version %d: %v", m.version, err))
+			}
+		}
+		err = tx.Commit()
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+		}
+		return nil
+	})
+}
+
+func (db DB) withMigrationLock(f func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't get a connection: %v", err))
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLock)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't lock migrations: %v", err))
+	}
+	defer func() {
+		_, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLock)
+		if err != nil {
+			logrus.WithError(err).Error("can't unlock migrations")
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name TEXT NOT NULL,
+		dirty BOOLEAN NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now())`)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("table 'schema_migrations' failed: %v", err))
+	}
+	return f(conn)
+}
+
+func appliedMigrations(conn *sql.Conn) ([]appliedMigration, error) {
+	rows, err := conn.QueryContext(context.Background(), `
+		SELECT version, dirty
+		FROM schema_migrations
+		ORDER BY version`)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get applied migrations: %v", err))
+	}
+	defer rows.Close()
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		err := rows.Scan(&a.version, &a.dirty)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get applied migrations: %v", err))
+		}
+		applied = append(applied, a)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return applied, nil
+}
+
+// checkSchema returns the applied migrations if they are exactly the first
+// known migrations, in order, and none of them is dirty.
+func checkSchema(conn *sql.Conn) ([]appliedMigration, error) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			return nil, entity.DBErr(fmt.Errorf("migration %q has version %d, expected %d", m.name, m.version, i+1))
+		}
+	}
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range applied {
+		if a.dirty {
+			return nil, entity.DBErr(fmt.Errorf("schema is dirty at version %d, repair it and force the version", a.version))
+		}
+		if i >= len(migrations) {
+			return nil, entity.DBErr(fmt.Errorf("schema version %d is newer than the latest known migration %d", a.version, len(migrations)))
+		}
+		if a.version != migrations[i].version {
+			return nil, entity.DBErr(fmt.Errorf("schema is out of order: migration %d is applied but %d is not", a.version, migrations[i].version))
+		}
+	}
+	return applied, nil
+}
+
+func up(conn *sql.Conn, m migration) error {
+	ctx := context.Background()
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, dirty)
+		VALUES ($1, $2, TRUE)`, m.version, m.name)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't start migration %d: %v", m.version, err))
+	}
+	err = inTx(conn, m.up, `
+		UPDATE schema_migrations
+		SET dirty = FALSE, applied_at = now()
+		WHERE version = $1`, m.version)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("migration %d %q failed: %v", m.version, m.name, err))
+	}
+	logrus.WithFields(logrus.Fields{"version": m.version, "name": m.name}).Info("migration applied")
+	return nil
+}
+
+func down(conn *sql.Conn, m migration) error {
+	ctx := context.Background()
+	_, err := conn.ExecContext(ctx, `
+		UPDATE schema_migrations
+		SET dirty = TRUE
+		WHERE version = $1`, m.version)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't start reverting migration %d: %v", m.version, err))
+	}
+	err = inTx(conn, m.down, `
+		DELETE FROM schema_migrations
+		WHERE version = $1`, m.version)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("reverting migration %d %q failed: %v", m.version, m.name, err))
+	}
+	logrus.WithFields(logrus.Fields{"version": m.version, "name": m.name}).Info("migration reverted")
+	return nil
+}
+
+// inTx runs the migration script and the bookkeeping query in one
+// transaction. A failed script leaves the version marked dirty.
+func inTx(conn *sql.Conn, script string, bookkeeping string, version int) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(script)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(bookkeeping, version)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDB connects to the scratch database in TEST_CONN and wipes it. Tests
+// that need a database are skipped without one.
+func testDB(t *testing.T) DB {
+	t.Helper()
+	connStr, ok := os.LookupEnv("TEST_CONN")
+	if !ok {
+		t.Skip("TEST_CONN is not set")
+	}
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	_, err = db.Exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public`)
+	require.NoError(t, err)
+	return DB{db: db}
+}
+
+// migratedDB returns a wiped scratch database with the latest schema.
+func migratedDB(t *testing.T) DB {
+	t.Helper()
+	db := testDB(t)
+	require.NoError(t, db.Migrate())
+	return db
+}
+
+func requireVersion(t *testing.T, db DB, version int, dirty bool) {
+	t.Helper()
+	v, d, err := db.MigrationVersion()
+	require.NoError(t, err)
+	require.Equal(t, version, v)
+	require.Equal(t, dirty, d)
+}
+
+func TestMigrate(t *testing.T) {
+	db := testDB(t)
+	requireVersion(t, db, 0, false)
+
+	require.NoError(t, db.Migrate())
+	requireVersion(t, db, len(migrations), false)
+	require.NoError(t, db.Migrate(), "migrating twice changes nothing")
+	requireVersion(t, db, len(migrations), false)
+
+	require.NoError(t, db.MigrateDown(len(migrations)+1))
+	requireVersion(t, db, 0, false)
+	_, err := db.db.Exec(`SELECT 1 FROM users`)
+	assert.Error(t, err, "reverting every migration drops the tables")
+	assert.Error(t, db.MigrateDown(0))
+
+	require.NoError(t, db.Migrate())
+	requireVersion(t, db, len(migrations), false)
+}
+
+func TestMigrateDirty(t *testing.T) {
+	db := migratedDB(t)
+	latest := len(migrations)
+	defer func(ms []migration) {
+		migrations = ms
+	}(migrations)
+	migrations = append(migrations[:latest:latest], migration{
+		version: latest + 1,
+		name:    "broken",
+		up:      `CREATE TABLE broken (id INT); SELEC 1`,
+		down:    `DROP TABLE broken`,
+	})
+
+	assert.Error(t, db.Migrate())
+	requireVersion(t, db, latest+1, true)
+	_, err := db.db.Exec(`SELECT 1 FROM broken`)
+	assert.Error(t, err, "a failed migration is rolled back")
+	assert.Error(t, db.Migrate(), "a dirty schema isn't migrated")
+	assert.Error(t, db.MigrateDown(1))
+
+	require.NoError(t, db.ForceVersion(latest))
+	requireVersion(t, db, latest, false)
+	assert.Error(t, db.ForceVersion(latest+2))
+}
+
+func TestMigrateUnknownVersion(t *testing.T) {
+	db := migratedDB(t)
+	_, err := db.db.Exec(`
+		INSERT INTO schema_migrations (version, name, dirty)
+		VALUES ($1, 'newer', FALSE)`, len(migrations)+1)
+	require.NoError(t, err)
+	assert.Error(t, db.Migrate(), "the schema is newer than the code")
+
+	_, err = db.db.Exec(`DELETE FROM schema_migrations WHERE version = 1`)
+	require.NoError(t, err)
+	assert.Error(t, db.Migrate(), "the schema is out of order")
+}
+
+func TestMigrationLock(t *testing.T) {
+	db := testDB(t)
+	conn, err := db.db.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.ExecContext(context.Background(), `SELECT pg_advisory_lock($1)`, migrationLock)
+	require.NoError(t, err)
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- db.Migrate()
+		}()
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("migrated while another instance held the lock: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	_, err = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLock)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	requireVersion(t, db, len(migrations), false)
+}
+
+func TestMigrateActiveTourns(t *testing.T) {
+	db := testDB(t)
+	all := migrations
+	defer func() {
+		migrations = all
+	}()
+	migrations = all[:4]
+	require.NoError(t, db.Migrate())
+	_, err := db.db.Exec(`
+		INSERT INTO tournaments (name, deposit, status)
+		VALUES ('active', 100, 'active'), ('finished', 100, 'finished')`)
+	require.NoError(t, err)
+
+	migrations = all
+	require.NoError(t, db.Migrate())
+	var statuses []string
+	rows, err := db.db.Query(`SELECT status FROM tournaments ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var s string
+		require.NoError(t, rows.Scan(&s))
+		statuses = append(statuses, s)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"running", "finished"}, statuses, "active tournaments may have players already")
+}
@@ -0,0 +1,538 @@
+package postgres
+
+// migrations is the ordered list of schema changes. Versions must be
+// consecutive and a released migration must never be edited, add a new one
+// instead.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_tables",
+		up: `
+		CREATE TABLE IF NOT EXISTS users(
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		balance INT NOT NULL CHECK(balance>=0));
+
+		CREATE TABLE IF NOT EXISTS tournaments (
+		id serial PRIMARY KEY,
+		name TEXT NOT NULL,
+		deposit INT NOT NULL CHECK(deposit>=0),
+		prize INT NOT NULL CHECK(prize>=0) DEFAULT 0,
+		finished BOOLEAN NOT NULL DEFAULT FALSE,
+		winner_id INT);
+
+		CREATE TABLE IF NOT EXISTS tournament_req (
+		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE RESTRICT,
+		user_id INT NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+		PRIMARY KEY (tournament_id, user_id) );`,
+		down: `
+		DROP TABLE tournament_req;
+		DROP TABLE tournaments;
+		DROP TABLE users;`,
+	},
+	{
+		version: 2,
+		name:    "create_transactions",
+		up: `
+		CREATE TABLE transactions (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL,
+		type TEXT NOT NULL,
+		amount INT NOT NULL,
+		balance INT NOT NULL,
+		tournament_id INT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+
+		CREATE INDEX transactions_user_id_idx ON transactions (user_id, id);
+
+		CREATE FUNCTION transactions_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'transactions are append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER transactions_append_only
+		BEFORE UPDATE OR DELETE ON transactions
+		FOR EACH ROW EXECUTE PROCEDURE transactions_append_only();`,
+		down: `
+		DROP TABLE transactions;
+		DROP FUNCTION transactions_append_only();`,
+	},
+	{
+		version: 3,
+		name:    "create_double_entry_ledger",
+		up: `
+		CREATE TABLE journals (
+		id SERIAL PRIMARY KEY,
+		type TEXT NOT NULL,
+		tournament_id INT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+
+		CREATE TABLE postings (
+		id SERIAL PRIMARY KEY,
+		journal_id INT NOT NULL REFERENCES journals (id) ON DELETE RESTRICT,
+		account TEXT NOT NULL,
+		amount INT NOT NULL);
+
+		CREATE INDEX postings_account_idx ON postings (account);
+
+		CREATE FUNCTION postings_balanced() RETURNS trigger AS $$
+		BEGIN
+			IF (SELECT SUM(amount) FROM postings WHERE journal_id = NEW.journal_id) <> 0 THEN
+				RAISE EXCEPTION 'journal % is not balanced', NEW.journal_id;
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE CONSTRAINT TRIGGER postings_balanced
+		AFTER INSERT ON postings
+		DEFERRABLE INITIALLY DEFERRED
+		FOR EACH ROW EXECUTE PROCEDURE postings_balanced();
+
+		CREATE FUNCTION postings_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'postings are append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		CREATE TRIGGER postings_append_only
+		BEFORE UPDATE OR DELETE ON postings
+		FOR EACH ROW EXECUTE PROCEDURE postings_append_only();
+
+		WITH opening AS (
+			INSERT INTO journals (type)
+			VALUES ('opening')
+			RETURNING id)
+		INSERT INTO postings (journal_id, account, amount)
+		SELECT opening.id, 'user:' || users.id, users.balance
+		FROM opening, users
+		WHERE users.balance <> 0
+		UNION ALL
+		SELECT opening.id, 'escrow:' || tournaments.id, tournaments.prize
+		FROM opening, tournaments
+		WHERE NOT tournaments.finished AND tournaments.prize <> 0
+		UNION ALL
+		SELECT opening.id, 'house',
+			-(SELECT COALESCE(SUM(balance), 0) FROM users)
+			-(SELECT COALESCE(SUM(prize), 0) FROM tournaments WHERE NOT finished)
+		FROM opening;`,
+		down: `
+		DROP TABLE postings;
+		DROP TABLE journals;
+		DROP FUNCTION postings_balanced();
+		DROP FUNCTION postings_append_only();`,
+	},
+	{
+		version: 4,
+		name:    "add_tournament_status",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN status TEXT NOT NULL DEFAULT 'active'
+		CHECK (status IN ('active', 'finished', 'cancelled'));
+
+		UPDATE tournaments
+		SET status = 'finished'
+		WHERE finished;
+
+		ALTER TABLE tournaments
+		DROP COLUMN finished;`,
+		down: `
+		ALTER TABLE tournaments
+		ADD COLUMN finished BOOLEAN NOT NULL DEFAULT FALSE;
+
+		UPDATE tournaments
+		SET finished = status <> 'active';
+
+		ALTER TABLE tournaments
+		DROP COLUMN status;`,
+	},
+	{
+		version: 5,
+		name:    "add_tournament_lifecycle",
+		up: `
+		ALTER TABLE tournaments
+		DROP CONSTRAINT tournaments_status_check;
+
+		UPDATE tournaments
+		SET status = 'running'
+		WHERE status = 'active';
+
+		ALTER TABLE tournaments
+		ALTER COLUMN status SET DEFAULT 'draft',
+		ADD CONSTRAINT tournaments_status_check
+		CHECK (status IN ('draft', 'open', 'running', 'finished', 'cancelled'));`,
+		down: `
+		ALTER TABLE tournaments
+		DROP CONSTRAINT tournaments_status_check;
+
+		UPDATE tournaments
+		SET status = 'active'
+		WHERE status IN ('draft', 'open', 'running');
+
+		ALTER TABLE tournaments
+		ALTER COLUMN status SET DEFAULT 'active',
+		ADD CONSTRAINT tournaments_status_check
+		CHECK (status IN ('active', 'finished', 'cancelled'));`,
+	},
+	{
+		version: 6,
+		name:    "add_winner_selectors",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN selector TEXT NOT NULL DEFAULT 'uniform';
+
+		ALTER TABLE tournament_req
+		ADD COLUMN seq SERIAL,
+		ADD COLUMN stake INT NOT NULL DEFAULT 0 CHECK(stake>=0);
+
+		UPDATE tournament_req
+		SET stake = tournaments.deposit
+		FROM tournaments
+		WHERE tournaments.id = tournament_req.tournament_id;`,
+		down: `
+		ALTER TABLE tournament_req
+		DROP COLUMN seq,
+		DROP COLUMN stake;
+
+		ALTER TABLE tournaments
+		DROP COLUMN selector;`,
+	},
+	{
+		version: 7,
+		name:    "add_tournament_seeds",
+		// Tournaments created before this migration get their seeds from the
+		// database's random(), new ones get them from crypto/rand.
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN seed TEXT NOT NULL DEFAULT '',
+		ADD COLUMN seed_hash TEXT NOT NULL DEFAULT '';
+
+		UPDATE tournaments
+		SET seed = md5(random()::text || clock_timestamp()::text || id::text);
+
+		UPDATE tournaments
+		SET seed_hash = encode(sha256(convert_to(seed, 'UTF8')), 'hex');`,
+		down: `
+		ALTER TABLE tournaments
+		DROP COLUMN seed,
+		DROP COLUMN seed_hash;`,
+	},
+	{
+		version: 8,
+		name:    "add_payout_tables",
+		// Winners of tournaments finished before this migration took the
+		// whole prize.
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN payouts JSONB NOT NULL DEFAULT '{"places": [100]}';
+
+		ALTER TABLE tournament_req
+		ADD COLUMN place INT,
+		ADD COLUMN payout INT NOT NULL DEFAULT 0 CHECK(payout>=0);
+
+		UPDATE tournament_req
+		SET place = 1, payout = tournaments.prize
+		FROM tournaments
+		WHERE tournaments.id = tournament_req.tournament_id
+			AND tournaments.winner_id = tournament_req.user_id
+			AND tournaments.status = 'finished';`,
+		down: `
+		ALTER TABLE tournament_req
+		DROP COLUMN place,
+		DROP COLUMN payout;
+
+		ALTER TABLE tournaments
+		DROP COLUMN payouts;`,
+	},
+	{
+		version: 9,
+		name:    "add_tournament_rake",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN rake_percent INT NOT NULL DEFAULT 0 CHECK(rake_percent>=0 AND rake_percent<100),
+		ADD COLUMN rake_fee INT NOT NULL DEFAULT 0 CHECK(rake_fee>=0),
+		ADD COLUMN rake INT NOT NULL DEFAULT 0 CHECK(rake>=0);
+
+		ALTER TABLE tournament_req
+		ADD COLUMN rake INT NOT NULL DEFAULT 0 CHECK(rake>=0);`,
+		down: `
+		ALTER TABLE tournament_req
+		DROP COLUMN rake;
+
+		ALTER TABLE tournaments
+		DROP COLUMN rake_percent,
+		DROP COLUMN rake_fee,
+		DROP COLUMN rake;`,
+	},
+	{
+		version: 10,
+		name:    "add_tournament_schedule",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN opens_at TIMESTAMPTZ,
+		ADD COLUMN starts_at TIMESTAMPTZ,
+		ADD COLUMN ends_at TIMESTAMPTZ;
+
+		CREATE INDEX tournaments_unfinished_idx ON tournaments (status)
+		WHERE status IN ('draft', 'open', 'running');`,
+		down: `
+		DROP INDEX tournaments_unfinished_idx;
+
+		ALTER TABLE tournaments
+		DROP COLUMN opens_at,
+		DROP COLUMN starts_at,
+		DROP COLUMN ends_at;`,
+	},
+	{
+		version: 11,
+		name:    "add_tournament_capacity",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN min_players INT NOT NULL DEFAULT 0 CHECK(min_players>=0),
+		ADD COLUMN max_players INT NOT NULL DEFAULT 0 CHECK(max_players>=0);
+
+		CREATE TABLE tournament_waitlist (
+		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE CASCADE,
+		user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		seq SERIAL,
+		PRIMARY KEY (tournament_id, user_id) );`,
+		down: `
+		DROP TABLE tournament_waitlist;
+
+		ALTER TABLE tournaments
+		DROP COLUMN min_players,
+		DROP COLUMN max_players;`,
+	},
+	{
+		version: 12,
+		name:    "add_tournament_formats",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN format TEXT NOT NULL DEFAULT 'standard';`,
+		down: `
+		ALTER TABLE tournaments
+		DROP COLUMN format;`,
+	},
+	{
+		version: 13,
+		name:    "add_tournament_scores",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN tie_break TEXT NOT NULL DEFAULT '';
+
+		ALTER TABLE tournament_req
+		ADD COLUMN score INT,
+		ADD COLUMN scored_at TIMESTAMPTZ;`,
+		down: `
+		ALTER TABLE tournament_req
+		DROP COLUMN score,
+		DROP COLUMN scored_at;
+
+		ALTER TABLE tournaments
+		DROP COLUMN tie_break;`,
+	},
+	{
+		version: 14,
+		name:    "create_matches",
+		up: `
+		CREATE TABLE matches (
+		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE CASCADE,
+		round INT NOT NULL,
+		slot INT NOT NULL,
+		player1 INT REFERENCES users (id) ON DELETE RESTRICT,
+		player2 INT REFERENCES users (id) ON DELETE RESTRICT,
+		winner INT REFERENCES users (id) ON DELETE RESTRICT,
+		bye BOOLEAN NOT NULL DEFAULT FALSE,
+		PRIMARY KEY (tournament_id, round, slot) );`,
+		down: `
+		DROP TABLE matches;`,
+	},
+	{
+		version: 15,
+		name:    "add_match_draws",
+		up: `
+		ALTER TABLE matches
+		ADD COLUMN draw BOOLEAN NOT NULL DEFAULT FALSE;`,
+		down: `
+		ALTER TABLE matches
+		DROP COLUMN draw;`,
+	},
+	{
+		version: 16,
+		name:    "add_swiss_rounds",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN rounds INT NOT NULL DEFAULT 0;`,
+		down: `
+		ALTER TABLE tournaments
+		DROP COLUMN rounds;`,
+	},
+	{
+		version: 17,
+		name:    "add_ratings",
+		up: `
+		ALTER TABLE users
+		ADD COLUMN rating INT NOT NULL DEFAULT 1500;
+
+		CREATE INDEX users_rating_idx ON users (rating, id);
+
+		ALTER TABLE tournaments
+		ADD COLUMN min_rating INT NOT NULL DEFAULT 0,
+		ADD COLUMN max_rating INT NOT NULL DEFAULT 0;
+
+		CREATE TABLE rating_changes (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL,
+		tournament_id INT NOT NULL,
+		change INT NOT NULL,
+		rating INT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+
+		CREATE INDEX rating_changes_user_id_idx ON rating_changes (user_id, id);`,
+		down: `
+		DROP TABLE rating_changes;
+
+		ALTER TABLE tournaments
+		DROP COLUMN min_rating,
+		DROP COLUMN max_rating;
+
+		ALTER TABLE users
+		DROP COLUMN rating;`,
+	},
+	{
+		version: 18,
+		name:    "create_teams",
+		up: `
+		CREATE TABLE teams (
+		id SERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		captain_id INT NOT NULL REFERENCES users (id) ON DELETE RESTRICT);
+
+		CREATE TABLE team_members (
+		team_id INT NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+		user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+		share INT NOT NULL CHECK(share>0),
+		seq SERIAL,
+		PRIMARY KEY (team_id, user_id) );
+
+		ALTER TABLE tournaments
+		ADD COLUMN team_size INT NOT NULL DEFAULT 0;
+
+		ALTER TABLE tournament_req
+		ADD COLUMN team_id INT REFERENCES teams (id) ON DELETE RESTRICT;
+
+		CREATE TABLE tournament_members (
+		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE RESTRICT,
+		user_id INT NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+		captain_id INT NOT NULL,
+		stake INT NOT NULL,
+		rake INT NOT NULL,
+		share INT NOT NULL,
+		seq SERIAL,
+		PRIMARY KEY (tournament_id, user_id) );`,
+		down: `
+		DROP TABLE tournament_members;
+
+		ALTER TABLE tournament_req
+		DROP COLUMN team_id;
+
+		ALTER TABLE tournaments
+		DROP COLUMN team_size;
+
+		DROP TABLE team_members;
+		DROP TABLE teams;`,
+	},
+	{
+		version: 19,
+		name:    "create_invites",
+		up: `
+		ALTER TABLE tournaments
+		ADD COLUMN private BOOLEAN NOT NULL DEFAULT FALSE;
+
+		CREATE TABLE invites (
+		id SERIAL PRIMARY KEY,
+		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE CASCADE,
+		code TEXT,
+		user_id INT REFERENCES users (id) ON DELETE CASCADE,
+		max_uses INT NOT NULL CHECK(max_uses>=0) DEFAULT 0,
+		uses INT NOT NULL DEFAULT 0,
+		revoked BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+		CHECK((code IS NULL) <> (user_id IS NULL)));
+
+		CREATE UNIQUE INDEX invites_code_idx ON invites (tournament_id, code) WHERE NOT revoked;
+		CREATE UNIQUE INDEX invites_user_id_idx ON invites (tournament_id, user_id) WHERE NOT revoked;`,
+		down: `
+		DROP TABLE invites;
+
+		ALTER TABLE tournaments
+		DROP COLUMN private;`,
+	},
+	{
+		version: 20,
+		name:    "create_policies",
+		up: `
+		CREATE TABLE policies (
+		id SERIAL PRIMARY KEY,
+		fee INT NOT NULL CHECK(fee>=0),
+		bonus INT NOT NULL CHECK(bonus>=0),
+		min_balance INT NOT NULL CHECK(min_balance>=0),
+		created_at TIMESTAMPTZ NOT NULL DEFAULT now());
+
+		INSERT INTO policies (fee, bonus, min_balance)
+		VALUES (300, 0, 300);
+
+		ALTER TABLE users
+		ADD COLUMN policy_id INT REFERENCES policies (id) ON DELETE RESTRICT;
+
+		UPDATE users
+		SET policy_id = (SELECT MIN(id) FROM policies);
+
+		ALTER TABLE users
+		ALTER COLUMN policy_id SET NOT NULL;`,
+		down: `
+		ALTER TABLE users
+		DROP COLUMN policy_id;
+
+		DROP TABLE policies;`,
+	},
+	{
+		version: 21,
+		name:    "add_bonus_balances",
+		up: `
+		ALTER TABLE policies
+		ADD COLUMN wagering INT NOT NULL DEFAULT 0 CHECK(wagering>=0),
+		ADD COLUMN spend_order TEXT NOT NULL DEFAULT 'cash_first';
+
+		ALTER TABLE users
+		ADD COLUMN bonus INT NOT NULL DEFAULT 0 CHECK(bonus>=0),
+		ADD COLUMN wagering INT NOT NULL DEFAULT 0 CHECK(wagering>=0);
+
+		ALTER TABLE transactions
+		ADD COLUMN bonus BOOLEAN NOT NULL DEFAULT false;
+
+		ALTER TABLE tournament_req
+		ADD COLUMN bonus_stake INT NOT NULL DEFAULT 0;
+
+		ALTER TABLE tournament_members
+		ADD COLUMN bonus_stake INT NOT NULL DEFAULT 0;`,
+		down: `
+		ALTER TABLE tournament_members
+		DROP COLUMN bonus_stake;
+
+		ALTER TABLE tournament_req
+		DROP COLUMN bonus_stake;
+
+		ALTER TABLE transactions
+		DROP COLUMN bonus;
+
+		ALTER TABLE users
+		DROP COLUMN bonus,
+		DROP COLUMN wagering;
+
+		ALTER TABLE policies
+		DROP COLUMN wagering,
+		DROP COLUMN spend_order;`,
+	},
+}
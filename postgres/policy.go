@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db DB) CurrentPolicy() (entity.Policy, error) {
+	return currentPolicy(db.db)
+}
+
+func currentPolicy(q querier) (entity.Policy, error) {
+	var p entity.Policy
+	err := q.QueryRow(`
+		SELECT id, fee, bonus, min_balance, wagering, spend_order, created_at
+		FROM policies
+		ORDER BY id DESC
+		LIMIT 1`).Scan(&p.ID, &p.Fee, &p.Bonus, &p.MinBalance, &p.Wagering, &p.SpendOrder, &p.CreatedAt)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't get the policy: %v", err))
+	}
+	return p, nil
+}
+
+func (db DB) GetPolicy(id int) (entity.Policy, error) {
+	p := entity.Policy{ID: id}
+	err := db.db.QueryRow(`
+		SELECT fee, bonus, min_balance, wagering, spend_order, created_at
+		FROM policies
+		WHERE id = $1`, id).Scan(&p.Fee, &p.Bonus, &p.MinBalance, &p.Wagering, &p.SpendOrder, &p.CreatedAt)
+	if err == sql.ErrNoRows {
+		return p, entity.ReqErr(errors.New("policy doesn't exist"))
+	} else if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't get the policy: %v", err))
+	}
+	return p, nil
+}
+
+func (db DB) SetPolicy(p entity.Policy) (entity.Policy, error) {
+	err := db.db.QueryRow(`
+		INSERT INTO policies (fee, bonus, min_balance, wagering, spend_order)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`, p.Fee, p.Bonus, p.MinBalance, p.Wagering, p.SpendOrder).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't set the policy: %v", err))
+	}
+	return p, nil
+}
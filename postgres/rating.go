@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// changeRatings applies the changes to the users' ratings and records them
+// within tx. Every change is for a participant of tournament tID.
+func changeRatings(tx *sql.Tx, tID int, changes []entity.RatingChange) error {
+	for _, c := range changes {
+		var rating int
+		err := tx.QueryRow(`
+			UPDATE users
+			SET rating = rating + $1
+			WHERE id = $2 AND EXISTS (
+				SELECT 1
+				FROM tournament_req
+				WHERE tournament_id = $3 AND user_id = $2)
+			RETURNING rating`, c.Change, c.UserID, tID).Scan(&rating)
+		if err == sql.ErrNoRows {
+			return entity.DBErr(fmt.Errorf("user %d rated in tournament %d isn't a participant", c.UserID, tID))
+		} else if err != nil {
+			return entity.DBErr(fmt.Errorf("can't update user's rating: %v", err))
+		}
+		_, err = tx.Exec(`
+			INSERT INTO rating_changes (user_id, tournament_id, change, rating)
+			VALUES ($1, $2, $3, $4)`, c.UserID, tID, c.Change, rating)
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("can't record rating change: %v", err))
+		}
+	}
+	return nil
+}
+
+func (db DB) RatingChanges(uID int) ([]entity.RatingChange, error) {
+	rows, err := db.db.Query(`
+		SELECT user_id, tournament_id, change, rating, created_at
+		FROM rating_changes
+		WHERE user_id = $1
+		ORDER BY id`, uID)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get rating changes: %v", err))
+	}
+	defer rows.Close()
+	changes := []entity.RatingChange{}
+	for rows.Next() {
+		var c entity.RatingChange
+		err := rows.Scan(&c.UserID, &c.TournamentID, &c.Change, &c.Rating, &c.CreatedAt)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get rating changes: %v", err))
+		}
+		changes = append(changes, c)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return changes, nil
+}
@@ -0,0 +1,363 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db DB) CreateTeam(t entity.Team) (entity.Team, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		SELECT id
+		FROM users
+		WHERE id = $1`, t.CaptainID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return t, entity.ReqErr(errors.New("the captain doesn't exist"))
+	} else if err != nil {
+		return t, entity.DBErr(err)
+	}
+	err = tx.QueryRow(`
+		INSERT INTO teams (name, captain_id)
+		VALUES ($1, $2)
+		RETURNING id`, t.Name, t.CaptainID).Scan(&t.ID)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't create team: %v", err))
+	}
+	for _, m := range t.Members {
+		_, err = tx.Exec(`
+			INSERT INTO team_members (team_id, user_id, share)
+			VALUES ($1, $2, $3)`, t.ID, m.UserID, m.Share)
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't add a team member: %v", err))
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return t, nil
+}
+
+func (db DB) GetTeam(id int) (entity.Team, error) {
+	if id <= 0 {
+		return entity.Team{}, entity.InvIDErr(errors.New("expected id greater than 0"))
+	}
+	return getTeam(db.db, id)
+}
+
+func getTeam(q querier, id int) (entity.Team, error) {
+	t := entity.Team{ID: id, Members: []entity.TeamMember{}}
+	err := q.QueryRow(`
+		SELECT name, captain_id
+		FROM teams
+		WHERE id = $1`, id).Scan(&t.Name, &t.CaptainID)
+	if err == sql.ErrNoRows {
+		return t, entity.ReqErr(errors.New("team doesn't exist"))
+	} else if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't get team: %v", err))
+	}
+
+	rows, err := q.Query(`
+		SELECT users.id, users.name, team_members.share
+		FROM team_members
+		INNER JOIN users ON team_members.user_id = users.id
+		WHERE team_members.team_id = $1
+		ORDER BY team_members.seq`, id)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't get team members: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m entity.TeamMember
+		err := rows.Scan(&m.UserID, &m.Name, &m.Share)
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't get team members: %v", err))
+		}
+		t.Members = append(t.Members, m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return t, nil
+}
+
+func (db DB) JoinTeam(teamID int, m entity.TeamMember) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	err = lockTeam(tx, teamID)
+	if err != nil {
+		return err
+	}
+	var id int
+	err = tx.QueryRow(`
+		SELECT id
+		FROM users
+		WHERE id = $1`, m.UserID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return entity.ReqErr(errors.New("the user doesn't exist"))
+	} else if err != nil {
+		return entity.DBErr(err)
+	}
+	res, err := tx.Exec(`
+		INSERT INTO team_members (team_id, user_id, share)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`, teamID, m.UserID, m.Share)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't add a team member: %v", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return entity.DBErr(err)
+	}
+	if n == 0 {
+		return entity.RegErr(fmt.Errorf("user is already a member of the team"))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
+
+func (db DB) SetTeamShare(teamID, uID, share int) error {
+	res, err := db.db.Exec(`
+		UPDATE team_members
+		SET share = $1
+		WHERE team_id = $2 AND user_id = $3`, share, teamID, uID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't change the share: %v", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return entity.DBErr(err)
+	}
+	if n == 0 {
+		return entity.ReqErr(errors.New("user is not a member of the team"))
+	}
+	return nil
+}
+
+func (db DB) LeaveTeam(teamID, uID int) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := getTeam(tx, teamID)
+	if err != nil {
+		return err
+	}
+	if t.CaptainID == uID {
+		return entity.ReqErr(errors.New("the captain can't leave the team"))
+	}
+	res, err := tx.Exec(`
+		DELETE FROM team_members
+		WHERE team_id = $1 AND user_id = $2`, teamID, uID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't remove a team member: %v", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return entity.DBErr(err)
+	}
+	if n == 0 {
+		return entity.ReqErr(errors.New("user is not a member of the team"))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
+
+// lockTeam locks the team row until the end of tx, so its members don't
+// change meanwhile.
+func lockTeam(tx *sql.Tx, teamID int) error {
+	var id int
+	err := tx.QueryRow(`
+		SELECT id
+		FROM teams
+		WHERE id = $1
+		FOR UPDATE`, teamID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return entity.ReqErr(errors.New("team doesn't exist"))
+	} else if err != nil {
+		return entity.DBErr(err)
+	}
+	return nil
+}
+
+// RegTeam registers a team for an open tournament. The members pay what pay
+// returns, all of them or none, and the team takes a single seat. A full
+// tournament has no waitlist for teams.
+func (db DB) RegTeam(tID, teamID int, code string, pay func(t entity.Tournament, team entity.Team, users []entity.User, inv *entity.Invite) ([]entity.Member, error), rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.Tournament{ID: tID}, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return t, err
+	}
+	err = t.Status.Require(entity.Open)
+	if err != nil {
+		return t, err
+	}
+	err = lockTeam(tx, teamID)
+	if err != nil {
+		return t, err
+	}
+	team, err := getTeam(tx, teamID)
+	if err != nil {
+		return t, err
+	}
+
+	ids := make([]int64, len(team.Members))
+	users := make([]entity.User, len(team.Members))
+	for i, m := range team.Members {
+		ids[i] = int64(m.UserID)
+		users[i] = entity.User{ID: m.UserID}
+		err = tx.QueryRow(`
+			SELECT name, balance, bonus, rating
+			FROM users
+			WHERE id = $1`, m.UserID).Scan(&users[i].Name, &users[i].Balance, &users[i].Bonus, &users[i].Rating)
+		if err != nil {
+			return t, entity.DBErr(err)
+		}
+	}
+	var registered int
+	err = tx.QueryRow(`
+		SELECT user_id
+		FROM tournament_req
+		WHERE tournament_id = $1 AND user_id = ANY($2)
+		UNION
+		SELECT user_id
+		FROM tournament_members
+		WHERE tournament_id = $1 AND user_id = ANY($2)
+		LIMIT 1`, tID, pq.Array(ids)).Scan(&registered)
+	if err == nil {
+		return t, entity.RegErr(fmt.Errorf("user %d is already registered", registered))
+	} else if err != sql.ErrNoRows {
+		return t, entity.DBErr(err)
+	}
+
+	inv, err := findInvite(tx, tID, team.CaptainID, code)
+	if err != nil {
+		return t, err
+	}
+	members, err := pay(t, team, users, inv)
+	if err != nil {
+		return t, err
+	}
+	err = useInvite(tx, inv)
+	if err != nil {
+		return t, err
+	}
+	players, err := countPlayers(tx, tID)
+	if err != nil {
+		return t, err
+	}
+	if t.IsFull(players) {
+		return t, entity.RegErr(errors.New("the tournament is full"))
+	}
+
+	e := entity.Entry{UserID: team.CaptainID, TeamID: team.ID}
+	for _, m := range members {
+		m.BonusStake, err = deposit(tx, tID, m.UserID, m.Stake)
+		if err != nil {
+			return t, err
+		}
+		err = transfer(tx, entity.EscrowAccount(tID), entity.RakeAccount, m.Rake, entity.TxRake, tID)
+		if err != nil {
+			return t, err
+		}
+		_, err = tx.Exec(`
+			INSERT INTO tournament_members (tournament_id, user_id, captain_id, stake, bonus_stake, rake, share)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`, tID, m.UserID, e.UserID, m.Stake, m.BonusStake, m.Rake, m.Share)
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't register a team member: %v", err))
+		}
+		e.Stake += m.Stake
+		e.BonusStake += m.BonusStake
+		e.Rake += m.Rake
+	}
+	_, err = tx.Exec(`
+		INSERT INTO tournament_req (tournament_id, user_id, stake, bonus_stake, rake, team_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`, tID, e.UserID, e.Stake, e.BonusStake, e.Rake, e.TeamID)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't register a team: %v", err))
+	}
+	err = tx.QueryRow(`
+		UPDATE tournaments
+		SET prize = prize + $1, rake = rake + $2
+		WHERE id = $3
+		RETURNING prize, rake`, e.Stake-e.Rake, e.Rake, tID).Scan(&t.Prize, &t.Rake)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't update the prize: %v", err))
+	}
+	if t.GoesWhenFull(players + 1) {
+		t, err = finishFull(tx, t, rank)
+		if err != nil {
+			return t, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return t, nil
+}
+
+// getMembers returns the members of the team entries of a tournament by
+// their captains, in the order of registration.
+func getMembers(q querier, tID int) (map[int][]entity.Member, error) {
+	rows, err := q.Query(`
+		SELECT tournament_members.captain_id, users.id, users.name, tournament_members.stake,
+			tournament_members.bonus_stake, tournament_members.rake, tournament_members.share
+		FROM tournament_members
+		INNER JOIN users ON tournament_members.user_id = users.id
+		WHERE tournament_members.tournament_id = $1
+		ORDER BY tournament_members.seq`, tID)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get team members: %v", err))
+	}
+	defer rows.Close()
+	members := map[int][]entity.Member{}
+	for rows.Next() {
+		var captainID int
+		var m entity.Member
+		err := rows.Scan(&captainID, &m.UserID, &m.Name, &m.Stake, &m.BonusStake, &m.Rake, &m.Share)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get team members: %v", err))
+		}
+		members[captainID] = append(members[captainID], m)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return members, nil
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -9,14 +10,21 @@ import (
 )
 
 func (db DB) CreateTourn(t entity.Tournament) (entity.Tournament, error) {
-	err := db.db.QueryRow(`
-		INSERT INTO tournaments (name, deposit)
-		VALUES ($1, $2)
- 		RETURNING id`, t.Name, t.Deposit).Scan(&t.ID)
+	payouts, err := json.Marshal(t.Payouts)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't encode payouts: %v", err))
+	}
+	err = db.db.QueryRow(`
+		INSERT INTO tournaments (name, deposit, status, selector, seed, seed_hash, payouts, rake_percent, rake_fee,
+			opens_at, starts_at, ends_at, min_players, max_players, format, tie_break, rounds, min_rating, max_rating,
+			team_size, private)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+ 		RETURNING id`, t.Name, t.Deposit, t.Status, t.Selector, t.Seed, t.SeedHash, payouts,
+		t.RakePercent, t.RakeFee, t.OpensAt, t.StartsAt, t.EndsAt, t.MinPlayers, t.MaxPlayers, t.Format,
+		t.TieBreak, t.Rounds, t.MinRating, t.MaxRating, t.TeamSize, t.Private).Scan(&t.ID)
 	if err != nil {
 		return t, entity.DBErr(fmt.Errorf("can't create tournament: %v", err))
 	}
-	t.Status = entity.Active
 	return t, nil
 }
 
@@ -25,60 +33,60 @@ func (db DB) GetTourn(id int) (entity.Tournament, error) {
 		return entity.Tournament{}, entity.InvIDErr(errors.New("expected id greater than 0"))
 	}
 	var t entity.Tournament
-	var finished bool
+	var payouts []byte
 
 	err := db.db.QueryRow(`
-		SELECT finished
+		SELECT id, name, deposit, prize, status, selector, COALESCE(winner_id, 0), seed_hash,
+			CASE WHEN status = $2 THEN seed ELSE '' END, payouts, rake_percent, rake_fee, rake,
+			opens_at, starts_at, ends_at, min_players, max_players, format, tie_break, rounds, min_rating, max_rating,
+			team_size, private
 		FROM tournaments
-		WHERE id=$1`, id).Scan(&finished)
+		WHERE id = $1`,
+		id, entity.Finished).Scan(&t.ID, &t.Name, &t.Deposit, &t.Prize, &t.Status, &t.Selector, &t.Winner,
+		&t.SeedHash, &t.Seed, &payouts, &t.RakePercent, &t.RakeFee, &t.Rake, &t.OpensAt, &t.StartsAt, &t.EndsAt,
+		&t.MinPlayers, &t.MaxPlayers, &t.Format, &t.TieBreak, &t.Rounds, &t.MinRating, &t.MaxRating, &t.TeamSize, &t.Private)
 	if err == sql.ErrNoRows {
 		return entity.Tournament{}, entity.ReqErr(fmt.Errorf("tournament doesn't exist: %v", err))
 	} else if err != nil {
-		return entity.Tournament{}, entity.DBErr(err)
+		return entity.Tournament{}, entity.DBErr(fmt.Errorf("can't get tournament: %v", err))
 	}
-
-	if finished {
-		t.Status = entity.Finished
-
-		err = db.db.QueryRow(`
-		SELECT id, name, deposit, prize, winner_id
-		FROM tournaments 
-		WHERE id = $1`,
-			id).Scan(&t.ID, &t.Name, &t.Deposit, &t.Prize, &t.Winner)
-		if err != nil {
-			return t, entity.DBErr(fmt.Errorf("can't get tournament: %v", err))
-		}
-	} else {
-		t.Status = entity.Active
-		err = db.db.QueryRow(`
-		SELECT id, name, deposit, prize
-		FROM tournaments 
-		WHERE id = $1`,
-			id).Scan(&t.ID, &t.Name, &t.Deposit, &t.Prize)
-		if err != nil {
-			return t, entity.DBErr(fmt.Errorf("can't get tournament: %v", err))
-		}
+	err = json.Unmarshal(payouts, &t.Payouts)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't decode payouts: %v", err))
 	}
 
+	members, err := getMembers(db.db, id)
+	if err != nil {
+		return t, err
+	}
 	rows, err := db.db.Query(`
-		SELECT users.id, users.name
+		SELECT users.id, users.name, tournament_req.stake, COALESCE(tournament_req.place, 0),
+			tournament_req.payout, tournament_req.score, tournament_req.scored_at, COALESCE(tournament_req.team_id, 0)
 		FROM tournament_req
 		INNER JOIN users ON tournament_req.user_id = users.id
-		WHERE tournament_req.tournament_id = $1`, id)
+		WHERE tournament_req.tournament_id = $1
+		ORDER BY tournament_req.seq`, id)
 	if err != nil {
 		return t, entity.DBErr(fmt.Errorf("can't get tournament data: %v", err))
 	}
 	defer rows.Close()
 
-	var w entity.Winner
 	for rows.Next() {
-		err := rows.Scan(&w.ID, &w.Name)
+		var w entity.Winner
+		err := rows.Scan(&w.ID, &w.Name, &w.Stake, &w.Place, &w.Payout, &w.Score, &w.ScoredAt, &w.TeamID)
 		if err != nil {
 			return t, entity.DBErr(fmt.Errorf("can't get tournament data: %v", err))
 		}
 		if w.ID == t.Winner {
 			w.Winner = true
 		}
+		if w.TeamID != 0 {
+			e := entity.Entry{UserID: w.ID, TeamID: w.TeamID, Members: members[w.ID]}
+			for i, amount := range e.Split(w.Payout) {
+				e.Members[i].Payout = amount
+			}
+			w.Members = e.Members
+		}
 		t.Users = append(t.Users, w)
 	}
 	if len(t.Users) == 0 {
@@ -88,38 +96,152 @@ func (db DB) GetTourn(id int) (entity.Tournament, error) {
 	if err != nil {
 		return t, entity.DBErr(err)
 	}
+
+	rows, err = db.db.Query(`
+		SELECT users.id, users.name
+		FROM tournament_waitlist
+		INNER JOIN users ON tournament_waitlist.user_id = users.id
+		WHERE tournament_waitlist.tournament_id = $1
+		ORDER BY tournament_waitlist.seq`, id)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't get the waitlist: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u entity.UserTourn
+		err := rows.Scan(&u.ID, &u.Name)
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't get the waitlist: %v", err))
+		}
+		t.Waitlist = append(t.Waitlist, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return t, entity.DBErr(err)
+	}
 	return t, nil
 }
 
-func (db DB) JoinTourn(tID, uID int, check func(balance int, deposit int) error) (entity.Tournament, error) {
+// lockTourn locks the tournament row until the end of tx and returns it
+// without participants. Unlike GetTourn it always returns the seed.
+func lockTourn(tx *sql.Tx, tID int) (entity.Tournament, error) {
 	var t entity.Tournament
-	t.ID = tID
+	var payouts []byte
+	err := tx.QueryRow(`
+		SELECT id, name, deposit, prize, status, selector, seed, seed_hash, payouts, rake_percent, rake_fee, rake,
+			min_players, max_players, format, tie_break, rounds, min_rating, max_rating, team_size, private
+		FROM tournaments
+		WHERE id = $1
+		FOR UPDATE`, tID).Scan(&t.ID, &t.Name, &t.Deposit, &t.Prize, &t.Status, &t.Selector, &t.Seed, &t.SeedHash,
+		&payouts, &t.RakePercent, &t.RakeFee, &t.Rake, &t.MinPlayers, &t.MaxPlayers, &t.Format, &t.TieBreak,
+		&t.Rounds, &t.MinRating, &t.MaxRating, &t.TeamSize, &t.Private)
+	if err == sql.ErrNoRows {
+		return t, entity.ReqErr(fmt.Errorf("tournament doesn't exist: %v", err))
+	} else if err != nil {
+		return t, entity.DBErr(err)
+	}
+	err = json.Unmarshal(payouts, &t.Payouts)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't decode payouts: %v", err))
+	}
+	return t, nil
+}
 
+// SetTournStatus moves a tournament to a status that doesn't involve any
+// points, that is opens its registration or starts it.
+func (db DB) SetTournStatus(tID int, to entity.Status) error {
 	tx, err := db.db.Begin()
 	if err != nil {
-		return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
 	defer tx.Rollback()
 
-	var finished bool
-	err = tx.QueryRow(`
-		SELECT finished
-		FROM tournaments
-		WHERE id=$1`, tID).Scan(&finished)
-	if err == sql.ErrNoRows {
-		return t, entity.ReqErr(fmt.Errorf("tournament doesn't exist: %v", err))
-	} else if err != nil {
-		return t, entity.DBErr(err)
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Transition(to)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE tournaments
+		SET status = $1
+		WHERE id = $2`, to, tID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't update tournament status: %v", err))
 	}
-	if finished {
-		return t, entity.ReqErr(errors.New("the tournament is finished"))
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
+
+func (db DB) StartTourn(tID int, prepare func(t entity.Tournament, entries []entity.Entry) ([]entity.Match, error)) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
+	defer tx.Rollback()
 
-	var name string
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Transition(entity.Running)
+	if err != nil {
+		return err
+	}
+	entries, err := getEntries(tx, tID)
+	if err != nil {
+		return err
+	}
+	matches, err := prepare(t, entries)
+	if err != nil {
+		return err
+	}
+	err = saveMatches(tx, tID, matches)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE tournaments
+		SET status = $1
+		WHERE id = $2`, entity.Running, tID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't update tournament status: %v", err))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
+
+func (db DB) JoinTourn(tID, uID int, code string, check func(u entity.User, t entity.Tournament, inv *entity.Invite) error, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.Tournament{ID: tID}, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return t, err
+	}
+	err = t.Status.Require(entity.Open)
+	if err != nil {
+		return t, err
+	}
+
+	u := entity.User{ID: uID}
 	err = tx.QueryRow(`
-		SELECT name
+		SELECT name, balance, bonus, rating
 		FROM users
-		WHERE id = $1`, uID).Scan(&name)
+		WHERE id = $1`, uID).Scan(&u.Name, &u.Balance, &u.Bonus, &u.Rating)
 	if err == sql.ErrNoRows {
 		return t, entity.ReqErr(errors.New("the user doesn't exist"))
 	} else if err != nil {
@@ -136,51 +258,239 @@ func (db DB) JoinTourn(tID, uID int, check func(balance int, deposit int) error)
 	} else if id != 0 {
 		return t, entity.RegErr(fmt.Errorf("user is already registered"))
 	}
-
 	err = tx.QueryRow(`
-		SELECT deposit 
-		FROM tournaments 
-		WHERE id = $1`, tID).Scan(&t.Deposit)
-	if err != nil {
+		SELECT tournament_id
+		FROM tournament_waitlist
+		WHERE user_id = $1 AND tournament_id = $2`, uID, tID).Scan(&id)
+	if err == nil {
+		return t, entity.RegErr(fmt.Errorf("user is already on the waitlist"))
+	} else if err != sql.ErrNoRows {
 		return t, entity.DBErr(err)
 	}
-	var balance int
-	err = tx.QueryRow(`
-		SELECT balance 
-		FROM users 
-		WHERE id = $1`, uID).Scan(&balance)
+
+	inv, err := findInvite(tx, tID, uID, code)
 	if err != nil {
-		return t, entity.DBErr(err)
+		return t, err
+	}
+	err = check(u, t, inv)
+	if err != nil {
+		return t, err
+	}
+	err = useInvite(tx, inv)
+	if err != nil {
+		return t, err
 	}
 
-	err = check(balance, t.Deposit)
+	players, err := countPlayers(tx, tID)
 	if err != nil {
 		return t, err
 	}
+	if t.IsFull(players) {
+		_, err = tx.Exec(`
+			INSERT INTO tournament_waitlist (tournament_id, user_id)
+			VALUES ($1, $2)`, tID, uID)
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't put a user on the waitlist: %v", err))
+		}
+	} else {
+		t, err = enter(tx, t, uID)
+		if err != nil {
+			return t, err
+		}
+		if t.GoesWhenFull(players + 1) {
+			t, err = finishFull(tx, t, rank)
+			if err != nil {
+				return t, err
+			}
+		}
+	}
 
-	_, err = tx.Exec(`
-		UPDATE users
-		SET balance = balance - $1
-		WHERE id = $2`, t.Deposit, uID)
+	err = tx.Commit()
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return t, nil
+}
+
+// finishFull finishes a sit-and-go the registration in tx has filled.
+func finishFull(tx *sql.Tx, t entity.Tournament, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) (entity.Tournament, error) {
+	entries, err := getEntries(tx, t.ID)
+	if err != nil {
+		return t, err
+	}
+	err = finish(tx, t, entries, rank)
+	if err != nil {
+		return t, err
+	}
+	t.Status = entity.Finished
+	return t, nil
+}
+
+func countPlayers(tx *sql.Tx, tID int) (int, error) {
+	var n int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM tournament_req
+		WHERE tournament_id = $1`, tID).Scan(&n)
 	if err != nil {
-		return t, entity.DBErr(fmt.Errorf("can't update user's balance: %v", err))
+		return 0, entity.DBErr(fmt.Errorf("can't count players: %v", err))
+	}
+	return n, nil
+}
+
+// enter charges the deposit and registers the user.
+func enter(tx *sql.Tx, t entity.Tournament, uID int) (entity.Tournament, error) {
+	rake := t.EntryRake()
+	bonus, err := deposit(tx, t.ID, uID, t.Deposit)
+	if err != nil {
+		return t, err
+	}
+	err = transfer(tx, entity.EscrowAccount(t.ID), entity.RakeAccount, rake, entity.TxRake, t.ID)
+	if err != nil {
+		return t, err
 	}
 
 	_, err = tx.Exec(`
-		INSERT INTO tournament_req (tournament_id, user_id)
-		VALUES ($1, $2)`, tID, uID)
+		INSERT INTO tournament_req (tournament_id, user_id, stake, bonus_stake, rake)
+		VALUES ($1, $2, $3, $4, $5)`, t.ID, uID, t.Deposit, bonus, rake)
 	if err != nil {
 		return t, entity.DBErr(fmt.Errorf("can't register a user: %v", err))
 	}
 
 	err = tx.QueryRow(`
 		UPDATE tournaments
-		SET prize = prize + $1
-		WHERE id = $2
-		RETURNING name, prize`, t.Deposit, t.ID).Scan(&t.Name, &t.Prize)
+		SET prize = prize + $1, rake = rake + $2
+		WHERE id = $3
+		RETURNING name, prize, rake`, t.Deposit-rake, rake, t.ID).Scan(&t.Name, &t.Prize, &t.Rake)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't update the prize: %v", err))
+	}
+	return t, nil
+}
+
+// promote moves users from the head of the waitlist into the free seats.
+// Users who can't pay the deposit any more are dropped from the waitlist.
+func promote(tx *sql.Tx, t entity.Tournament) (entity.Tournament, error) {
+	for {
+		players, err := countPlayers(tx, t.ID)
+		if err != nil {
+			return t, err
+		}
+		if t.IsFull(players) {
+			return t, nil
+		}
+		var uID int
+		err = tx.QueryRow(`
+			DELETE FROM tournament_waitlist
+			WHERE tournament_id = $1 AND user_id = (
+				SELECT user_id
+				FROM tournament_waitlist
+				WHERE tournament_id = $1
+				ORDER BY seq
+				LIMIT 1)
+			RETURNING user_id`, t.ID).Scan(&uID)
+		if err == sql.ErrNoRows {
+			return t, nil
+		} else if err != nil {
+			return t, entity.DBErr(fmt.Errorf("can't promote from the waitlist: %v", err))
+		}
+		u, err := getUser(tx, uID)
+		if err != nil {
+			return t, err
+		}
+		if u.Funds() < t.Deposit {
+			continue
+		}
+		t, err = enter(tx, t, uID)
+		if err != nil {
+			return t, err
+		}
+	}
+}
+
+// LeaveTourn unregisters a user from an open tournament and refunds the
+// deposit from the prize and the rake. The seat goes to the first user on the
+// waitlist. A waitlisted user just leaves the waitlist.
+func (db DB) LeaveTourn(tID, uID int) (entity.Tournament, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.Tournament{ID: tID}, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return t, err
+	}
+	err = t.Status.Require(entity.Open)
+	if err != nil {
+		return t, err
+	}
+
+	res, err := tx.Exec(`
+		DELETE FROM tournament_waitlist
+		WHERE tournament_id = $1 AND user_id = $2`, tID, uID)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't remove a user from the waitlist: %v", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return t, entity.DBErr(err)
+	}
+	if n != 0 {
+		err = tx.Commit()
+		if err != nil {
+			return t, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+		}
+		return t, nil
+	}
+
+	members, err := getMembers(tx, tID)
+	if err != nil {
+		return t, err
+	}
+	e := entity.Entry{UserID: uID}
+	err = tx.QueryRow(`
+		DELETE FROM tournament_req
+		WHERE tournament_id = $1 AND user_id = $2
+		RETURNING stake, bonus_stake, rake, COALESCE(team_id, 0)`, tID, uID).Scan(&e.Stake, &e.BonusStake, &e.Rake, &e.TeamID)
+	if err == sql.ErrNoRows {
+		for _, team := range members {
+			for _, m := range team {
+				if m.UserID == uID {
+					return t, entity.ReqErr(errors.New("only the captain withdraws the team"))
+				}
+			}
+		}
+		return t, entity.ReqErr(errors.New("user is not registered"))
+	} else if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't unregister a user: %v", err))
+	}
+	e.Members = members[uID]
+	_, err = tx.Exec(`
+		DELETE FROM tournament_members
+		WHERE tournament_id = $1 AND captain_id = $2`, tID, uID)
+	if err != nil {
+		return t, entity.DBErr(fmt.Errorf("can't unregister a team: %v", err))
+	}
+
+	err = refund(tx, tID, e)
+	if err != nil {
+		return t, err
+	}
+
+	err = tx.QueryRow(`
+		UPDATE tournaments
+		SET prize = prize - $1, rake = rake - $2
+		WHERE id = $3
+		RETURNING prize, rake`, e.Stake-e.Rake, e.Rake, tID).Scan(&t.Prize, &t.Rake)
 	if err != nil {
 		return t, entity.DBErr(fmt.Errorf("can't update the prize: %v", err))
 	}
+	t, err = promote(tx, t)
+	if err != nil {
+		return t, err
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -189,87 +499,252 @@ func (db DB) JoinTourn(tID, uID int, check func(balance int, deposit int) error)
 	return t, nil
 }
 
-func getTournUsers(tx *sql.Tx, tID int) ([]int, error) {
+func (db DB) SubmitScore(tID, uID, score int) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Require(entity.Running)
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(`
+		UPDATE tournament_req
+		SET score = $1, scored_at = clock_timestamp()
+		WHERE tournament_id = $2 AND user_id = $3`, score, tID, uID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't record the score: %v", err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return entity.DBErr(err)
+	}
+	if n == 0 {
+		return entity.ReqErr(errors.New("user is not registered"))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	return nil
+}
+
+// refund returns the whole stake of an entry, its rake included, to those
+// who paid it. Bonus points go back as bonus points, or as cash if the
+// payer's wagering requirement has been met meanwhile.
+func refund(tx *sql.Tx, tID int, e entity.Entry) error {
+	for _, m := range e.Payers() {
+		u, err := getUser(tx, m.UserID)
+		if err != nil {
+			return err
+		}
+		cash, bonus := m.Stake-m.BonusStake, m.BonusStake
+		if u.Wagering == 0 {
+			cash, bonus = m.Stake, 0
+		}
+		err = transfer(tx, entity.RakeAccount, entity.EscrowAccount(tID), m.Rake, entity.TxRefund, tID)
+		if err != nil {
+			return err
+		}
+		err = transfer(tx, entity.EscrowAccount(tID), entity.UserAccount(m.UserID), cash, entity.TxRefund, tID)
+		if err != nil {
+			return err
+		}
+		err = transfer(tx, entity.EscrowAccount(tID), entity.BonusAccount(m.UserID), bonus, entity.TxRefund, tID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getEntries(tx *sql.Tx, tID int) ([]entity.Entry, error) {
+	members, err := getMembers(tx, tID)
+	if err != nil {
+		return nil, err
+	}
 	rows, err := tx.Query(`
-		SELECT user_id
+		SELECT tournament_req.user_id, tournament_req.stake, tournament_req.bonus_stake, tournament_req.rake,
+			tournament_req.score, tournament_req.scored_at, users.rating, COALESCE(tournament_req.team_id, 0)
 		FROM tournament_req
-		WHERE tournament_id = $1`, tID)
+		INNER JOIN users ON tournament_req.user_id = users.id
+		WHERE tournament_req.tournament_id = $1
+		ORDER BY tournament_req.seq`, tID)
 	if err != nil {
 		return nil, entity.DBErr(fmt.Errorf("can't get data: %v", err))
 	}
 	defer rows.Close()
-	var userIDs []int
-	var id int
+	var entries []entity.Entry
 	for rows.Next() {
-		err := rows.Scan(&id)
+		var e entity.Entry
+		err := rows.Scan(&e.UserID, &e.Stake, &e.BonusStake, &e.Rake, &e.Score, &e.ScoredAt, &e.Rating, &e.TeamID)
 		if err != nil {
 			return nil, entity.DBErr(fmt.Errorf("can't get data: %v", err))
 		}
-		userIDs = append(userIDs, id)
+		e.Members = members[e.UserID]
+		entries = append(entries, e)
 	}
 	err = rows.Err()
 	if err != nil {
 		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
 	}
-	return userIDs, nil
+	return entries, nil
 }
 
-func (db DB) FinishTourn(tID int, chooseWinner func(ids []int) int) error {
+// FinishTourn pays out the prize of a running tournament by the placings
+// ranked among its entries, which are in the order of registration. The
+// prize of a team is split among its members. The deposits count toward the
+// wagering requirements of those who paid them. A tournament without a quorum
+// is cancelled instead.
+func (db DB) FinishTourn(tID int, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) error {
 	tx, err := db.db.Begin()
 	if err != nil {
 		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
 	defer tx.Rollback()
 
-	var finished bool
-	err = tx.QueryRow(`
-		SELECT finished
-		FROM tournaments
-		WHERE id=$1`, tID).Scan(&finished)
-	if err == sql.ErrNoRows {
-		return entity.ReqErr(fmt.Errorf("tournament doesn't exist: %v", err))
-	} else if err != nil {
-		return entity.DBErr(err)
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Transition(entity.Finished)
+	if err != nil {
+		return err
 	}
 
-	rows, err := tx.Query(`
-		SELECT user_id
-		FROM tournament_req
-		WHERE tournament_id = $1`, tID)
+	entries, err := getEntries(tx, tID)
 	if err != nil {
-		return entity.DBErr(fmt.Errorf("can't get tournament data: %v", err))
+		return err
 	}
-	if !rows.Next() {
-		return entity.ReqErr(errors.New("can't finish, no users"))
+	if !t.HasQuorum(len(entries)) {
+		err = cancel(tx, tID, entries)
+		if err != nil {
+			return err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+		}
+		return nil
 	}
-	err = rows.Err()
+	err = finish(tx, t, entries, rank)
 	if err != nil {
-		return entity.DBErr(err)
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
-	rows.Close()
-	//todo trying to start a new statement before reading all of the rows of the preceding statement
-	users, err := getTournUsers(tx, tID)
+	return nil
+}
+
+// finish pays out the prize of t by the placings rank returns for its entries
+// and marks it finished.
+func finish(tx *sql.Tx, t entity.Tournament, entries []entity.Entry, rank func(t entity.Tournament, entries []entity.Entry) ([]entity.Placing, []entity.RatingChange, error)) error {
+	placings, ratings, err := rank(t, entries)
 	if err != nil {
 		return err
 	}
-	var uID = chooseWinner(users)
+	paid := 0
+	for _, p := range placings {
+		paid += p.Amount
+	}
+	if len(placings) == 0 || paid != t.Prize {
+		return entity.DBErr(fmt.Errorf("placings pay out %d points, the prize is %d", paid, t.Prize))
+	}
 
-	var prize int
-	err = tx.QueryRow(`
+	for _, p := range placings {
+		res, err := tx.Exec(`
+			UPDATE tournament_req
+			SET place = $1, payout = $2
+			WHERE tournament_id = $3 AND user_id = $4`, p.Place, p.Amount, t.ID, p.UserID)
+		if err != nil {
+			return entity.DBErr(fmt.Errorf("can't record the placing: %v", err))
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return entity.DBErr(err)
+		}
+		if n == 0 {
+			return entity.DBErr(fmt.Errorf("user %d placed %d isn't a participant", p.UserID, p.Place))
+		}
+		e := entity.Entry{UserID: p.UserID}
+		for _, entry := range entries {
+			if entry.UserID == p.UserID {
+				e = entry
+			}
+		}
+		payers := e.Payers()
+		for i, amount := range e.Split(p.Amount) {
+			err = payPrize(tx, t.ID, payers[i], amount)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, e := range entries {
+		for _, m := range e.Payers() {
+			err = wager(tx, t.ID, m.UserID, m.Stake)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = changeRatings(tx, t.ID, ratings)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
 		UPDATE tournaments
-		SET winner_id = $1, finished = $2
-		WHERE id = $3	
-		RETURNING prize`, uID, true, tID).Scan(&prize)
+		SET winner_id = $1, status = $2
+		WHERE id = $3`, placings[0].UserID, entity.Finished, t.ID)
 	if err != nil {
 		return entity.DBErr(err)
 	}
-
 	_, err = tx.Exec(`
-		UPDATE users
-		SET balance = balance + $1
-		WHERE id = $2`, prize, uID)
+		DELETE FROM tournament_waitlist
+		WHERE tournament_id = $1`, t.ID)
 	if err != nil {
-		return entity.DBErr(err)
+		return entity.DBErr(fmt.Errorf("can't clear the waitlist: %v", err))
+	}
+	return nil
+}
+
+// CancelTourn refunds the deposits of all participants of an unfinished
+// tournament and marks it cancelled. The tournament and its participants are
+// kept.
+func (db DB) CancelTourn(tID int) error {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	t, err := lockTourn(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = t.Status.Transition(entity.Cancelled)
+	if err != nil {
+		return err
+	}
+
+	entries, err := getEntries(tx, tID)
+	if err != nil {
+		return err
+	}
+	err = cancel(tx, tID, entries)
+	if err != nil {
+		return err
 	}
 
 	err = tx.Commit()
@@ -279,12 +754,41 @@ func (db DB) FinishTourn(tID int, chooseWinner func(ids []int) int) error {
 	return nil
 }
 
+func cancel(tx *sql.Tx, tID int, entries []entity.Entry) error {
+	for _, e := range entries {
+		err := refund(tx, tID, e)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`
+		DELETE FROM tournament_waitlist
+		WHERE tournament_id = $1`, tID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't clear the waitlist: %v", err))
+	}
+	_, err = tx.Exec(`
+		UPDATE tournaments
+		SET status = $1, prize = 0, rake = 0
+		WHERE id = $2`, entity.Cancelled, tID)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't cancel the tournament: %v", err))
+	}
+	return nil
+}
+
 func (db DB) DelTourn(id int) error {
 	tx, err := db.db.Begin()
 	if err != nil {
 		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
 	defer tx.Rollback()
+	_, err = tx.Exec(`
+		DELETE FROM tournament_members
+		WHERE tournament_id = $1`, id)
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't delete from tournament_members table: %v", err))
+	}
 	_, err = tx.Exec(`
 		DELETE FROM tournament_req
 		WHERE tournament_id = $1`, id)
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func (db DB) ListTourns(f entity.TournamentFilter) (entity.TournamentPage, error) {
+	p := entity.TournamentPage{Tournaments: []entity.TournamentSummary{}}
+
+	where := "NOT tournaments.private"
+	var args []interface{}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, s := range f.Statuses {
+			statuses[i] = string(s)
+		}
+		args = append(args, pq.Array(statuses))
+		where += fmt.Sprintf(" AND tournaments.status = ANY($%d)", len(args))
+	}
+	if f.MinDeposit != nil {
+		args = append(args, *f.MinDeposit)
+		where += fmt.Sprintf(" AND tournaments.deposit >= $%d", len(args))
+	}
+	if f.MaxDeposit != nil {
+		args = append(args, *f.MaxDeposit)
+		where += fmt.Sprintf(" AND tournaments.deposit <= $%d", len(args))
+	}
+
+	err := db.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM tournaments
+		WHERE `+where, args...).Scan(&p.Total)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't count tournaments: %v", err))
+	}
+
+	if f.Cursor.ID != 0 {
+		args = append(args, f.Cursor.ID)
+		where += fmt.Sprintf(" AND tournaments.id < $%d", len(args))
+	}
+	args = append(args, f.Limit+1)
+	rows, err := db.db.Query(fmt.Sprintf(`
+		SELECT tournaments.id, tournaments.name, tournaments.deposit, tournaments.prize, tournaments.rake,
+			tournaments.status, tournaments.format, COALESCE(tournaments.winner_id, 0), COUNT(tournament_req.user_id)
+		FROM tournaments
+		LEFT JOIN tournament_req ON tournament_req.tournament_id = tournaments.id
+		WHERE %s
+		GROUP BY tournaments.id
+		ORDER BY tournaments.id DESC
+		LIMIT $%d`, where, len(args)), args...)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't get tournaments: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t entity.TournamentSummary
+		err := rows.Scan(&t.ID, &t.Name, &t.Deposit, &t.Prize, &t.Rake, &t.Status, &t.Format, &t.Winner, &t.Participants)
+		if err != nil {
+			return p, entity.DBErr(fmt.Errorf("can't get tournaments: %v", err))
+		}
+		p.Tournaments = append(p.Tournaments, t)
+	}
+	err = rows.Err()
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	if len(p.Tournaments) > f.Limit {
+		p.Tournaments = p.Tournaments[:f.Limit]
+		p.NextCursor = entity.Cursor{ID: p.Tournaments[f.Limit-1].ID}.Encode()
+	}
+	return p, nil
+}
+
+func (db DB) DueTourns(now time.Time) ([]entity.Tournament, error) {
+	rows, err := db.db.Query(`
+		SELECT id, status, selector, format, opens_at, starts_at, ends_at
+		FROM tournaments
+		WHERE status = $2 AND opens_at <= $1
+			OR status = $3 AND starts_at <= $1
+			OR status = $4 AND ends_at <= $1
+		ORDER BY id`, now, entity.Draft, entity.Open, entity.Running)
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("can't get due tournaments: %v", err))
+	}
+	defer rows.Close()
+	ts := []entity.Tournament{}
+	for rows.Next() {
+		var t entity.Tournament
+		err := rows.Scan(&t.ID, &t.Status, &t.Selector, &t.Format, &t.OpensAt, &t.StartsAt, &t.EndsAt)
+		if err != nil {
+			return nil, entity.DBErr(fmt.Errorf("can't get due tournaments: %v", err))
+		}
+		ts = append(ts, t)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	return ts, nil
+}
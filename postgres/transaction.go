@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// nullID stores 0 as NULL.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// changeBalance adds amount to the user's cash, or bonus points if bonus is
+// set, and records the change in the user's transaction history within tx.
+// tID is 0 when the change is not tied to a tournament. Use transfer to keep
+// the books balanced.
+func changeBalance(tx *sql.Tx, uID, amount int, bonus bool, typ entity.TransactionType, tID int) error {
+	column := "balance"
+	if bonus {
+		column = "bonus"
+	}
+	var balance int
+	err := tx.QueryRow(fmt.Sprintf(`
+		UPDATE users
+		SET %[1]s = %[1]s + $1
+		WHERE id = $2
+		RETURNING %[1]s`, column), amount, uID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return entity.UserNotFoundErr(err)
+	} else if err != nil {
+		return entity.DBErr(fmt.Errorf("can't update user's balance: %v", err))
+	}
+	_, err = tx.Exec(`
+		INSERT INTO transactions (user_id, type, amount, bonus, balance, tournament_id)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		uID, typ, amount, bonus, balance, nullID(tID))
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("can't record transaction: %v", err))
+	}
+	return nil
+}
+
+func (db DB) ListTransactions(f entity.TransactionFilter) (entity.TransactionPage, error) {
+	p := entity.TransactionPage{Transactions: []entity.Transaction{}}
+	query := `
+		SELECT id, user_id, type, amount, bonus, balance, tournament_id, created_at
+		FROM transactions
+		WHERE user_id = $1`
+	args := []interface{}{f.UserID}
+	if len(f.Types) > 0 {
+		types := make([]string, len(f.Types))
+		for i, t := range f.Types {
+			types[i] = string(t)
+		}
+		args = append(args, pq.Array(types))
+		query += fmt.Sprintf(" AND type = ANY($%d)", len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		query += fmt.Sprintf(" AND created_at >= $%d", len(args))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+	if f.Cursor.ID != 0 {
+		args = append(args, f.Cursor.ID)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+	args = append(args, f.Limit+1)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := db.db.Query(query, args...)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't get transactions: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t entity.Transaction
+		var tID sql.NullInt64
+		err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &t.Bonus, &t.Balance, &tID, &t.CreatedAt)
+		if err != nil {
+			return p, entity.DBErr(fmt.Errorf("can't get transactions: %v", err))
+		}
+		t.TournamentID = int(tID.Int64)
+		p.Transactions = append(p.Transactions, t)
+	}
+	err = rows.Err()
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	if len(p.Transactions) > f.Limit {
+		p.Transactions = p.Transactions[:f.Limit]
+		p.NextCursor = entity.Cursor{ID: p.Transactions[f.Limit-1].ID}.Encode()
+	}
+	return p, nil
+}
@@ -14,7 +14,9 @@ type DB struct {
 	db *sql.DB
 }
 
-func New() (DB, error) {
+// Open connects to the database from the CONN environment variable without
+// touching the schema.
+func Open() (DB, error) {
 	connStr, ok := os.LookupEnv("CONN")
 	if !ok {
 		return DB{}, entity.DBErr(errors.New("empty connection string"))
@@ -24,56 +26,62 @@ func New() (DB, error) {
 		return DB{}, entity.DBErr(err)
 	}
 	err = db.Ping()
-	gm := DB{db: db}
 	if err != nil {
 		return DB{}, entity.DBErr(err)
 	}
-	err = gm.CreateTables()
+	db.SetMaxOpenConns(5)
+	return DB{db: db}, nil
+}
+
+// New connects to the database and brings its schema up to date.
+func New() (DB, error) {
+	gm, err := Open()
+	if err != nil {
+		return DB{}, err
+	}
+	err = gm.Migrate()
 	if err != nil {
-		return DB{}, entity.DBErr(err)
+		return DB{}, err
 	}
-	gm.db.SetMaxOpenConns(5)
 	return gm, nil
 }
 
-func (db DB) CreateTables() error {
-	_, err := db.db.Exec(`
-		CREATE TABLE IF NOT EXISTS users(
-		id SERIAL PRIMARY KEY,
-		name TEXT NOT NULL,
-		balance INT NOT NULL CHECK(balance>=0))`)
+// CreateUser stores a user with the initial balance u.Balance, pays the
+// signup bonus and charges the registration fee of p.
+func (db DB) CreateUser(u entity.User, p entity.Policy) (entity.User, error) {
+	tx, err := db.db.Begin()
+	if err != nil {
+		return u, entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO users (name, balance, rating, policy_id)
+		VALUES ($1, 0, $2, $3)
+ 		RETURNING id`, u.Name, u.Rating, u.PolicyID).Scan(&u.ID)
 	if err != nil {
-		return entity.DBErr(fmt.Errorf("table 'users' failed: %v", err))
+		return u, entity.DBErr(err)
 	}
-	_, err = db.db.Exec(`
-		CREATE TABLE IF NOT EXISTS tournaments (
-		id serial PRIMARY KEY,
-		name TEXT NOT NULL,
-		deposit INT NOT NULL CHECK(deposit>=0),
-		prize INT NOT NULL CHECK(prize>=0) DEFAULT 0,
-		finished BOOLEAN NOT NULL DEFAULT FALSE,
-		winner_id INT)`)
+	err = transfer(tx, entity.HouseAccount, entity.UserAccount(u.ID), u.Balance, entity.TxRegistration, 0)
 	if err != nil {
-		return entity.DBErr(fmt.Errorf("table 'tournaments' failed: %v", err))
+		return u, err
 	}
-	_, err = db.db.Exec(`
-		CREATE TABLE IF NOT EXISTS tournament_req (
-		tournament_id INT NOT NULL REFERENCES tournaments (id) ON DELETE RESTRICT,
-		user_id INT NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
-		PRIMARY KEY (tournament_id, user_id) )`)
+	err = grantBonus(tx, u.ID, p)
 	if err != nil {
-		return entity.DBErr(fmt.Errorf("table 'tournament_req' failed: %v", err))
+		return u, err
+	}
+	err = transfer(tx, entity.UserAccount(u.ID), entity.FeesAccount, p.Fee, entity.TxRegFee, 0)
+	if err != nil {
+		return u, err
+	}
+	u, err = getUser(tx, u.ID)
+	if err != nil {
+		return u, err
 	}
-	return nil
-}
 
-func (db DB) CreateUser(u entity.User) (entity.User, error) {
-	err := db.db.QueryRow(`
-		INSERT INTO users (name, balance)
-		VALUES ($1, $2)
- 		RETURNING id`, u.Name, u.Balance).Scan(&u.ID)
+	err = tx.Commit()
 	if err != nil {
-		return u, entity.DBErr(err)
+		return u, entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
 	return u, nil
 }
@@ -82,11 +90,15 @@ func (db DB) GetUser(id int) (entity.User, error) {
 	if id <= 0 {
 		return entity.User{}, entity.InvIDErr(errors.New("expected id greater than 0"))
 	}
+	return getUser(db.db, id)
+}
+
+func getUser(q querier, id int) (entity.User, error) {
 	u := entity.User{}
-	err := db.db.QueryRow(`
-		SELECT id, name, balance 
+	err := q.QueryRow(`
+		SELECT id, name, balance, bonus, wagering, rating, policy_id
 		FROM users 
-		WHERE id = $1`, id).Scan(&u.ID, &u.Name, &u.Balance)
+		WHERE id = $1`, id).Scan(&u.ID, &u.Name, &u.Balance, &u.Bonus, &u.Wagering, &u.Rating, &u.PolicyID)
 	if err == sql.ErrNoRows {
 		return u, entity.UserNotFoundErr(err)
 	} else if err != nil {
@@ -95,48 +107,92 @@ func (db DB) GetUser(id int) (entity.User, error) {
 	return u, nil
 }
 
+// lockUser reads a user and locks the row until tx ends, so that the
+// balances read stay the balances written.
+func lockUser(tx *sql.Tx, id int) (entity.User, error) {
+	u := entity.User{}
+	err := tx.QueryRow(`
+		SELECT id, name, balance, bonus, wagering, rating, policy_id
+		FROM users
+		WHERE id = $1
+		FOR UPDATE`, id).Scan(&u.ID, &u.Name, &u.Balance, &u.Bonus, &u.Wagering, &u.Rating, &u.PolicyID)
+	if err == sql.ErrNoRows {
+		return u, entity.UserNotFoundErr(err)
+	} else if err != nil {
+		return u, entity.DBErr(err)
+	}
+	return u, nil
+}
+
+// DelUser removes a user and returns the rest of the balance, bonus points
+// included, to the house.
 func (db DB) DelUser(id int) error {
-	u, err := db.GetUser(id)
+	if id <= 0 {
+		return entity.InvIDErr(errors.New("expected id greater than 0"))
+	}
+	tx, err := db.db.Begin()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
+	defer tx.Rollback()
+
+	u, err := lockUser(tx, id)
+	if err != nil {
+		return err
+	}
+	err = transfer(tx, entity.UserAccount(u.ID), entity.HouseAccount, u.Balance, entity.TxClose, 0)
+	if err != nil {
+		return err
+	}
+	err = transfer(tx, entity.BonusAccount(u.ID), entity.HouseAccount, u.Bonus, entity.TxClose, 0)
 	if err != nil {
 		return err
 	}
-	_, err = db.db.Exec(`
+	_, err = tx.Exec(`
 		DELETE FROM users 
 		WHERE id = $1`, u.ID)
 	if err != nil {
 		return entity.DBErr(fmt.Errorf("delete constraint on a dependent table: %v", err))
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		return entity.DBErr(fmt.Errorf("transaction error: %v", err))
+	}
 	return nil
 }
 
 func (db DB) TakePoints(id, points int) (entity.User, error) {
+	return db.movePoints(id, entity.UserAccount(id), entity.HouseAccount, points, entity.TxTake)
+}
+
+func (db DB) FundPoints(id, points int) (entity.User, error) {
+	return db.movePoints(id, entity.HouseAccount, entity.UserAccount(id), points, entity.TxFund)
+}
+
+func (db DB) movePoints(id int, from, to entity.Account, points int, typ entity.TransactionType) (entity.User, error) {
 	u, err := db.GetUser(id)
 	if err != nil {
 		return u, err
 	}
-	err = db.db.QueryRow(`
-		UPDATE users
-		SET balance = balance - $1
-		WHERE id = $2
-		RETURNING balance`, points, u.ID).Scan(&u.Balance)
+	tx, err := db.db.Begin()
 	if err != nil {
-		return u, entity.DBErr(err)
+		return u, entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
-	return u, nil
-}
+	defer tx.Rollback()
 
-func (db DB) FundPoints(id, points int) (entity.User, error) {
-	u, err := db.GetUser(id)
+	err = transfer(tx, from, to, points, typ, 0)
 	if err != nil {
 		return u, err
 	}
-	err = db.db.QueryRow(`
-		UPDATE users
-		SET balance = balance + $1
-		WHERE id = $2
-		RETURNING balance`, points, u.ID).Scan(&u.Balance)
+	u, err = getUser(tx, u.ID)
 	if err != nil {
-		return u, entity.DBErr(err)
+		return u, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return u, entity.DBErr(fmt.Errorf("transaction error: %v", err))
 	}
 	return u, nil
 }
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// free is the default policy without its fee, so that users keep the balance
+// they are created with.
+var free = entity.Policy{ID: 1}
+
+func newUser(t *testing.T, db DB, balance int) entity.User {
+	t.Helper()
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: balance, PolicyID: 1}, free)
+	require.NoError(t, err)
+	return u
+}
+
+func balance(t *testing.T, db DB, uID int) int {
+	t.Helper()
+	u, err := db.GetUser(uID)
+	require.NoError(t, err)
+	return u.Balance
+}
+
+func count(t *testing.T, db DB, table string) int {
+	t.Helper()
+	var n int
+	require.NoError(t, db.db.QueryRow(`SELECT count(*) FROM `+table).Scan(&n))
+	return n
+}
+
+func transactions(t *testing.T, db DB, f entity.TransactionFilter) []entity.Transaction {
+	t.Helper()
+	if f.Limit == 0 {
+		f.Limit = entity.MaxLimit
+	}
+	p, err := db.ListTransactions(f)
+	require.NoError(t, err)
+	return p.Transactions
+}
+
+func TestCreateUser(t *testing.T) {
+	db := migratedDB(t)
+	u, err := db.CreateUser(entity.User{Name: "alice", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, 700, u.Balance)
+	got, err := db.GetUser(u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u, got)
+
+	_, err = db.CreateUser(entity.User{Name: "bob", Balance: 200, PolicyID: 1}, entity.DefaultPolicy)
+	assert.Error(t, err, "the fee is charged from the initial balance")
+	assert.Equal(t, 1, count(t, db, "users"))
+	assert.Equal(t, 2, count(t, db, "transactions"))
+
+	_, err = db.GetUser(0)
+	assert.Error(t, err)
+	_, err = db.GetUser(u.ID + 100)
+	assert.Error(t, err)
+}
+
+func TestTakeFundPoints(t *testing.T) {
+	db := migratedDB(t)
+	u := newUser(t, db, 700)
+
+	_, err := db.TakePoints(u.ID, 701)
+	require.Error(t, err)
+	assert.Equal(t, 700, balance(t, db, u.ID))
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: u.ID}), 1, "a failed change isn't recorded")
+	u, err = db.TakePoints(u.ID, 200)
+	require.NoError(t, err)
+	assert.Equal(t, 500, u.Balance)
+	u, err = db.FundPoints(u.ID, 400)
+	require.NoError(t, err)
+	assert.Equal(t, 900, u.Balance)
+	assert.Equal(t, 900, balance(t, db, u.ID))
+
+	_, err = db.FundPoints(u.ID+100, 1)
+	assert.Error(t, err)
+}
+
+func TestLedger(t *testing.T) {
+	db := migratedDB(t)
+	u, err := db.CreateUser(entity.User{Name: "user", Balance: 1000, PolicyID: 1}, entity.DefaultPolicy)
+	require.NoError(t, err)
+	other := newUser(t, db, 500)
+	_, err = db.TakePoints(u.ID, 200)
+	require.NoError(t, err)
+	_, err = db.FundPoints(u.ID, 50)
+	require.NoError(t, err)
+	tr := newTourn(t, db, entity.Tournament{Deposit: 100})
+	join(t, db, tr.ID, u.ID)
+	join(t, db, tr.ID, other.ID)
+	start(t, db, tr.ID)
+	require.NoError(t, db.FinishTourn(tr.ID, pick(u.ID)))
+
+	var got []entity.Transaction
+	for _, tx := range transactions(t, db, entity.TransactionFilter{UserID: u.ID}) {
+		assert.Equal(t, u.ID, tx.UserID)
+		assert.False(t, tx.CreatedAt.IsZero())
+		tx.ID, tx.UserID, tx.CreatedAt = 0, 0, time.Time{}
+		got = append(got, tx)
+	}
+	assert.Equal(t, []entity.Transaction{
+		{Type: entity.TxPrize, Amount: 200, Balance: 650, TournamentID: tr.ID},
+		{Type: entity.TxDeposit, Amount: -100, Balance: 450, TournamentID: tr.ID},
+		{Type: entity.TxFund, Amount: 50, Balance: 550},
+		{Type: entity.TxTake, Amount: -200, Balance: 500},
+		{Type: entity.TxRegFee, Amount: -300, Balance: 700},
+		{Type: entity.TxRegistration, Amount: 1000, Balance: 1000},
+	}, got, "newest first")
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: other.ID}), 2)
+
+	_, err = db.db.Exec(`UPDATE transactions SET amount = 0`)
+	assert.Error(t, err, "transactions are append-only")
+	_, err = db.db.Exec(`DELETE FROM transactions`)
+	assert.Error(t, err, "transactions are append-only")
+}
+
+func TestListTransactions(t *testing.T) {
+	db := migratedDB(t)
+	u := newUser(t, db, 1000)
+	for i := 1; i <= 5; i++ {
+		_, err := db.FundPoints(u.ID, i)
+		require.NoError(t, err)
+	}
+
+	p, err := db.ListTransactions(entity.TransactionFilter{Page: entity.Page{Limit: 2}, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 2)
+	assert.Equal(t, 5, p.Transactions[0].Amount)
+	assert.Equal(t, 4, p.Transactions[1].Amount)
+	c, err := entity.DecodeCursor(p.NextCursor)
+	require.NoError(t, err)
+
+	p, err = db.ListTransactions(entity.TransactionFilter{Page: entity.Page{Limit: 4, Cursor: c}, UserID: u.ID})
+	require.NoError(t, err)
+	require.Len(t, p.Transactions, 4)
+	assert.Equal(t, 3, p.Transactions[0].Amount)
+	assert.Equal(t, entity.TxRegistration, p.Transactions[3].Type)
+	assert.Empty(t, p.NextCursor)
+
+	funds := transactions(t, db, entity.TransactionFilter{UserID: u.ID, Types: []entity.TransactionType{entity.TxFund, entity.TxTake}})
+	assert.Len(t, funds, 5)
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: u.ID, From: time.Now().Add(time.Hour)}), 0)
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: u.ID, To: time.Now().Add(time.Hour)}), 6)
+	assert.Len(t, transactions(t, db, entity.TransactionFilter{UserID: u.ID, From: time.Now().Add(-time.Hour)}), 6)
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (db DB) ListUsers(f entity.UserFilter) (entity.UserPage, error) {
+	p := entity.UserPage{Users: []entity.User{}}
+
+	where := "TRUE"
+	var args []interface{}
+	if f.NamePrefix != "" {
+		args = append(args, likeEscaper.Replace(f.NamePrefix)+"%")
+		where += fmt.Sprintf(" AND name LIKE $%d", len(args))
+	}
+	if f.MinBalance != nil {
+		args = append(args, *f.MinBalance)
+		where += fmt.Sprintf(" AND balance >= $%d", len(args))
+	}
+	if f.MaxBalance != nil {
+		args = append(args, *f.MaxBalance)
+		where += fmt.Sprintf(" AND balance <= $%d", len(args))
+	}
+
+	err := db.db.QueryRow(`
+		SELECT COUNT(*)
+		FROM users
+		WHERE `+where, args...).Scan(&p.Total)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't count users: %v", err))
+	}
+
+	column := string(f.Sort)
+	order, cmp := "ASC", ">"
+	if f.Desc {
+		order, cmp = "DESC", "<"
+	}
+	if f.Cursor.ID != 0 {
+		var key interface{} = f.Cursor.ID
+		switch f.Sort {
+		case entity.UserSortName:
+			key = f.Cursor.Value
+		case entity.UserSortBalance, entity.UserSortRating:
+			key, _ = strconv.Atoi(f.Cursor.Value)
+		}
+		args = append(args, key, f.Cursor.ID)
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", column, cmp, len(args)-1, len(args))
+	}
+	args = append(args, f.Limit+1)
+	rows, err := db.db.Query(fmt.Sprintf(`
+		SELECT id, name, balance, bonus, wagering, rating, policy_id
+		FROM users
+		WHERE %s
+		ORDER BY %s %s, id %s
+		LIMIT $%d`, where, column, order, order, len(args)), args...)
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("can't get users: %v", err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u entity.User
+		err := rows.Scan(&u.ID, &u.Name, &u.Balance, &u.Bonus, &u.Wagering, &u.Rating, &u.PolicyID)
+		if err != nil {
+			return p, entity.DBErr(fmt.Errorf("can't get users: %v", err))
+		}
+		p.Users = append(p.Users, u)
+	}
+	err = rows.Err()
+	if err != nil {
+		return p, entity.DBErr(fmt.Errorf("rows error: %v", err))
+	}
+	if len(p.Users) > f.Limit {
+		p.Users = p.Users[:f.Limit]
+		p.NextCursor = f.CursorOf(p.Users[f.Limit-1]).Encode()
+	}
+	return p, nil
+}
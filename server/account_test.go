@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestTrialBalance(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name":"alice","balance":1000}`, nil))
+
+	var tb entity.TrialBalance
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/accounts/trial-balance", "", &tb))
+	assert.Equal(t, entity.TrialBalance{
+		Accounts: []entity.AccountBalance{
+			{Account: entity.HouseAccount, Balance: -1000},
+			{Account: entity.FeesAccount, Balance: 300},
+			{Account: entity.UserAccount(1), Balance: 700},
+		},
+		Balanced: true,
+	}, tb)
+	assert.Equal(t, http.StatusMethodNotAllowed, do(t, h, "POST", "/accounts/trial-balance", "", nil))
+}
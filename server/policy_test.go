@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestPolicy(t *testing.T) {
+	h := newServer(t)
+	var p entity.Policy
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/policy", "", &p))
+	assert.Equal(t, 1, p.ID)
+	assert.True(t, p.Same(entity.DefaultPolicy))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/admin/policy", `{"fee": "x"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/admin/policy", `{"fee": 100}`, nil), "the fee isn't covered")
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/admin/policy", `{"fee": 100, "bonus": 50, "minBalance": 200}`, &p))
+	assert.Equal(t, 2, p.ID)
+
+	var u entity.User
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/user", `{"name": "poor", "balance": 199}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "user", "balance": 200}`, &u))
+	assert.Equal(t, 150, u.Balance)
+	assert.Equal(t, 2, u.PolicyID)
+
+	var old entity.Policy
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/policy/1", "", &old))
+	assert.True(t, old.Same(entity.DefaultPolicy))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/policy/3", "", nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/policy/x", "", nil))
+}
+
+func TestBonusPoints(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/admin/policy", `{"fee": 100, "bonus": 200, "minBalance": 100, "wagering": 2, "spendOrder": "bonus_first"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/admin/policy", `{"spendOrder": "never"}`, nil))
+
+	var u entity.User
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "user", "balance": 150}`, &u))
+	assert.Equal(t, 50, u.Balance)
+	assert.Equal(t, 200, u.Bonus)
+	assert.Equal(t, 400, u.Wagering)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/user/1/take", `{"points": 60}`, nil), "bonus points aren't withdrawn")
+
+	var txs entity.TransactionPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1/transactions?type=signup_bonus", "", &txs))
+	require.Len(t, txs.Transactions, 1)
+	assert.True(t, txs.Transactions[0].Bonus)
+	assert.Equal(t, 200, txs.Transactions[0].Balance)
+}
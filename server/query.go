@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+// readPage reads the "cursor" and "limit" query parameters.
+func readPage(r *http.Request) (entity.Page, error) {
+	q := r.URL.Query()
+	p := entity.Page{Limit: entity.DefaultLimit}
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > entity.MaxLimit {
+			return p, entity.ReqErr(fmt.Errorf("limit must be between 1 and %d", entity.MaxLimit))
+		}
+		p.Limit = limit
+	}
+	c, err := entity.DecodeCursor(q.Get("cursor"))
+	if err != nil {
+		return p, err
+	}
+	p.Cursor = c
+	return p, nil
+}
+
+// readTime reads an RFC 3339 query parameter, a missing one is zero time.
+func readTime(r *http.Request, key string) (time.Time, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, entity.ReqErr(fmt.Errorf("'%s' must be an RFC 3339 time", key))
+	}
+	return t, nil
+}
+
+// readList reads a query parameter that may be repeated or comma separated.
+func readList(r *http.Request, key string) []string {
+	var list []string
+	for _, v := range r.URL.Query()[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// readInt reads an optional integer query parameter.
+func readInt(r *http.Request, key string) (*int, error) {
+	s := r.URL.Query().Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, entity.ReqErr(fmt.Errorf("'%s' must be an integer", key))
+	}
+	return &i, nil
+}
+
+// readOrder reads the "order" query parameter and reports whether it is
+// descending.
+func readOrder(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("order") {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, entity.ReqErr(errors.New("'order' must be 'asc' or 'desc'"))
+}
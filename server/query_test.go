@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestReadPage(t *testing.T) {
+	cursor := entity.Cursor{ID: 3, Value: "700"}
+	tests := []struct {
+		query string
+		want  entity.Page
+		err   bool
+	}{
+		{"", entity.Page{Limit: entity.DefaultLimit}, false},
+		{"limit=1", entity.Page{Limit: 1}, false},
+		{"limit=100", entity.Page{Limit: 100}, false},
+		{"limit=0", entity.Page{}, true},
+		{"limit=101", entity.Page{}, true},
+		{"limit=ten", entity.Page{}, true},
+		{"cursor=" + cursor.Encode(), entity.Page{Limit: entity.DefaultLimit, Cursor: cursor}, false},
+		{"cursor=garbage&limit=5", entity.Page{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			p, err := readPage(httptest.NewRequest("GET", "/?"+tt.query, nil))
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestReadTime(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?from=2019-04-01T10:00:00%2B02:00&to=yesterday", nil)
+	from, err := readTime(r, "from")
+	require.NoError(t, err)
+	assert.True(t, from.Equal(time.Date(2019, 4, 1, 8, 0, 0, 0, time.UTC)))
+	_, err = readTime(r, "to")
+	assert.Error(t, err)
+	missing, err := readTime(r, "since")
+	require.NoError(t, err)
+	assert.True(t, missing.IsZero())
+}
+
+func TestReadList(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?type=deposit,prize&type=take&type=,&other=x", nil)
+	assert.Equal(t, []string{"deposit", "prize", "take"}, readList(r, "type"))
+	assert.Empty(t, readList(r, "status"))
+}
+
+func TestReadInt(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?min=-5&max=five", nil)
+	min, err := readInt(r, "min")
+	require.NoError(t, err)
+	require.NotNil(t, min)
+	assert.Equal(t, -5, *min)
+	_, err = readInt(r, "max")
+	assert.Error(t, err)
+	missing, err := readInt(r, "limit")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestReadOrder(t *testing.T) {
+	for query, desc := range map[string]bool{"": false, "order=asc": false, "order=desc": true} {
+		got, err := readOrder(httptest.NewRequest("GET", "/?"+query, nil))
+		require.NoError(t, err, query)
+		assert.Equal(t, desc, got, query)
+	}
+	_, err := readOrder(httptest.NewRequest("GET", "/?order=DESC", nil))
+	assert.Error(t, err)
+}
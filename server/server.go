@@ -2,6 +2,8 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -16,6 +18,42 @@ type ReqPoints struct {
 	Points int `json:"points"`
 }
 
+// ReqJoin joins a user to a tournament. Code is the invite code a private
+// tournament needs unless the user has their own invite.
+type ReqJoin struct {
+	UserID int    `json:"userId"`
+	Code   string `json:"code"`
+}
+
+type ReqScore struct {
+	UserID int `json:"userId"`
+	Score  int `json:"score"`
+}
+
+// ReqFinish carries the result for the external selector, either the winner
+// alone or the ranking from the first place down.
+type ReqFinish struct {
+	Winner  int   `json:"winner"`
+	Ranking []int `json:"ranking"`
+}
+
+// ReqTeamMember joins a user to a team, or changes their share of its
+// prizes.
+type ReqTeamMember struct {
+	UserID int `json:"userId"`
+	Share  int `json:"share"`
+}
+
+// ReqRegTeam registers a team for a tournament on behalf of its captain.
+// CaptainPays charges the deposits of the whole team to the captain. Code is
+// the invite code like in ReqJoin.
+type ReqRegTeam struct {
+	TeamID      int    `json:"teamId"`
+	UserID      int    `json:"userId"`
+	Code        string `json:"code"`
+	CaptainPays bool   `json:"captainPays"`
+}
+
 type API struct {
 	r *mux.Router
 	c game.Controller
@@ -28,19 +66,53 @@ func New(c game.Controller) (*mux.Router, error) {
 	}
 	a.r.HandleFunc("/user", a.regUser).Methods(http.MethodPost)
 	a.r.HandleFunc("/user/{id}", a.getUser).Methods(http.MethodGet)
+	a.r.HandleFunc("/users", a.listUsers).Methods(http.MethodGet)
 	a.r.HandleFunc("/user/{id}", a.delUser).Methods(http.MethodDelete)
 	a.r.HandleFunc("/user/{id}/take", a.takePoints).Methods(http.MethodPost)
 	a.r.HandleFunc("/user/{id}/fund", a.fundPoints).Methods(http.MethodPost)
+	a.r.HandleFunc("/user/{id}/transactions", a.listTransactions).Methods(http.MethodGet)
+	a.r.HandleFunc("/user/{id}/rating", a.getRating).Methods(http.MethodGet)
+	a.r.HandleFunc("/ratings", a.listRatings).Methods(http.MethodGet)
 	a.r.HandleFunc("/tournament", a.regTourn).Methods(http.MethodPost)
 	a.r.HandleFunc("/tournament/{id}", a.getTourn).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournaments", a.listTourns).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournament/{id}/open", a.openTourn).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/start", a.startTourn).Methods(http.MethodPost)
 	a.r.HandleFunc("/tournament/{id}/join", a.joinTourn).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/join/{userId}", a.leaveTourn).Methods(http.MethodDelete)
+	a.r.HandleFunc("/tournament/{id}/team", a.regTeam).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/invite", a.createInvite).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/invites", a.listInvites).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournament/{id}/invite/{inviteId}", a.revokeInvite).Methods(http.MethodDelete)
+	a.r.HandleFunc("/tournament/{id}/score", a.submitScore).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/bracket", a.getBracket).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournament/{id}/match", a.reportMatch).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/league", a.getLeague).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournament/{id}/league/match", a.reportLeagueMatch).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/swiss", a.getSwiss).Methods(http.MethodGet)
+	a.r.HandleFunc("/tournament/{id}/swiss/match", a.reportSwissMatch).Methods(http.MethodPost)
 	a.r.HandleFunc("/tournament/{id}/finish", a.finishTourn).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/cancel", a.cancelTourn).Methods(http.MethodPost)
+	a.r.HandleFunc("/tournament/{id}/verify", a.verifyTourn).Methods(http.MethodGet)
 	a.r.HandleFunc("/tournament/{id}", a.delTourn).Methods(http.MethodDelete)
+	a.r.HandleFunc("/team", a.createTeam).Methods(http.MethodPost)
+	a.r.HandleFunc("/team/{id}", a.getTeam).Methods(http.MethodGet)
+	a.r.HandleFunc("/team/{id}/join", a.joinTeam).Methods(http.MethodPost)
+	a.r.HandleFunc("/team/{id}/join/{userId}", a.leaveTeam).Methods(http.MethodDelete)
+	a.r.HandleFunc("/team/{id}/share", a.setTeamShare).Methods(http.MethodPost)
+	a.r.HandleFunc("/policy", a.currentPolicy).Methods(http.MethodGet)
+	a.r.HandleFunc("/policy/{id}", a.getPolicy).Methods(http.MethodGet)
+	a.r.HandleFunc("/admin/policy", a.setPolicy).Methods(http.MethodPost)
+	a.r.HandleFunc("/accounts/trial-balance", a.trialBalance).Methods(http.MethodGet)
 	return a.r, nil
 }
 
 func readID(r *http.Request) (int, error) {
-	strID := mux.Vars(r)["id"]
+	return readVarID(r, "id")
+}
+
+func readVarID(r *http.Request, name string) (int, error) {
+	strID := mux.Vars(r)[name]
 	id, err := strconv.Atoi(strID)
 	if err != nil {
 		return 0, entity.InvIDErr(err)
@@ -77,13 +149,110 @@ func (a API) getTourn(w http.ResponseWriter, r *http.Request) {
 	jsonResp(w, t)
 }
 
+func (a API) listTourns(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f := entity.TournamentFilter{Page: page}
+	for _, s := range readList(r, "status") {
+		f.Statuses = append(f.Statuses, entity.Status(s))
+	}
+	f.MinDeposit, err = readInt(r, "minDeposit")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f.MaxDeposit, err = readInt(r, "maxDeposit")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.ListTourns(f)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) openTourn(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.OpenTourn(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) startTourn(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.StartTourn(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
 func (a API) finishTourn(w http.ResponseWriter, r *http.Request) {
 	id, err := readID(r)
 	if err != nil {
 		errResp(w, err)
 		return
 	}
-	t, err := a.c.FinishTourn(id)
+	f := ReqFinish{}
+	err = json.NewDecoder(r.Body).Decode(&f)
+	if err != nil && err != io.EOF {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	if f.Winner != 0 {
+		if len(f.Ranking) != 0 {
+			errResp(w, entity.ReqErr(errors.New("supply either the winner or the ranking")))
+			return
+		}
+		f.Ranking = []int{f.Winner}
+	}
+	t, err := a.c.FinishTourn(id, f.Ranking)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) verifyTourn(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.VerifyTourn(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) cancelTourn(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.CancelTourn(id)
 	if err != nil {
 		errResp(w, err)
 		return
@@ -106,8 +275,130 @@ func (a API) delTourn(w http.ResponseWriter, r *http.Request) {
 }
 
 func (a API) joinTourn(w http.ResponseWriter, r *http.Request) {
-	u := entity.UserTourn{}
-	err := json.NewDecoder(r.Body).Decode(&u)
+	j := ReqJoin{}
+	err := json.NewDecoder(r.Body).Decode(&j)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.JoinTourn(id, j.UserID, j.Code)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) submitScore(w http.ResponseWriter, r *http.Request) {
+	s := ReqScore{}
+	err := json.NewDecoder(r.Body).Decode(&s)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.SubmitScore(id, s.UserID, s.Score)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) getBracket(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	b, err := a.c.GetBracket(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, b)
+}
+
+func (a API) reportMatch(w http.ResponseWriter, r *http.Request) {
+	m := entity.Match{}
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	b, err := a.c.ReportMatch(id, m)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, b)
+}
+
+func (a API) getLeague(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	l, err := a.c.GetLeague(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, l)
+}
+
+func (a API) reportLeagueMatch(w http.ResponseWriter, r *http.Request) {
+	m := entity.Match{}
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	l, err := a.c.ReportLeagueMatch(id, m)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, l)
+}
+
+func (a API) getSwiss(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	s, err := a.c.GetSwiss(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, s)
+}
+
+func (a API) reportSwissMatch(w http.ResponseWriter, r *http.Request) {
+	m := entity.Match{}
+	err := json.NewDecoder(r.Body).Decode(&m)
 	if err != nil {
 		errResp(w, entity.DecodeErr(err))
 		return
@@ -117,7 +408,26 @@ func (a API) joinTourn(w http.ResponseWriter, r *http.Request) {
 		errResp(w, err)
 		return
 	}
-	t, err := a.c.JoinTourn(id, u.ID)
+	s, err := a.c.ReportSwissMatch(id, m)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, s)
+}
+
+func (a API) leaveTourn(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	uID, err := readVarID(r, "userId")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.LeaveTourn(id, uID)
 	if err != nil {
 		errResp(w, err)
 		return
@@ -154,6 +464,71 @@ func (a API) getUser(w http.ResponseWriter, r *http.Request) {
 	jsonResp(w, u)
 }
 
+func (a API) listUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f := entity.UserFilter{
+		Page:       page,
+		Sort:       entity.UserSortID,
+		NamePrefix: r.URL.Query().Get("name"),
+	}
+	if s := r.URL.Query().Get("sort"); s != "" {
+		f.Sort = entity.UserSort(s)
+	}
+	f.Desc, err = readOrder(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f.MinBalance, err = readInt(r, "minBalance")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f.MaxBalance, err = readInt(r, "maxBalance")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.ListUsers(f)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) getRating(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	rating, err := a.c.GetRating(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, rating)
+}
+
+func (a API) listRatings(w http.ResponseWriter, r *http.Request) {
+	page, err := readPage(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.Ratings(page)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
 func (a API) delUser(w http.ResponseWriter, r *http.Request) {
 	id, err := readID(r)
 	if err != nil {
@@ -207,3 +582,244 @@ func (a *API) fundPoints(w http.ResponseWriter, r *http.Request) {
 	}
 	jsonResp(w, u)
 }
+
+func (a API) listTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	page, err := readPage(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f := entity.TransactionFilter{Page: page, UserID: id}
+	for _, t := range readList(r, "type") {
+		f.Types = append(f.Types, entity.TransactionType(t))
+	}
+	f.From, err = readTime(r, "from")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	f.To, err = readTime(r, "to")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.ListTransactions(f)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) trialBalance(w http.ResponseWriter, r *http.Request) {
+	tb, err := a.c.TrialBalance()
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, tb)
+}
+
+func (a API) regTeam(w http.ResponseWriter, r *http.Request) {
+	req := ReqRegTeam{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.RegTeam(id, req.TeamID, req.UserID, req.Code, req.CaptainPays)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) createTeam(w http.ResponseWriter, r *http.Request) {
+	t := entity.Team{}
+	err := json.NewDecoder(r.Body).Decode(&t)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	t, err = a.c.CreateTeam(t)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) getTeam(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.GetTeam(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) joinTeam(w http.ResponseWriter, r *http.Request) {
+	m := ReqTeamMember{}
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.JoinTeam(id, m.UserID, m.Share)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) leaveTeam(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	uID, err := readVarID(r, "userId")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.LeaveTeam(id, uID)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) setTeamShare(w http.ResponseWriter, r *http.Request) {
+	m := ReqTeamMember{}
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	t, err := a.c.SetTeamShare(id, m.UserID, m.Share)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, t)
+}
+
+func (a API) createInvite(w http.ResponseWriter, r *http.Request) {
+	inv := entity.Invite{}
+	err := json.NewDecoder(r.Body).Decode(&inv)
+	if err != nil && err != io.EOF {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	inv, err = a.c.CreateInvite(id, inv)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, inv)
+}
+
+func (a API) listInvites(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	invites, err := a.c.ListInvites(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, invites)
+}
+
+func (a API) revokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	inviteID, err := readVarID(r, "inviteId")
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	inv, err := a.c.RevokeInvite(id, inviteID)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, inv)
+}
+
+func (a API) currentPolicy(w http.ResponseWriter, r *http.Request) {
+	p, err := a.c.CurrentPolicy()
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) getPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := readID(r)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	p, err := a.c.GetPolicy(id)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
+
+func (a API) setPolicy(w http.ResponseWriter, r *http.Request) {
+	p := entity.Policy{}
+	err := json.NewDecoder(r.Body).Decode(&p)
+	if err != nil {
+		errResp(w, entity.DecodeErr(err))
+		return
+	}
+	p, err = a.c.SetPolicy(p)
+	if err != nil {
+		errResp(w, err)
+		return
+	}
+	jsonResp(w, p)
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/game"
+	"github.com/yanrishbe/gaming-website/memory"
+)
+
+func newServer(t *testing.T) http.Handler {
+	t.Helper()
+	h, err := New(game.New(memory.New()))
+	require.NoError(t, err)
+	return h
+}
+
+// do sends a request with a JSON body and decodes the JSON response into
+// resp, if it's not nil. It returns the status code.
+func do(t *testing.T, h http.Handler, method, path, body string, resp interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if resp != nil && w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), resp), w.Body.String())
+	}
+	return w.Code
+}
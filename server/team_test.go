@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestTeam(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"a", "b"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	var team entity.Team
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/team", `{"name": "red"}`, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/team", `{"name": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/team", `{"name": "red", "captainId": 1}`, &team))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/team/1/join", `{"userId": 2}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/team/1/share", `{"userId": 2, "share": 2}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/team/1", "", &team))
+	assert.Equal(t, entity.Team{ID: 1, Name: "red", CaptainID: 1, Members: []entity.TeamMember{
+		{UserID: 1, Name: "a", Share: 1},
+		{UserID: 2, Name: "b", Share: 2},
+	}}, team)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "DELETE", "/team/1/join/1", "", nil), "the captain stays")
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/team/2", "", nil))
+
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "teamSize": 2}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil), "a captain registers the team")
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/team", `{"teamId": 1, "userId": 2}`, nil), "only the captain registers the team")
+	var joined entity.JoinResult
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/team", `{"teamId": 1, "userId": 1, "captainPays": true}`, &joined))
+	require.Len(t, joined.Users, 1)
+	assert.Equal(t, []entity.Member{
+		{UserID: 1, Name: "a", Stake: 200, Share: 1},
+		{UserID: 2, Name: "b", Share: 2},
+	}, joined.Users[0].Members)
+
+	require.Equal(t, http.StatusOK, do(t, h, "DELETE", "/team/1/join/2", "", &team))
+	assert.Len(t, team.Members, 1)
+	var u entity.User
+	require.Equal(t, http.StatusOK, do(t, h, "DELETE", "/tournament/1/join/1", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1", "", &u))
+	assert.Equal(t, 700, u.Balance, "the captain gets the whole stake back")
+}
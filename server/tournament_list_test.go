@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestListTourns(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	for _, body := range []string{
+		`{"name": "first", "status": "open", "deposit": 100}`,
+		`{"name": "second", "status": "open", "deposit": 200}`,
+		`{"name": "third", "status": "open", "deposit": 300}`,
+	} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", body, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/finish", "", nil))
+
+	var p entity.TournamentPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournaments?limit=2", "", &p))
+	assert.Equal(t, 3, p.Total)
+	require.Len(t, p.Tournaments, 2)
+	assert.Equal(t, "third", p.Tournaments[0].Name)
+	assert.Equal(t, "second", p.Tournaments[1].Name)
+	var next entity.TournamentPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournaments?limit=2&cursor="+p.NextCursor, "", &next))
+	assert.Equal(t, []entity.TournamentSummary{{ID: 1, Name: "first", Deposit: 100, Prize: 100, Participants: 1, Status: entity.Finished, Format: entity.FormatStandard, Winner: 1}}, next.Tournaments)
+
+	p = entity.TournamentPage{}
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournaments?status=open&minDeposit=150&maxDeposit=250", "", &p))
+	require.Len(t, p.Tournaments, 1)
+	assert.Equal(t, "second", p.Tournaments[0].Name)
+
+	for _, path := range []string{
+		"/tournaments?status=paused",
+		"/tournaments?minDeposit=x",
+		"/tournaments?minDeposit=2&maxDeposit=1",
+		"/tournaments?cursor=x",
+	} {
+		var e entity.Error
+		assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", path, "", &e), path)
+		assert.NotEmpty(t, e.Message, path)
+	}
+}
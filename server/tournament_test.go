@@ -0,0 +1,286 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestTournLifecycle(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "draft", "deposit": 100}`, &tourn))
+	assert.Equal(t, entity.Draft, tourn.Status)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "finished", "deposit": 100}`, nil))
+	var other entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "deposit": 100}`, &other))
+	assert.Equal(t, entity.Open, other.Status)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "deposit": 100, "opensAt": "2030-01-01T00:00:00Z"}`, &other))
+	assert.Equal(t, entity.Draft, other.Status)
+
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/start", "", nil))
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/finish", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/open", "", &tourn))
+	assert.Equal(t, entity.Open, tourn.Status)
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/open", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", &tourn))
+	assert.Equal(t, entity.Running, tourn.Status)
+	assert.Equal(t, http.StatusConflict, do(t, h, "DELETE", "/tournament/1/join/1", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/finish", "", &tourn))
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/cancel", "", nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/4/open", "", nil))
+}
+
+func TestFinishTournExternal(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "selector": "external"}`, &tourn))
+	assert.Equal(t, entity.SelectExternal, tourn.Selector)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 2}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/finish", "", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/finish", `{"winner": "bob"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/finish", `{"winner": 3}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/finish", `{"winner": 2}`, &tourn))
+	assert.Equal(t, 2, tourn.Winner)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament", `{"name": "cup", "deposit": 100, "selector": "coin"}`, nil))
+}
+
+func TestFinishTournRanking(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "selector": "external", "payouts": {"places": [70, 30]}}`, nil))
+	for _, id := range []string{"1", "2", "3"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": `+id+`}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/finish", `{"winner": 3, "ranking": [3, 1]}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/finish", `{"ranking": [3]}`, nil), "two places are paid")
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/finish", `{"ranking": [3, 1]}`, &tourn))
+	assert.Equal(t, 3, tourn.Winner)
+	payouts := map[int]int{}
+	for _, u := range tourn.Users {
+		payouts[u.ID] = u.Payout
+	}
+	assert.Equal(t, map[int]int{1: 90, 2: 0, 3: 210}, payouts)
+}
+
+func TestCancelTourn(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/cancel", "", &tourn))
+	assert.Equal(t, entity.Cancelled, tourn.Status)
+	var u entity.User
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1", "", &u))
+	assert.Equal(t, 700, u.Balance)
+
+	var e entity.Error
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/cancel", "", &e))
+	assert.NotEmpty(t, e.Message)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/x/cancel", "", nil))
+}
+
+func TestLeaveTourn(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "DELETE", "/tournament/1/join/1", "", &tourn))
+	assert.Equal(t, 0, tourn.Prize)
+	assert.Empty(t, tourn.Users)
+	var u entity.User
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1", "", &u))
+	assert.Equal(t, 700, u.Balance)
+
+	for path, code := range map[string]int{
+		"/tournament/1/join/1": http.StatusBadRequest,
+		"/tournament/1/join/x": http.StatusBadRequest,
+		"/tournament/x/join/1": http.StatusBadRequest,
+		"/tournament/2/join/1": http.StatusBadRequest,
+	} {
+		var e entity.Error
+		assert.Equal(t, code, do(t, h, "DELETE", path, "", &e), path)
+		assert.NotEmpty(t, e.Message, path)
+	}
+}
+
+func TestVerifyTourn(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	assert.Equal(t, http.StatusConflict, do(t, h, "GET", "/tournament/1/verify", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/finish", "", nil))
+
+	var p entity.Proof
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1/verify", "", &p))
+	assert.True(t, p.Valid)
+	assert.Equal(t, 1, p.Winner)
+	assert.Equal(t, "1:100", p.Input)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/tournament/2/verify", "", nil))
+}
+
+func TestWaitlist(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament", `{"name": "cup", "deposit": 100, "minPlayers": 3, "maxPlayers": 2}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "maxPlayers": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 2}`, &tourn))
+	assert.Len(t, tourn.Users, 1)
+	assert.Equal(t, []entity.UserTourn{{ID: 2, Name: "bob"}}, tourn.Waitlist)
+	var left entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "DELETE", "/tournament/1/join/1", "", &left))
+	assert.Equal(t, 2, left.Users[0].ID)
+	assert.Empty(t, left.Waitlist)
+}
+
+func TestSubmitScore(t *testing.T) {
+	h := newServer(t)
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "selector": "score"}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	assert.Equal(t, http.StatusConflict, do(t, h, "POST", "/tournament/1/score", `{"userId": 1, "score": 3}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/score", `{"userId": 1, "score": 3}`, &tourn))
+	assert.Equal(t, []entity.Standing{{Rank: 1, UserID: 1, Name: "alice", Score: 3}}, tourn.Leaderboard)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/score", `{"score": "high"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/x/score", `{"userId": 1, "score": 3}`, nil))
+}
+
+func TestBracket(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "format": "single_elimination"}`, nil))
+	for _, id := range []string{"1", "2"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": `+id+`}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	var b entity.Bracket
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1/bracket", "", &b))
+	assert.Equal(t, [][]entity.Match{{{Round: 1, Slot: 0, Player1: 1, Player2: 2}}}, b.Rounds)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/match", `{"round": 1, "slot": 0, "winner": 3}`, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/match", `{"winner": "bob"}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/match", `{"round": 1, "slot": 0, "winner": 2}`, &b))
+	assert.Equal(t, 2, b.Winner)
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1", "", &tourn))
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/tournament/x/bracket", "", nil))
+}
+
+func TestLeague(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "format": "round_robin"}`, nil))
+	for _, id := range []string{"1", "2"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": `+id+`}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	var l entity.League
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1/league", "", &l))
+	assert.Equal(t, [][]entity.Match{{{Round: 1, Slot: 0, Player1: 1, Player2: 2}}}, l.Rounds)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/league/match", `{"round": 1, "slot": 0, "winner": 1, "draw": true}`, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/league/match", `{"draw": "yes"}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/league/match", `{"round": 1, "slot": 0, "draw": true}`, &l))
+	assert.Equal(t, []entity.LeagueStanding{
+		{Rank: 1, UserID: 1, Name: "alice", Played: 1, Draws: 1, Points: 1},
+		{Rank: 2, UserID: 2, Name: "bob", Played: 1, Draws: 1, Points: 1},
+	}, l.Standings)
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1", "", &tourn))
+	assert.Equal(t, entity.Finished, tourn.Status)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/tournament/x/league", "", nil))
+}
+
+func TestSwiss(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	require.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament", `{"name": "cup", "deposit": 100, "rounds": 2}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "format": "swiss", "rounds": 3}`, nil))
+	for _, id := range []string{"1", "2"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": `+id+`}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+
+	var s entity.Swiss
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1/swiss", "", &s))
+	assert.Equal(t, [][]entity.Match{{{Round: 1, Slot: 0, Player1: 1, Player2: 2}}}, s.Rounds)
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/swiss/match", `{"round": "1"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/swiss/match", `{"round": 2, "slot": 0, "winner": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/swiss/match", `{"round": 1, "slot": 0, "winner": 2}`, &s))
+	assert.Equal(t, []int{2, 1}, s.Ranking())
+	var tourn entity.Tournament
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1", "", &tourn))
+	assert.Equal(t, entity.Finished, tourn.Status, "two players play a single round")
+	assert.Equal(t, 2, tourn.Winner)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/tournament/2/swiss", "", nil))
+}
+
+func TestInvite(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "private": true}`, nil))
+	var code, personal entity.Invite
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/invite", "", &code))
+	assert.NotEmpty(t, code.Code, "an empty body draws a code")
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/invite", `{"userId": 2}`, &personal))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/invite", `{"userId": 2, "code": "vip"}`, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, do(t, h, "POST", "/tournament/1/invite", `{"maxUses": "1"}`, nil))
+
+	var p entity.TournamentPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournaments", "", &p))
+	assert.Empty(t, p.Tournaments, "a private tournament isn't listed")
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/join", `{"userId": 1}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": 1, "code": "`+code.Code+`"}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "DELETE", "/tournament/1/invite/2", "", &personal))
+	assert.True(t, personal.Revoked)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "POST", "/tournament/1/join", `{"userId": 2}`, nil))
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "DELETE", "/tournament/1/invite/x", "", nil))
+
+	var invites []entity.Invite
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/tournament/1/invites", "", &invites))
+	require.Len(t, invites, 2)
+	assert.Equal(t, 1, invites[0].Uses)
+	assert.Equal(t, 0, invites[1].Uses)
+}
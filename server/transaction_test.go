@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestListTransactions(t *testing.T) {
+	h := newServer(t)
+	var u entity.User
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "alice", "balance": 1000}`, &u))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user/1/fund", `{"points": 400}`, nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/user/1/take", `{"points": 100}`, nil))
+
+	var p entity.TransactionPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1/transactions?limit=2", "", &p))
+	require.Len(t, p.Transactions, 2)
+	assert.Equal(t, entity.TxTake, p.Transactions[0].Type)
+	assert.Equal(t, 1000, p.Transactions[0].Balance)
+	assert.Equal(t, entity.TxFund, p.Transactions[1].Type)
+	require.NotEmpty(t, p.NextCursor)
+
+	var next entity.TransactionPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1/transactions?cursor="+p.NextCursor, "", &next))
+	require.Len(t, next.Transactions, 2)
+	assert.Equal(t, entity.TxRegFee, next.Transactions[0].Type)
+	assert.Equal(t, entity.TxRegistration, next.Transactions[1].Type)
+	assert.Empty(t, next.NextCursor)
+
+	p = entity.TransactionPage{}
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/1/transactions?type=registration,take&from=2019-01-01T00:00:00Z", "", &p))
+	require.Len(t, p.Transactions, 2)
+	assert.Equal(t, entity.TxTake, p.Transactions[0].Type)
+	assert.Equal(t, entity.TxRegistration, p.Transactions[1].Type)
+
+	for path, code := range map[string]int{
+		"/user/x/transactions":                http.StatusBadRequest,
+		"/user/2/transactions":                http.StatusNotFound,
+		"/user/1/transactions?limit=0":        http.StatusBadRequest,
+		"/user/1/transactions?cursor=x":       http.StatusBadRequest,
+		"/user/1/transactions?type=bet":       http.StatusBadRequest,
+		"/user/1/transactions?from=yesterday": http.StatusBadRequest,
+		"/user/1/transactions?from=2019-01-02T00:00:00Z&to=2019-01-01T00:00:00Z": http.StatusBadRequest,
+	} {
+		var e entity.Error
+		assert.Equal(t, code, do(t, h, "GET", path, "", &e), path)
+		assert.NotEmpty(t, e.Message, path)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanrishbe/gaming-website/entity"
+)
+
+func TestListUsers(t *testing.T) {
+	h := newServer(t)
+	for _, body := range []string{
+		`{"name": "carol", "balance": 1000}`,
+		`{"name": "alice", "balance": 1500}`,
+		`{"name": "bob", "balance": 1000}`,
+	} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", body, nil))
+	}
+
+	var p entity.UserPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/users", "", &p))
+	assert.Equal(t, 3, p.Total)
+	require.Len(t, p.Users, 3)
+	for i, name := range []string{"carol", "alice", "bob"} {
+		assert.Equal(t, i+1, p.Users[i].ID)
+		assert.Equal(t, name, p.Users[i].Name)
+	}
+	assert.Equal(t, 1200, p.Users[1].Balance)
+
+	p = entity.UserPage{}
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/users?sort=balance&order=desc&limit=2", "", &p))
+	require.Len(t, p.Users, 2)
+	assert.Equal(t, "alice", p.Users[0].Name)
+	assert.Equal(t, "bob", p.Users[1].Name)
+	var next entity.UserPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/users?sort=balance&order=desc&limit=2&cursor="+p.NextCursor, "", &next))
+	require.Len(t, next.Users, 1)
+	assert.Equal(t, "carol", next.Users[0].Name)
+
+	p = entity.UserPage{}
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/users?sort=name&name=b&maxBalance=700", "", &p))
+	require.Len(t, p.Users, 1)
+	assert.Equal(t, "bob", p.Users[0].Name)
+
+	for _, path := range []string{
+		"/users?sort=age",
+		"/users?order=up",
+		"/users?minBalance=x",
+		"/users?maxBalance=1.5",
+		"/users?minBalance=2&maxBalance=1",
+		"/users?limit=0",
+	} {
+		var e entity.Error
+		assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", path, "", &e), path)
+		assert.NotEmpty(t, e.Message, path)
+	}
+}
+
+func TestRatings(t *testing.T) {
+	h := newServer(t)
+	for _, name := range []string{"alice", "bob"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/user", `{"name": "`+name+`", "balance": 1000, "rating": 3000}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament", `{"name": "cup", "status": "open", "deposit": 100, "format": "round_robin"}`, nil))
+	for _, id := range []string{"1", "2"} {
+		require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/join", `{"userId": `+id+`}`, nil))
+	}
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/start", "", nil))
+	require.Equal(t, http.StatusOK, do(t, h, "POST", "/tournament/1/league/match", `{"round": 1, "slot": 0, "winner": 2}`, nil))
+
+	var r entity.UserRating
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/user/2/rating", "", &r))
+	assert.Equal(t, entity.UserRating{UserID: 2, Name: "bob", Rating: 1516, History: []entity.TournamentRating{
+		{TournamentID: 1, Before: 1500, After: 1516, Games: 1},
+	}}, r, "a new user starts at the initial rating")
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/user/x/rating", "", nil))
+
+	var p entity.UserPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/ratings?limit=1", "", &p))
+	require.Len(t, p.Users, 1)
+	assert.Equal(t, "bob", p.Users[0].Name)
+	var next entity.UserPage
+	require.Equal(t, http.StatusOK, do(t, h, "GET", "/ratings?limit=1&cursor="+p.NextCursor, "", &next))
+	require.Len(t, next.Users, 1)
+	assert.Equal(t, 1484, next.Users[0].Rating)
+	assert.Equal(t, http.StatusBadRequest, do(t, h, "GET", "/ratings?limit=0", "", nil))
+}